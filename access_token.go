@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/accesstoken"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/pkg/errors"
+)
+
+// accessKeysRefreshInterval как часто каждый экземпляр сервиса перечитывает
+// ключи из базы: за это время все начинают подписывать новым ключом
+const accessKeysRefreshInterval = time.Minute
+
+// ErrAccessTokensDisabled access токены выключены в конфиге
+var ErrAccessTokensDisabled = errors.New("access_tokens_disabled")
+
+// AccessTokenPolicy выпуск access токенов. Ключ подписи меняется раз
+// в RotationInterval, старые ключи публикуются ещё один RotationInterval,
+// пока не истекут подписанные ими токены
+type AccessTokenPolicy struct {
+	Enabled          bool
+	TTL              time.Duration
+	RotationInterval time.Duration
+	Issuer           string
+}
+
+// accessTokenPolicy политика, с которой работает сервис
+var accessTokenPolicy = NewAccessTokenPolicy(AccessTokenConfig{})
+
+// accessKeySecrets шифровальщик закрытых ключей подписи в базе. Ключ задаётся в main
+var accessKeySecrets = &SecretBox{}
+
+// NewAccessTokenPolicy создаёт политику из конфига, незаданные значения берутся по умолчанию
+func NewAccessTokenPolicy(c AccessTokenConfig) *AccessTokenPolicy {
+	p := &AccessTokenPolicy{
+		Enabled:          c.Enabled,
+		TTL:              5 * time.Minute,
+		RotationInterval: 24 * time.Hour,
+		Issuer:           "warscript-users",
+	}
+
+	if c.TTL > 0 {
+		p.TTL = time.Duration(c.TTL) * time.Second
+	}
+	if c.RotationInterval > 0 {
+		p.RotationInterval = time.Duration(c.RotationInterval) * time.Second
+	}
+	if c.Issuer != "" {
+		p.Issuer = c.Issuer
+	}
+
+	// токен, подписанный старым ключом перед самой ротацией, должен
+	// дожить до конца срока, пока ключ ещё публикуется
+	if min := p.TTL + accessKeysRefreshInterval; p.RotationInterval < min {
+		p.RotationInterval = min
+	}
+
+	return p
+}
+
+// AccessKeyring ключи, которыми экземпляр сервиса подписывает и которые публикует
+type AccessKeyring struct {
+	mu        sync.RWMutex
+	signer    *ecdsa.PrivateKey
+	signerKID string
+	jwks      *accesstoken.JWKS
+}
+
+// accessKeys ключи сервиса, обновляются refreshAccessKeysImpl
+var accessKeys = &AccessKeyring{jwks: &accesstoken.JWKS{Keys: []accesstoken.JWK{}}}
+
+// Signer ключ для подписи и его kid
+func (k *AccessKeyring) Signer() (*ecdsa.PrivateKey, string) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signer, k.signerKID
+}
+
+// JWKS опубликованные открытые ключи
+func (k *AccessKeyring) JWKS() *accesstoken.JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.jwks
+}
+
+func (k *AccessKeyring) set(signer *ecdsa.PrivateKey, kid string, jwks *accesstoken.JWKS) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signer, k.signerKID, k.jwks = signer, kid, jwks
+}
+
+// newSigningKey генерирует ключ P-256, kid берётся из хеша открытого ключа
+func newSigningKey() (*SigningKeyModel, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not generate signing key")
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "public key marshal error")
+	}
+	priv, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "private key marshal error")
+	}
+	sealed, err := accessKeySecrets.Seal(priv)
+	if err != nil {
+		return nil, errors.Wrap(err, "private key seal error")
+	}
+
+	sum := sha256.Sum256(pub)
+	return &SigningKeyModel{
+		KID:        hex.EncodeToString(sum[:8]),
+		PublicKey:  pub,
+		PrivateKey: sealed,
+	}, nil
+}
+
+// openSigningKey расшифровывает закрытый ключ. Ключи, зашифрованные
+// другим accessKeySecrets, прочитать нельзя, но их можно публиковать
+func openSigningKey(k *SigningKeyModel) (*ecdsa.PrivateKey, error) {
+	der, err := accessKeySecrets.Open(k.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseECPrivateKey(der)
+}
+
+// refreshAccessKeysImpl перечитывает ключи из базы, при необходимости
+// создаёт новый и удаляет те, что больше не нужны для проверки
+func refreshAccessKeysImpl(now time.Time) error {
+	if err := SigningKeys.DeleteBefore(now.Add(-2 * accessTokenPolicy.RotationInterval)); err != nil {
+		return errors.Wrap(err, "old signing keys delete error")
+	}
+
+	keys, err := SigningKeys.List()
+	if err != nil {
+		return errors.Wrap(err, "signing keys get error")
+	}
+
+	// подписываем самым новым ключом, который можем прочитать,
+	// если он ещё не старше периода ротации
+	var signer *ecdsa.PrivateKey
+	var signerKID string
+	for _, k := range keys {
+		if now.Sub(k.CreatedAt) >= accessTokenPolicy.RotationInterval {
+			break
+		}
+		if signer, err = openSigningKey(k); err == nil {
+			signerKID = k.KID
+			break
+		}
+	}
+
+	if signer == nil {
+		k, err := newSigningKey()
+		if err != nil {
+			return err
+		}
+		if err = SigningKeys.Create(k); err != nil {
+			return errors.Wrap(err, "signing key create error")
+		}
+		if signer, err = openSigningKey(k); err != nil {
+			return errors.Wrap(err, "signing key open error")
+		}
+		signerKID = k.KID
+		keys = append([]*SigningKeyModel{k}, keys...)
+	}
+
+	jwks := &accesstoken.JWKS{Keys: make([]accesstoken.JWK, 0, len(keys))}
+	for _, k := range keys {
+		pub, err := x509.ParsePKIXPublicKey(k.PublicKey)
+		if err != nil {
+			logger.Warnf("can not parse signing key %s: %s", k.KID, err)
+			continue
+		}
+		if ecPub, ok := pub.(*ecdsa.PublicKey); ok {
+			jwks.Keys = append(jwks.Keys, accesstoken.NewJWK(k.KID, ecPub))
+		}
+	}
+
+	accessKeys.set(signer, signerKID, jwks)
+	return nil
+}
+
+// runAccessKeyRotation обновляет ключи раз в accessKeysRefreshInterval, пока не закрыт stop
+func runAccessKeyRotation(stop <-chan struct{}) {
+	ticker := time.NewTicker(accessKeysRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if err := refreshAccessKeysImpl(time.Now()); err != nil {
+			logger.Errorf("can not refresh access token keys: %s", err)
+		}
+	}
+}
+
+// issueAccessTokenImpl выпускает access токен по refresh токену, которым служит
+// токен сессии. Сессия проверяется в редисе, так что после выхода,
+// бана или смены пароля новый access токен не получить
+func issueAccessTokenImpl(refreshToken string) (*jmodels.AccessToken, error) {
+	if !accessTokenPolicy.Enabled {
+		return nil, ErrAccessTokensDisabled
+	}
+
+	info, err := getSessionInfoImpl(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := userPermissionsImpl(info.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get permissions error")
+	}
+
+	key, kid := accessKeys.Signer()
+	if key == nil {
+		return nil, errors.New("signing key is not loaded")
+	}
+
+	now := time.Now()
+	token, err := accesstoken.Sign(key, kid, &accesstoken.Claims{
+		Issuer:      accessTokenPolicy.Issuer,
+		Subject:     strconv.FormatInt(info.ID, 10),
+		SessionID:   (&Session{Token: refreshToken}).PublicID(),
+		Permissions: up.Permissions,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(accessTokenPolicy.TTL).Unix(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "access token sign error")
+	}
+
+	return &jmodels.AccessToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(accessTokenPolicy.TTL / time.Second),
+	}, nil
+}
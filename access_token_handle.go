@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+)
+
+// CreateAccessToken выдаёт access токен по сессии из куки или,
+// для клиентов без куки, по {"refresh_token": ...}
+func CreateAccessToken(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "CreateAccessToken")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	var refreshToken string
+	if cookie, err := r.Cookie("JSESSIONID"); err == nil {
+		refreshToken = cookie.Value
+	} else {
+		form := &jmodels.FormRefreshToken{}
+		if err = utils.DecodeBodyJSON(r.Body, form); err != nil {
+			errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+			return
+		}
+		refreshToken = form.RefreshToken
+	}
+	if refreshToken == "" {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("refresh token is not presented"))
+		return
+	}
+
+	token, err := issueAccessTokenImpl(refreshToken)
+	if err != nil {
+		switch errors.Cause(err) {
+		case ErrAccessTokensDisabled:
+			errWriter.WriteWarn(http.StatusNotFound, err)
+		case ErrSessionNotExists:
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+		default:
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteApplicationJSON(w, http.StatusOK, token)
+}
+
+// GetJWKS открытые ключи, которыми проверяются access токены
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "GetJWKS")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	if !accessTokenPolicy.Enabled {
+		errWriter.WriteWarn(http.StatusNotFound, ErrAccessTokensDisabled)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, accessKeys.JWKS())
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/accesstoken"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+)
+
+func initAccessTokenTests(enabled bool) {
+	initTests()
+	accessTokenPolicy = NewAccessTokenPolicy(AccessTokenConfig{Enabled: enabled})
+	accessKeySecrets = newTestSecretBox("signing-secret")
+	accessKeys = &AccessKeyring{jwks: &accesstoken.JWKS{Keys: []accesstoken.JWK{}}}
+}
+
+func TestNewAccessTokenPolicy(t *testing.T) {
+	p := NewAccessTokenPolicy(AccessTokenConfig{TTL: 600, RotationInterval: 60})
+	if p.TTL != 10*time.Minute || p.RotationInterval != 11*time.Minute || p.Issuer != "warscript-users" {
+		t.Errorf("TestNewAccessTokenPolicy got unexpected policy: %+v", p)
+	}
+}
+
+func TestRefreshAccessKeys(t *testing.T) {
+	initAccessTokenTests(true)
+	keys := SigningKeys.(*signingKeysTest)
+	now := time.Now()
+
+	if err := refreshAccessKeysImpl(now); err != nil {
+		t.Fatalf("TestRefreshAccessKeys got unexpected error: %v", err)
+	}
+	signer, first := accessKeys.Signer()
+	if signer == nil || len(keys.keys) != 1 || keys.keys[0].KID != first {
+		t.Fatalf("TestRefreshAccessKeys signing key was not created: %v", keys.keys)
+	}
+
+	// ключ ещё свежий: новый не создаётся
+	if err := refreshAccessKeysImpl(now.Add(time.Hour)); err != nil {
+		t.Fatalf("TestRefreshAccessKeys got unexpected error: %v", err)
+	}
+	if _, kid := accessKeys.Signer(); kid != first || len(keys.keys) != 1 {
+		t.Errorf("TestRefreshAccessKeys key was rotated too early: %s", kid)
+	}
+
+	// ротация: подписываем новым, старый ещё публикуется
+	if err := refreshAccessKeysImpl(now.Add(25 * time.Hour)); err != nil {
+		t.Fatalf("TestRefreshAccessKeys got unexpected error: %v", err)
+	}
+	_, second := accessKeys.Signer()
+	jwks := accessKeys.JWKS()
+	if second == first || len(jwks.Keys) != 2 || jwks.Keys[0].Kid != second || jwks.Keys[1].Kid != first {
+		t.Errorf("TestRefreshAccessKeys got unexpected keys after rotation: %s, %+v", second, jwks.Keys)
+	}
+
+	// старый ключ больше не нужен
+	keys.keys[0].CreatedAt = now.Add(30 * time.Hour)
+	if err := refreshAccessKeysImpl(now.Add(49 * time.Hour)); err != nil {
+		t.Fatalf("TestRefreshAccessKeys got unexpected error: %v", err)
+	}
+	if jwks = accessKeys.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].Kid != second {
+		t.Errorf("TestRefreshAccessKeys old key was not deleted: %+v", jwks.Keys)
+	}
+
+	// чужой секрет: ключ публикуется, но подписываем новым
+	accessKeySecrets = newTestSecretBox("other-secret")
+	if err := refreshAccessKeysImpl(now.Add(49 * time.Hour)); err != nil {
+		t.Fatalf("TestRefreshAccessKeys got unexpected error: %v", err)
+	}
+	if _, kid := accessKeys.Signer(); kid == second || len(accessKeys.JWKS().Keys) != 2 {
+		t.Errorf("TestRefreshAccessKeys got unexpected keys with other secret: %s, %+v", kid, accessKeys.JWKS().Keys)
+	}
+
+	keys.SetNextFail(utils.ErrInternal)
+	if err := refreshAccessKeysImpl(now); err == nil {
+		t.Errorf("TestRefreshAccessKeys error was not returned")
+	}
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	initAccessTokenTests(true)
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Active: true}
+	Roles.(*rolesTest).roles[1] = []string{RoleModerator}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":1}`)
+	if err := refreshAccessKeysImpl(time.Now()); err != nil {
+		t.Fatalf("TestCreateAccessToken got unexpected error: %v", err)
+	}
+
+	request := func(cookie, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/sessions/token", strings.NewReader(body))
+		if cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: cookie})
+		}
+		w := httptest.NewRecorder()
+		CreateAccessToken(w, r)
+		return w
+	}
+
+	for _, w := range []*httptest.ResponseRecorder{
+		request("golang-session", ``),
+		request("", `{"refresh_token":"golang-session"}`),
+	} {
+		if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("TestCreateAccessToken got unexpected result: %d, %s", w.Code, w.Body.String())
+		}
+
+		token := &jmodels.AccessToken{}
+		if err := json.Unmarshal(w.Body.Bytes(), token); err != nil {
+			t.Fatalf("TestCreateAccessToken can not decode response: %v", err)
+		}
+		if token.TokenType != "Bearer" || token.ExpiresIn != 300 {
+			t.Errorf("TestCreateAccessToken got unexpected token: %+v", token)
+		}
+
+		claims, err := accesstoken.Parse(token.AccessToken, accessKeys.JWKS().PublicKeys(), "warscript-users", time.Now())
+		if err != nil {
+			t.Fatalf("TestCreateAccessToken token can not be verified: %v", err)
+		}
+		if id, _ := claims.UserID(); id != 1 || !claims.HasPermission(permissions.ModerateBots) ||
+			claims.HasPermission(permissions.ManageUsers) ||
+			claims.SessionID != (&Session{Token: "golang-session"}).PublicID() {
+			t.Errorf("TestCreateAccessToken got unexpected claims: %+v", claims)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		cookie   string
+		body     string
+		fail     bool
+		expected int
+	}{
+		{name: "no token", body: `{}`, expected: http.StatusUnauthorized},
+		{name: "bad body", body: `kek`, expected: http.StatusBadRequest},
+		{name: "revoked", cookie: "other-session", expected: http.StatusUnauthorized},
+		{name: "db error", cookie: "golang-session", fail: true, expected: http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if c.fail {
+			Roles.(*rolesTest).SetNextFail(utils.ErrInternal)
+		}
+		if w := request(c.cookie, c.body); w.Code != c.expected {
+			t.Errorf("[%s] TestCreateAccessToken got unexpected code: %d, expected: %d", c.name, w.Code, c.expected)
+		}
+	}
+
+	// после выхода access токен больше не выдаётся
+	delete(Sessions.(*sessionsTest).sessions, "golang-session")
+	if w := request("golang-session", ``); w.Code != http.StatusUnauthorized {
+		t.Errorf("TestCreateAccessToken got unexpected code after logout: %d", w.Code)
+	}
+}
+
+func TestAccessTokensDisabled(t *testing.T) {
+	initAccessTokenTests(false)
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Active: true}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":1}`)
+
+	r := httptest.NewRequest("POST", "/sessions/token", nil)
+	r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "golang-session"})
+	w := httptest.NewRecorder()
+	CreateAccessToken(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("TestAccessTokensDisabled got unexpected token code: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	GetJWKS(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("TestAccessTokensDisabled got unexpected jwks code: %d", w.Code)
+	}
+}
+
+func TestGetJWKS(t *testing.T) {
+	initAccessTokenTests(true)
+	if err := refreshAccessKeysImpl(time.Now()); err != nil {
+		t.Fatalf("TestGetJWKS got unexpected error: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	GetJWKS(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+
+	set := &accesstoken.JWKS{}
+	if err := json.Unmarshal(w.Body.Bytes(), set); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("TestGetJWKS got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if _, kid := accessKeys.Signer(); len(set.Keys) != 1 || set.Keys[0].Kid != kid || strings.Contains(w.Body.String(), `"d"`) {
+		t.Errorf("TestGetJWKS got unexpected keys: %s", w.Body.String())
+	}
+}
+
+func TestSigningKeyListModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Now()
+	mock.ExpectQuery(`FROM signing_keys k ORDER BY created_at DESC`).
+		WillReturnRows(sqlmock.NewRows([]string{"kid", "public_key", "private_key", "created_at"}).
+			AddRow("0a1b2c3d4e5f6071", []byte("pub"), []byte("priv"), created))
+
+	pqConn = db
+	SigningKeys = &SigningKeyConn{}
+
+	keys, err := SigningKeys.List()
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("TestSigningKeyListModel got unexpected result: %v, %v", keys, err)
+	}
+	if k := keys[0]; k.KID != "0a1b2c3d4e5f6071" || string(k.PublicKey) != "pub" || !k.CreatedAt.Equal(created) {
+		t.Errorf("TestSigningKeyListModel got unexpected key: %+v", k)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestSigningKeyListModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,218 @@
+// Package accesstoken короткоживущие access токены вида JWT, подписанные ES256.
+// Сервис юзеров их выпускает, а остальные сервисы проверяют у себя по открытым
+// ключам из JWKS, не спрашивая сервис юзеров на каждый запрос
+package accesstoken
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Alg алгоритм подписи: ECDSA на P-256 с SHA-256
+const Alg = "ES256"
+
+// coordSize длина координаты и половины подписи P-256 в байтах
+const coordSize = 32
+
+var (
+	// ErrInvalid токен подделан, испорчен, истёк или выдан не тем издателем
+	ErrInvalid = errors.New("access_token_invalid")
+	// ErrUnknownKey токен подписан ключом, которого нет в наборе:
+	// возможно, набор пора перечитать
+	ErrUnknownKey = errors.New("access_token_unknown_key")
+)
+
+// Claims содержимое токена. Permissions права юзера на момент выпуска
+type Claims struct {
+	Issuer      string   `json:"iss"`
+	Subject     string   `json:"sub"`
+	SessionID   string   `json:"sid"`
+	Permissions []string `json:"perms,omitempty"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
+}
+
+// UserID id юзера из sub
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(ErrInvalid, "subject is not user id")
+	}
+
+	return id, nil
+}
+
+// HasPermission есть ли у юзера право
+func (c *Claims) HasPermission(permission string) bool {
+	for _, p := range c.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Sign подписывает claims ключом key с идентификатором kid
+func Sign(key *ecdsa.PrivateKey, kid string, c *Claims) (string, error) {
+	h, err := json.Marshal(&header{Alg: Alg, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", errors.Wrap(err, "header marshal error")
+	}
+	body, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "claims marshal error")
+	}
+
+	signed := encoding.EncodeToString(h) + "." + encoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "sign error")
+	}
+
+	sig := append(padded(r), padded(s)...)
+	return signed + "." + encoding.EncodeToString(sig), nil
+}
+
+// Parse проверяет подпись ключом из keys, срок на момент now и издателя,
+// если issuer не пустой
+func Parse(token string, keys map[string]*ecdsa.PublicKey, issuer string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalid
+	}
+
+	h := &header{}
+	if err := decodePart(parts[0], h); err != nil || h.Alg != Alg {
+		return nil, ErrInvalid
+	}
+	key, ok := keys[h.Kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil || len(sig) != 2*coordSize {
+		return nil, ErrInvalid
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(sig[:coordSize])
+	s := new(big.Int).SetBytes(sig[coordSize:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return nil, ErrInvalid
+	}
+
+	c := &Claims{}
+	if err = decodePart(parts[1], c); err != nil {
+		return nil, ErrInvalid
+	}
+	if now.Unix() >= c.ExpiresAt || (issuer != "" && c.Issuer != issuer) {
+		return nil, ErrInvalid
+	}
+
+	return c, nil
+}
+
+// padded число big-endian ровно в coordSize байт, как требуют JWS и JWK
+func padded(n *big.Int) []byte {
+	b := n.Bytes()
+	out := make([]byte, coordSize)
+	copy(out[coordSize-len(b):], b)
+	return out
+}
+
+func decodePart(part string, v interface{}) error {
+	data, err := encoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// JWK открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+// JWKS набор открытых ключей, которые отдаёт сервис юзеров
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK описывает открытый ключ P-256
+func NewJWK(kid string, pub *ecdsa.PublicKey) JWK {
+	return JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   encoding.EncodeToString(padded(pub.X)),
+		Y:   encoding.EncodeToString(padded(pub.Y)),
+		Kid: kid,
+		Use: "sig",
+		Alg: Alg,
+	}
+}
+
+// PublicKey восстанавливает ключ из JWK
+func (k *JWK) PublicKey() (*ecdsa.PublicKey, error) {
+	if k.Kty != "EC" || k.Crv != "P-256" {
+		return nil, errors.Errorf("unsupported key %s/%s", k.Kty, k.Crv)
+	}
+
+	x, err := encoding.DecodeString(k.X)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad x")
+	}
+	y, err := encoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad y")
+	}
+
+	pub := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("point is not on curve")
+	}
+
+	return pub, nil
+}
+
+// PublicKeys ключи набора по kid, ключи, которые не удалось прочитать, пропускаются
+func (s *JWKS) PublicKeys() map[string]*ecdsa.PublicKey {
+	keys := make(map[string]*ecdsa.PublicKey, len(s.Keys))
+	for i := range s.Keys {
+		if pub, err := s.Keys[i].PublicKey(); err == nil {
+			keys[s.Keys[i].Kid] = pub
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,102 @@
+package accesstoken
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("can not generate key: %v", err)
+	}
+
+	return key
+}
+
+func TestSignParse(t *testing.T) {
+	key := newTestKey(t)
+	other := newTestKey(t)
+	now := time.Unix(1558000000, 0)
+	keys := map[string]*ecdsa.PublicKey{"k1": &key.PublicKey, "k2": &other.PublicKey}
+
+	claims := &Claims{
+		Issuer:      "warscript-users",
+		Subject:     "42",
+		SessionID:   "03ac674216f3e15c",
+		Permissions: []string{"games.moderate"},
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(5 * time.Minute).Unix(),
+	}
+	token, err := Sign(key, "k1", claims)
+	if err != nil {
+		t.Fatalf("TestSignParse sign error: %v", err)
+	}
+
+	got, err := Parse(token, keys, "warscript-users", now)
+	if err != nil {
+		t.Fatalf("TestSignParse got unexpected error: %v", err)
+	}
+	if id, _ := got.UserID(); id != 42 || !got.HasPermission("games.moderate") || got.HasPermission("users.manage") {
+		t.Errorf("TestSignParse got unexpected claims: %+v", got)
+	}
+
+	parts := strings.Split(token, ".")
+	forged, _ := json.Marshal(&Claims{Issuer: "warscript-users", Subject: "1", ExpiresAt: claims.ExpiresAt})
+	otherKid, _ := json.Marshal(&header{Alg: Alg, Typ: "JWT", Kid: "k2"})
+	noneAlg, _ := json.Marshal(&header{Alg: "none", Typ: "JWT", Kid: "k1"})
+
+	cases := []struct {
+		name     string
+		token    string
+		issuer   string
+		now      time.Time
+		expected error
+	}{
+		{name: "expired", token: token, issuer: "warscript-users", now: now.Add(5 * time.Minute), expected: ErrInvalid},
+		{name: "issuer", token: token, issuer: "someone-else", now: now, expected: ErrInvalid},
+		{name: "claims", token: parts[0] + "." + encoding.EncodeToString(forged) + "." + parts[2], now: now, expected: ErrInvalid},
+		{name: "other key", token: encoding.EncodeToString(otherKid) + "." + parts[1] + "." + parts[2], now: now, expected: ErrInvalid},
+		{name: "alg", token: encoding.EncodeToString(noneAlg) + "." + parts[1] + ".", now: now, expected: ErrInvalid},
+		{name: "garbage", token: "kek", now: now, expected: ErrInvalid},
+		{name: "unknown kid", token: token, now: now, expected: nil},
+	}
+
+	for _, c := range cases {
+		k := keys
+		if c.name == "unknown kid" {
+			k = map[string]*ecdsa.PublicKey{"k2": &other.PublicKey}
+			c.expected = ErrUnknownKey
+		}
+		if _, err := Parse(c.token, k, c.issuer, c.now); err != c.expected {
+			t.Errorf("[%s] TestSignParse got unexpected error: %v, expected: %v", c.name, err, c.expected)
+		}
+	}
+}
+
+func TestJWK(t *testing.T) {
+	key := newTestKey(t)
+	jwk := NewJWK("k1", &key.PublicKey)
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" || jwk.Alg != Alg || len(jwk.X) != 43 || len(jwk.Y) != 43 {
+		t.Errorf("TestJWK got unexpected jwk: %+v", jwk)
+	}
+
+	data, err := json.Marshal(&JWKS{Keys: []JWK{jwk, {Kty: "RSA", Kid: "rsa"}}})
+	if err != nil {
+		t.Fatalf("TestJWK marshal error: %v", err)
+	}
+	set := &JWKS{}
+	if err = json.Unmarshal(data, set); err != nil {
+		t.Fatalf("TestJWK unmarshal error: %v", err)
+	}
+
+	keys := set.PublicKeys()
+	if len(keys) != 1 || keys["k1"].X.Cmp(key.X) != 0 || keys["k1"].Y.Cmp(key.Y) != 0 {
+		t.Errorf("TestJWK got unexpected keys: %v", keys)
+	}
+}
@@ -0,0 +1,178 @@
+package accesstoken
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// keysMaxAge как долго набор ключей считается свежим
+	keysMaxAge = time.Hour
+	// keysMinRefetch не чаще этого пытаемся перечитать набор, удачно или нет,
+	// чтобы мусорные токены и лежащий сервис юзеров не превращались в поток запросов
+	keysMinRefetch = time.Minute
+)
+
+// Verifier проверяет access токены по ключам из JWKS сервиса юзеров.
+// Ключи кешируются и перечитываются, когда устарели или встретился новый kid.
+// Если перечитать не вышло, работаем на ключах из кеша
+type Verifier struct {
+	url    string
+	issuer string
+	client *http.Client
+	logger *logrus.Logger
+
+	mu        sync.RWMutex
+	keys      map[string]*ecdsa.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+}
+
+// NewVerifier создаёт проверяльщик с JWKS по адресу jwksURL.
+// Если issuer не пустой, токены других издателей не принимаются
+func NewVerifier(jwksURL, issuer string, l *logrus.Logger) *Verifier {
+	return &Verifier{
+		url:    jwksURL,
+		issuer: issuer,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: l,
+		keys:   make(map[string]*ecdsa.PublicKey),
+	}
+}
+
+func (v *Verifier) cached() (map[string]*ecdsa.PublicKey, time.Time) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.keys, v.fetchedAt
+}
+
+// fetch перечитывает набор ключей
+func (v *Verifier) fetch() error {
+	resp, err := v.client.Get(v.url)
+	if err != nil {
+		return errors.Wrap(err, "jwks request error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("jwks request failed with status %d", resp.StatusCode)
+	}
+
+	set := &JWKS{}
+	if err = json.NewDecoder(resp.Body).Decode(set); err != nil {
+		return errors.Wrap(err, "jwks decode error")
+	}
+
+	v.mu.Lock()
+	v.keys = set.PublicKeys()
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	return nil
+}
+
+// refresh перечитывает набор, если с прошлой попытки прошло keysMinRefetch.
+// Ошибку только пишем в лог: ключи из кеша остаются в силе.
+// Возвращает true, если набор обновился
+func (v *Verifier) refresh() bool {
+	v.mu.Lock()
+	if time.Since(v.triedAt) < keysMinRefetch {
+		v.mu.Unlock()
+		return false
+	}
+	v.triedAt = time.Now()
+	v.mu.Unlock()
+
+	if err := v.fetch(); err != nil {
+		v.logger.Warnf("can not refresh jwks, using cached keys: %s", err)
+		return false
+	}
+
+	return true
+}
+
+// Verify проверяет токен и возвращает его содержимое
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	now := time.Now()
+	keys, fetchedAt := v.cached()
+	if now.Sub(fetchedAt) >= keysMaxAge && v.refresh() {
+		keys, _ = v.cached()
+	}
+
+	claims, err := Parse(token, keys, v.issuer, now)
+	if err == ErrUnknownKey && v.refresh() {
+		keys, _ = v.cached()
+		claims, err = Parse(token, keys, v.issuer, now)
+	}
+	if err == ErrUnknownKey {
+		return nil, ErrInvalid
+	}
+
+	return claims, err
+}
+
+type claimsKey struct{}
+
+// FromContext содержимое токена, который проверил WithAccessToken
+func FromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// bearerToken токен из заголовка Authorization: Bearer
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+
+	return ""
+}
+
+// WithAccessToken проверяет access токен из Authorization: Bearer и кладёт
+// юзера в контекст так же, как middlewares.WithAuthentication, так что
+// хендлеры не отличают токен от сессии
+//nolint: interfacer
+func WithAccessToken(next http.HandlerFunc, l *logrus.Logger, v *Verifier) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := utils.GetLogger(r, l, "WithAccessToken")
+		errWriter := utils.NewErrorResponseWriter(w, logger)
+
+		token := bearerToken(r)
+		if token == "" {
+			errWriter.WriteWarn(http.StatusUnauthorized, errors.New("access token is not presented"))
+			return
+		}
+
+		claims, err := v.Verify(token)
+		if err != nil {
+			if errors.Cause(err) == ErrInvalid {
+				errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "verify token error"))
+			} else {
+				errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "verify token error"))
+			}
+			return
+		}
+
+		userID, err := claims.UserID()
+		if err != nil {
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: userID})
+		ctx = context.WithValue(ctx, claimsKey{}, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,141 @@
+package accesstoken
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/logging"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+)
+
+func TestVerifier(t *testing.T) {
+	logger, _ := logging.NewLogger(ioutil.Discard, "")
+	key := newTestKey(t)
+	published := []JWK{}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(&JWKS{Keys: published}) //nolint: errcheck
+	}))
+	defer server.Close()
+
+	v := NewVerifier(server.URL, "warscript-users", logger)
+	claims := &Claims{Issuer: "warscript-users", Subject: "42", ExpiresAt: time.Now().Add(time.Minute).Unix()}
+	token, err := Sign(key, "k1", claims)
+	if err != nil {
+		t.Fatalf("TestVerifier sign error: %v", err)
+	}
+
+	// ключ ещё не опубликован
+	if _, err = v.Verify(token); err != ErrInvalid {
+		t.Errorf("TestVerifier got unexpected error: %v, expected: %v", err, ErrInvalid)
+	}
+
+	// ключ опубликован, но перечитать набор пока нельзя
+	published = []JWK{NewJWK("k1", &key.PublicKey)}
+	if _, err = v.Verify(token); err != ErrInvalid || fetches != 1 {
+		t.Errorf("TestVerifier got unexpected result: %v, %d fetches", err, fetches)
+	}
+
+	v.triedAt = time.Now().Add(-keysMinRefetch)
+	got, err := v.Verify(token)
+	if err != nil || got.Subject != "42" || fetches != 2 {
+		t.Errorf("TestVerifier got unexpected result: %+v, %v, %d fetches", got, err, fetches)
+	}
+
+	// знакомый ключ не требует запроса
+	if _, err = v.Verify(token); err != nil || fetches != 2 {
+		t.Errorf("TestVerifier got unexpected result: %v, %d fetches", err, fetches)
+	}
+}
+
+func TestVerifierFetchError(t *testing.T) {
+	logger, _ := logging.NewLogger(ioutil.Discard, "")
+	key := newTestKey(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	v := NewVerifier(server.URL, "", logger)
+	v.keys = map[string]*ecdsa.PublicKey{"k1": &key.PublicKey}
+	v.fetchedAt = time.Now().Add(-keysMaxAge)
+
+	known, _ := Sign(key, "k1", &Claims{Subject: "42", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	unknown, _ := Sign(key, "k2", &Claims{Subject: "42", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+
+	// набор устарел, а сервис лежит: проверяем старыми ключами
+	if _, err := v.Verify(known); err != nil || fetches != 1 {
+		t.Errorf("TestVerifierFetchError got unexpected result: %v, %d fetches", err, fetches)
+	}
+
+	// незнакомый kid не долбит лежащий сервис
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(unknown); err != ErrInvalid {
+			t.Errorf("TestVerifierFetchError got unexpected error: %v, expected: %v", err, ErrInvalid)
+		}
+	}
+	if _, err := v.Verify(known); err != nil || fetches != 1 {
+		t.Errorf("TestVerifierFetchError got unexpected result: %v, %d fetches", err, fetches)
+	}
+
+	v.triedAt = time.Now().Add(-keysMinRefetch)
+	if _, err := v.Verify(unknown); err != ErrInvalid || fetches != 2 {
+		t.Errorf("TestVerifierFetchError got unexpected result: %v, %d fetches", err, fetches)
+	}
+}
+
+func TestWithAccessToken(t *testing.T) {
+	logger, _ := logging.NewLogger(ioutil.Discard, "")
+	key := newTestKey(t)
+	v := NewVerifier("http://127.0.0.1:0", "", logger)
+	v.keys = map[string]*ecdsa.PublicKey{"k1": &key.PublicKey}
+	v.fetchedAt = time.Now()
+
+	handler := WithAccessToken(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := r.Context().Value(middlewares.SessionInfoKey).(*models.SessionPayload)
+		claims := FromContext(r.Context())
+		if !ok || info.ID != 42 || claims == nil || claims.SessionID != "s1" {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}, logger, v)
+
+	valid, _ := Sign(key, "k1", &Claims{Subject: "42", SessionID: "s1", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	expired, _ := Sign(key, "k1", &Claims{Subject: "42", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	notUser, _ := Sign(key, "k1", &Claims{Subject: "bot", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+
+	cases := []struct {
+		auth     string
+		expected int
+	}{
+		{auth: "Bearer " + valid, expected: http.StatusOK},
+		{auth: "bearer " + valid, expected: http.StatusOK},
+		{auth: "", expected: http.StatusUnauthorized},
+		{auth: "Basic " + valid, expected: http.StatusUnauthorized},
+		{auth: "Bearer " + expired, expected: http.StatusUnauthorized},
+		{auth: "Bearer " + notUser, expected: http.StatusUnauthorized},
+	}
+
+	for i, c := range cases {
+		r := httptest.NewRequest("GET", "/bots", nil)
+		if c.auth != "" {
+			r.Header.Set("Authorization", c.auth)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != c.expected {
+			t.Errorf("[%d] TestWithAccessToken got unexpected code: %d, expected: %d", i, w.Code, c.expected)
+		}
+	}
+}
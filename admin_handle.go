@@ -0,0 +1,156 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// writeAdminError ответ на ошибку действия админа
+func writeAdminError(errWriter *utils.ErrorResponseWriter, err error) {
+	if validErr, ok := err.(*utils.ValidationError); ok {
+		errWriter.WriteValidationError(validErr)
+	} else if errors.Cause(err) == utils.ErrNotExists {
+		errWriter.WriteWarn(http.StatusNotFound, errors.Wrap(err, "user not exists"))
+	} else {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+	}
+}
+
+// adminTargetID id юзера, над которым действует админ
+func adminTargetID(r *http.Request) (int64, error) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["user_id"], 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "wrong format user_id")
+	}
+
+	return userID, nil
+}
+
+// AdminSearchUsers поиск юзеров по id, началу username или email: ?q=&limit=&offset=
+func AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "AdminSearchUsers")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))   //nolint: errcheck кривое значение значит по умолчанию
+	offset, _ := strconv.Atoi(query.Get("offset")) //nolint: errcheck
+
+	users, err := searchUsersImpl(query.Get("q"), limit, offset)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, users)
+}
+
+// adminAction общая часть действий админа над юзером без тела запроса
+func adminAction(w http.ResponseWriter, r *http.Request, name string,
+	action func(admin *models.SessionPayload, client *clientInfo, userID int64) error) {
+	logger := utils.GetLogger(r, logger, name)
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	userID, err := adminTargetID(r)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusNotFound, err)
+		return
+	}
+
+	if err = action(info, newClientInfo(r), userID); err != nil {
+		writeAdminError(errWriter, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AdminBanUser выключает аккаунт и выкидывает юзера со всех устройств
+func AdminBanUser(w http.ResponseWriter, r *http.Request) {
+	adminAction(w, r, "AdminBanUser", func(admin *models.SessionPayload, client *clientInfo, userID int64) error {
+		return setUserActiveImpl(admin, client, userID, false)
+	})
+}
+
+// AdminUnbanUser включает аккаунт обратно
+func AdminUnbanUser(w http.ResponseWriter, r *http.Request) {
+	adminAction(w, r, "AdminUnbanUser", func(admin *models.SessionPayload, client *clientInfo, userID int64) error {
+		return setUserActiveImpl(admin, client, userID, true)
+	})
+}
+
+// AdminLogoutUser завершает все сессии юзера
+func AdminLogoutUser(w http.ResponseWriter, r *http.Request) {
+	adminAction(w, r, "AdminLogoutUser", forceLogoutImpl)
+}
+
+// AdminUnlockUser снимает блокировку входа после неудачных попыток
+func AdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	adminAction(w, r, "AdminUnlockUser", unlockUserImpl)
+}
+
+// AdminResetPassword сбрасывает пароль юзера
+func AdminResetPassword(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "AdminResetPassword")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	userID, err := adminTargetID(r)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusNotFound, err)
+		return
+	}
+
+	result, err := forcePasswordResetImpl(info, newClientInfo(r), userID)
+	if err != nil {
+		writeAdminError(errWriter, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, result)
+}
+
+// AdminRenameUser меняет username юзера
+func AdminRenameUser(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "AdminRenameUser")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	userID, err := adminTargetID(r)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusNotFound, err)
+		return
+	}
+
+	form := &jmodels.FormUsername{}
+	if err = utils.DecodeBodyJSON(r.Body, form); err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = renameUserImpl(info, newClientInfo(r), userID, form); err != nil {
+		writeAdminError(errWriter, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
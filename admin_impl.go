@@ -0,0 +1,220 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// adminSearchMaxLimit сколько юзеров максимум отдаёт поиск за раз
+const adminSearchMaxLimit = 100
+
+func searchUsersImpl(query string, limit, offset int) ([]*jmodels.AdminUser, error) {
+	if limit <= 0 || limit > adminSearchMaxLimit {
+		limit = adminSearchMaxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, err := Users.Search(jmodels.NormalizeUsername(query), limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "users search error")
+	}
+
+	found := make([]*jmodels.AdminUser, 0, len(users))
+	for _, u := range users {
+		roles, err := Roles.List(u.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "get roles error")
+		}
+
+		found = append(found, &jmodels.AdminUser{
+			InfoUser: jmodels.InfoUser{
+				ID:     u.ID,
+				Active: u.Active,
+				BasicUser: jmodels.BasicUser{
+					Username:  u.Username,
+					PhotoUUID: u.GetPhotoUUID(),
+				},
+			},
+			Email:         u.GetEmail(),
+			EmailVerified: u.EmailVerified,
+			Roles:         roles,
+		})
+	}
+
+	return found, nil
+}
+
+// setUserActiveImpl бан и разбан. Забаненного выкидывает со всех устройств, его личные токены отзываются.
+// Здесь и дальше действие сначала пишется в аудит и выполняется,
+// только если запись удалась: действий админа без следа быть не должно
+func setUserActiveImpl(admin *models.SessionPayload, client *clientInfo, userID int64, active bool) error {
+	if admin.ID == userID {
+		return &utils.ValidationError{
+			"user_id": utils.ErrInvalid.Error(),
+		}
+	}
+
+	event := AuditAdminBan
+	if active {
+		event = AuditAdminUnban
+	}
+	if err := auditImpl(event, userID, admin.ID, client, nil); err != nil {
+		return err
+	}
+
+	if err := Users.SetActive(userID, active); err != nil {
+		return errors.Wrap(err, "user set active error")
+	}
+
+	if !active {
+		if err := Sessions.DeleteAllForUser(userID); err != nil {
+			return errors.Wrap(err, "sessions revoke error")
+		}
+		if err := PersonalTokens.DeleteAllForUser(userID); err != nil {
+			return errors.Wrap(err, "personal tokens revoke error")
+		}
+	}
+
+	return nil
+}
+
+// forceLogoutImpl завершает все сессии юзера и отзывает его личные токены
+func forceLogoutImpl(admin *models.SessionPayload, client *clientInfo, userID int64) error {
+	if _, err := Users.GetUserByID(userID); err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	if err := auditImpl(AuditAdminLogout, userID, admin.ID, client, nil); err != nil {
+		return err
+	}
+
+	if err := Sessions.DeleteAllForUser(userID); err != nil {
+		return errors.Wrap(err, "sessions revoke error")
+	}
+	if err := PersonalTokens.DeleteAllForUser(userID); err != nil {
+		return errors.Wrap(err, "personal tokens revoke error")
+	}
+
+	return nil
+}
+
+// forcePasswordResetImpl заменяет пароль юзера случайным, завершает его сессии и отзывает личные токены.
+// Если у юзера есть подтверждённый email, туда уходит ссылка для нового пароля,
+// иначе восстановить доступ можно только через поддержку
+func forcePasswordResetImpl(admin *models.SessionPayload, client *clientInfo,
+	userID int64) (*jmodels.AdminPasswordReset, error) {
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user error")
+	}
+
+	notify := user.Active && user.Email.Valid && user.EmailVerified
+	err = auditImpl(AuditAdminPasswordReset, userID, admin.ID, client, map[string]string{
+		"notified": strconv.FormatBool(notify),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// пароль никто не знает, так что войти со старым уже не получится
+	random := uuid.New().String()
+	user.Password = &random
+	if err = Users.Save(user); err != nil {
+		return nil, errors.Wrap(err, "user save error")
+	}
+
+	if err = Sessions.DeleteAllForUser(userID); err != nil {
+		return nil, errors.Wrap(err, "sessions revoke error")
+	}
+	if err = PersonalTokens.DeleteAllForUser(userID); err != nil {
+		return nil, errors.Wrap(err, "personal tokens revoke error")
+	}
+
+	if !notify {
+		return &jmodels.AdminPasswordReset{Notified: false}, nil
+	}
+
+	token, err := PasswordResets.Create(user.ID, passwordResetTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, "password reset token error")
+	}
+	if err = notifier.PasswordReset(user, publicLink("/password-reset", token)); err != nil {
+		return nil, errors.Wrap(err, "password reset notify error")
+	}
+
+	return &jmodels.AdminPasswordReset{Notified: true}, nil
+}
+
+// renameUserImpl меняет username юзера. Новый username проходит ту же политику,
+// что и при регистрации
+func renameUserImpl(admin *models.SessionPayload, client *clientInfo, userID int64,
+	form *jmodels.FormUsername) error {
+	username := jmodels.NormalizeUsername(form.Username)
+	if valErr := jmodels.UsernameRules.Validate("username", username); valErr != nil {
+		return valErr
+	}
+
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	err = auditImpl(AuditAdminRename, userID, admin.ID, client, map[string]string{
+		"old": user.Username,
+		"new": username,
+	})
+	if err != nil {
+		return err
+	}
+
+	user.Username = username
+	if err = Users.Save(user); err != nil {
+		if errors.Cause(err) == utils.ErrTaken {
+			return &utils.ValidationError{
+				"username": utils.ErrTaken.Error(),
+			}
+		}
+		return errors.Wrap(err, "user save error")
+	}
+
+	return nil
+}
+
+// roleCommand выдаёт и забирает роли из консоли: так появляется первый админ
+func roleCommand(args []string) error {
+	if len(args) != 3 || (args[0] != "grant" && args[0] != "revoke") {
+		return errors.New("usage: role grant|revoke <username> <role>")
+	}
+
+	user, err := Users.GetUserByUsername(jmodels.NormalizeUsername(args[1]))
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	event := AuditRoleGrant
+	if args[0] == "revoke" {
+		event = AuditRoleRevoke
+	}
+	if err = auditImpl(event, user.ID, 0, &clientInfo{}, map[string]string{"role": args[2]}); err != nil {
+		return err
+	}
+
+	if args[0] == "grant" {
+		err = Roles.Grant(user.ID, args[2])
+	} else {
+		err = Roles.Revoke(user.ID, args[2])
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("role %s of user %s: %s", args[2], user.Username, args[0])
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/gorilla/mux"
+)
+
+// initAdminTests юзер 1 админ, юзер 2 обычный с подтверждённым email
+func initAdminTests() {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "admin1", Password: &pass, Active: true}
+	Users.(*usersTest).users[2] = UserModel{ID: 2, Username: "golang", Password: &pass, Active: true,
+		Email: sql.NullString{String: "golang@mail.ru", Valid: true}, EmailVerified: true}
+	Users.(*usersTest).users[3] = UserModel{ID: 3, Username: "gopher", Password: &pass, Active: true}
+	Roles.(*rolesTest).roles[1] = []string{RoleAdmin}
+	Sessions.(*sessionsTest).owners = map[string]int64{"golang-session": 2}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":2}`)
+}
+
+// adminRequest запрос от имени юзера actor через роутер, как в main
+func adminRequest(actor int64, method, target, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	withActor := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: actor})
+			WithPermission(h, permissions.ManageUsers, &LocalPermissionsClient{})(w, r.WithContext(ctx))
+		}
+	}
+	router.HandleFunc("/admin/users", withActor(AdminSearchUsers)).Methods("GET")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/ban", withActor(AdminBanUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/unban", withActor(AdminUnbanUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/logout", withActor(AdminLogoutUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/unlock", withActor(AdminUnlockUser)).Methods("POST")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/password-reset", withActor(AdminResetPassword)).Methods("POST")
+	router.HandleFunc("/admin/users/{user_id:[0-9]+}/username", withActor(AdminRenameUser)).Methods("PUT")
+	router.HandleFunc("/admin/events", withActor(AdminListEvents)).Methods("GET")
+
+	r := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminRoleRequired(t *testing.T) {
+	initAdminTests()
+
+	if w := adminRequest(2, "POST", "/admin/users/3/ban", ``); w.Code != http.StatusForbidden {
+		t.Errorf("TestAdminRoleRequired got unexpected code: %d, expected: %d", w.Code, http.StatusForbidden)
+	}
+	if !Users.(*usersTest).users[3].Active {
+		t.Errorf("TestAdminRoleRequired user was banned without role")
+	}
+
+	// модератору админка не положена
+	Roles.(*rolesTest).roles[2] = []string{RoleModerator}
+	if w := adminRequest(2, "POST", "/admin/users/3/ban", ``); w.Code != http.StatusForbidden {
+		t.Errorf("TestAdminRoleRequired got unexpected code: %d, expected: %d", w.Code, http.StatusForbidden)
+	}
+
+	Roles.(*rolesTest).SetNextFail(utils.ErrInternal)
+	if w := adminRequest(1, "POST", "/admin/users/3/ban", ``); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestAdminRoleRequired got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestAdminSearchUsers(t *testing.T) {
+	initAdminTests()
+
+	w := adminRequest(1, "GET", "/admin/users?q=GO&limit=1&offset=1", ``)
+	if w.Code != http.StatusOK {
+		t.Fatalf("TestAdminSearchUsers got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+
+	users := make([]*jmodels.AdminUser, 0)
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil {
+		t.Fatalf("TestAdminSearchUsers got unexpected error: %v, expected: %v", err, nil)
+	}
+	if len(users) != 1 || users[0].ID != 3 || users[0].Username != "gopher" {
+		t.Errorf("TestAdminSearchUsers got unexpected users: %s", w.Body.String())
+	}
+
+	w = adminRequest(1, "GET", "/admin/users?q=1", ``)
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil || len(users) != 1 ||
+		len(users[0].Roles) != 1 || users[0].Roles[0] != RoleAdmin {
+		t.Errorf("TestAdminSearchUsers got unexpected users: %s", w.Body.String())
+	}
+}
+
+func TestAdminBanUser(t *testing.T) {
+	initAdminTests()
+	audit := AuditEvents.(*auditEventsTest)
+
+	if w := adminRequest(1, "POST", "/admin/users/1/ban", ``); w.Code != http.StatusBadRequest {
+		t.Errorf("TestAdminBanUser got unexpected code on self ban: %d, expected: %d", w.Code, http.StatusBadRequest)
+	}
+	if w := adminRequest(1, "POST", "/admin/users/42/ban", ``); w.Code != http.StatusNotFound {
+		t.Errorf("TestAdminBanUser got unexpected code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+
+	// без записи в аудит ничего не делаем
+	audit.SetNextFail(utils.ErrInternal)
+	if w := adminRequest(1, "POST", "/admin/users/2/ban", ``); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestAdminBanUser got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+	if !Users.(*usersTest).users[2].Active {
+		t.Errorf("TestAdminBanUser user was banned without audit")
+	}
+
+	audit.events = nil
+	if w := adminRequest(1, "POST", "/admin/users/2/ban", ``); w.Code != http.StatusOK {
+		t.Errorf("TestAdminBanUser got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	if Users.(*usersTest).users[2].Active {
+		t.Errorf("TestAdminBanUser user was not banned")
+	}
+	if _, ok := Sessions.(*sessionsTest).sessions["golang-session"]; ok {
+		t.Errorf("TestAdminBanUser sessions were not revoked")
+	}
+
+	// забаненный не включит аккаунт сам
+	form := &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: "golang"}, Password: "golang4ever"}
+	if err := reactivateUserImpl(form, &clientInfo{}); err == nil {
+		t.Errorf("TestAdminBanUser banned user reactivated the account")
+	}
+
+	if w := adminRequest(1, "POST", "/admin/users/2/unban", ``); w.Code != http.StatusOK {
+		t.Errorf("TestAdminBanUser got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	if !Users.(*usersTest).users[2].Active {
+		t.Errorf("TestAdminBanUser user was not unbanned")
+	}
+
+	if len(audit.events) != 2 || audit.events[0].Event != AuditAdminBan || audit.events[1].Event != AuditAdminUnban ||
+		audit.events[0].ActorID.Int64 != 1 || audit.events[0].UserID.Int64 != 2 {
+		t.Errorf("TestAdminBanUser got unexpected audit: %+v", audit.events)
+	}
+}
+
+func TestAdminLogoutUser(t *testing.T) {
+	initAdminTests()
+
+	if w := adminRequest(1, "POST", "/admin/users/2/logout", ``); w.Code != http.StatusOK {
+		t.Errorf("TestAdminLogoutUser got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	if _, ok := Sessions.(*sessionsTest).sessions["golang-session"]; ok {
+		t.Errorf("TestAdminLogoutUser sessions were not revoked")
+	}
+	if events := AuditEvents.(*auditEventsTest).events; len(events) != 1 || events[0].Event != AuditAdminLogout {
+		t.Errorf("TestAdminLogoutUser got unexpected audit: %+v", events)
+	}
+}
+
+func TestAdminUnlockUser(t *testing.T) {
+	initAdminTests()
+	if err := LoginAttempts.Lock(loginUserSubject(2), time.Hour); err != nil {
+		t.Fatalf("TestAdminUnlockUser got unexpected error: %v", err)
+	}
+
+	if w := adminRequest(1, "POST", "/admin/users/2/unlock", ``); w.Code != http.StatusOK {
+		t.Errorf("TestAdminUnlockUser got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	if locked, _ := LoginAttempts.LockedFor(loginUserSubject(2)); locked != 0 {
+		t.Errorf("TestAdminUnlockUser login is still locked for %v", locked)
+	}
+	if events := AuditEvents.(*auditEventsTest).events; len(events) != 1 || events[0].Event != AuditAdminUnlock {
+		t.Errorf("TestAdminUnlockUser got unexpected audit: %+v", events)
+	}
+
+	if w := adminRequest(1, "POST", "/admin/users/42/unlock", ``); w.Code != http.StatusNotFound {
+		t.Errorf("TestAdminUnlockUser got unexpected code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	initAdminTests()
+	mails := &mailerTest{}
+	mailer = mails
+
+	cases := []struct {
+		target   string
+		notified bool
+	}{
+		{"/admin/users/2/password-reset", true},
+		{"/admin/users/3/password-reset", false}, // email нет
+	}
+	for _, c := range cases {
+		w := adminRequest(1, "POST", c.target, ``)
+		result := &jmodels.AdminPasswordReset{}
+		if err := json.Unmarshal(w.Body.Bytes(), result); err != nil || w.Code != http.StatusOK || result.Notified != c.notified {
+			t.Errorf("TestAdminResetPassword %s got unexpected response: %d %s", c.target, w.Code, w.Body.String())
+		}
+	}
+
+	for _, id := range []int64{2, 3} {
+		if *Users.(*usersTest).users[id].Password == "golang4ever" {
+			t.Errorf("TestAdminResetPassword password of user %d was not changed", id)
+		}
+	}
+	if len(mails.sent) != 1 || mails.sent[0].To != "golang@mail.ru" {
+		t.Errorf("TestAdminResetPassword got unexpected mails: %+v", mails.sent)
+	}
+	if _, ok := Sessions.(*sessionsTest).sessions["golang-session"]; ok {
+		t.Errorf("TestAdminResetPassword sessions were not revoked")
+	}
+}
+
+func TestAdminRenameUser(t *testing.T) {
+	initAdminTests()
+
+	cases := []struct {
+		payload string
+		code    int
+		body    string
+	}{
+		{`{"username":"a"}`, http.StatusBadRequest, `{"username":"too_short"}`},
+		{`{"username":"admin"}`, http.StatusBadRequest, `{"username":"reserved"}`},
+		{`{"username":" Kek "}`, http.StatusOK, ``},
+	}
+	for i, c := range cases {
+		w := adminRequest(1, "PUT", "/admin/users/2/username", c.payload)
+		if w.Code != c.code || w.Body.String() != c.body {
+			t.Errorf("[%d] TestAdminRenameUser got unexpected response: %d %s, expected: %d %s",
+				i, w.Code, w.Body.String(), c.code, c.body)
+		}
+	}
+
+	if u := Users.(*usersTest).users[2]; u.Username != "Kek" {
+		t.Errorf("TestAdminRenameUser user was not renamed: %+v", u)
+	}
+	events := AuditEvents.(*auditEventsTest).events
+	last := events[len(events)-1]
+	if last.Event != AuditAdminRename || last.Details["old"] != "golang" || last.Details["new"] != "Kek" {
+		t.Errorf("TestAdminRenameUser got unexpected audit: %+v", last)
+	}
+}
+
+func TestSearchUsersModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").
+		WithArgs(`ke_k%`, `ke\_k\%%`, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "ke_k%", []byte{1, 2, 3}, true, nil, "lol", nil, false))
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	users, err := Users.Search(`ke_k%`, 10, 0)
+	if err != nil || len(users) != 1 || users[0].Username != "ke_k%" {
+		t.Errorf("TestSearchUsersModel got unexpected result: %v, %v", users, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestSearchUsersModel there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAuditWriteModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO audit_events").
+		WithArgs(2, 1, AuditAdminRename, "127.0.0.1", []byte(`{"new":"kek","old":"lol"}`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+
+	pqConn = db
+	AuditEvents = &AuditConn{}
+
+	err = auditImpl(AuditAdminRename, 2, 1, &clientInfo{IP: "127.0.0.1"}, map[string]string{"old": "lol", "new": "kek"})
+	if err != nil {
+		t.Errorf("TestAuditWriteModel got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestAuditWriteModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/pkg/errors"
+)
+
+// auditMaxLimit сколько событий максимум отдаём за раз
+const auditMaxLimit = 100
+
+// События журнала аудита
+const (
+	AuditRegister       = "register"
+	AuditLogin          = "login"
+	AuditLoginFailed    = "login_failed"
+	AuditLogout         = "logout"
+	AuditPasswordChange = "password_change"
+	AuditUsernameChange = "username_change"
+	AuditEmailChange    = "email_change"
+	AuditIdentityLink   = "identity_link"
+	AuditIdentityUnlink = "identity_unlink"
+	AuditVkSecretRotate = "vk_secret_rotate"
+	AuditVkSecretRevoke = "vk_secret_revoke"
+	AuditTokenCreate    = "personal_token_create"
+	AuditTokenRevoke    = "personal_token_revoke"
+	AuditPasswordReset  = "password_reset"
+	AuditTwoFactorOn    = "two_factor_enable"
+	AuditTwoFactorOff   = "two_factor_disable"
+	AuditDeactivate     = "deactivate"
+	AuditReactivate     = "reactivate"
+
+	AuditAdminBan           = "admin_ban"
+	AuditAdminUnban         = "admin_unban"
+	AuditAdminLogout        = "admin_logout"
+	AuditAdminPasswordReset = "admin_password_reset"
+	AuditAdminRename        = "admin_rename"
+	AuditAdminUnlock        = "admin_unlock"
+	AuditRoleGrant          = "role_grant"
+	AuditRoleRevoke         = "role_revoke"
+)
+
+// auditImpl пишет событие юзера userID, которое совершил actorID.
+// Нулевой id значит, что его нет
+func auditImpl(event string, userID, actorID int64, client *clientInfo, details map[string]string) error {
+	e := &AuditEvent{
+		UserID:  sql.NullInt64{Int64: userID, Valid: userID != 0},
+		ActorID: sql.NullInt64{Int64: actorID, Valid: actorID != 0},
+		Event:   event,
+		IP:      client.IP,
+		Details: details,
+	}
+	if err := AuditEvents.Write(e); err != nil {
+		return errors.Wrap(err, "audit write error")
+	}
+
+	return nil
+}
+
+// auditUserImpl пишет событие, которое юзер совершил сам. В отличие от действий
+// админа, из-за недоступного журнала юзеру не отказываем, только логируем
+func auditUserImpl(event string, userID int64, client *clientInfo, details map[string]string) {
+	if err := auditImpl(event, userID, 0, client, details); err != nil {
+		logger.Warnf("can not write audit event %s: %s", event, err)
+	}
+}
+
+// newAuditEventModel конвертирует событие из базы в json модель
+func newAuditEventModel(e *AuditEvent) *jmodels.AuditEvent {
+	return &jmodels.AuditEvent{
+		ID:        e.ID,
+		UserID:    e.UserID.Int64,
+		ActorID:   e.ActorID.Int64,
+		Event:     e.Event,
+		IP:        e.IP,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// listAuditEventsImpl события по фильтру для админки
+func listAuditEventsImpl(f *AuditFilter) ([]*jmodels.AuditEvent, error) {
+	if f.Limit <= 0 || f.Limit > auditMaxLimit {
+		f.Limit = auditMaxLimit
+	}
+
+	events, err := AuditEvents.List(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "audit list error")
+	}
+
+	found := make([]*jmodels.AuditEvent, 0, len(events))
+	for _, e := range events {
+		found = append(found, newAuditEventModel(e))
+	}
+
+	return found, nil
+}
+
+// listOwnEventsImpl история аккаунта для самого юзера. Кто из админов
+// совершал действия, юзеру не показываем
+func listOwnEventsImpl(info *models.SessionPayload, beforeID int64, limit int) ([]*jmodels.AuditEvent, error) {
+	events, err := listAuditEventsImpl(&AuditFilter{
+		UserID:   info.ID,
+		BeforeID: beforeID,
+		Limit:    limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range events {
+		e.UserID = 0
+		e.ActorID = 0
+	}
+
+	return events, nil
+}
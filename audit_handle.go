@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+)
+
+// GetOwnEvents история аккаунта текущего юзера: ?before=&limit=
+func GetOwnEvents(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "GetOwnEvents")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	query := r.URL.Query()
+	before, _ := strconv.ParseInt(query.Get("before"), 10, 64) //nolint: errcheck кривое значение значит с начала
+	limit, _ := strconv.Atoi(query.Get("limit"))               //nolint: errcheck
+
+	events, err := listOwnEventsImpl(info, before, limit)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, events)
+}
+
+// auditFilterFromQuery фильтр админки:
+// ?user_id=&actor_id=&event=&ip=&since=&until=&before=&limit=, время в RFC 3339
+func auditFilterFromQuery(r *http.Request) (*AuditFilter, *utils.ValidationError) {
+	query := r.URL.Query()
+	f := &AuditFilter{
+		Event: query.Get("event"),
+		IP:    query.Get("ip"),
+	}
+
+	ids := map[string]*int64{
+		"user_id":  &f.UserID,
+		"actor_id": &f.ActorID,
+		"before":   &f.BeforeID,
+	}
+	for name, dst := range ids {
+		if v := query.Get(name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, &utils.ValidationError{name: utils.ErrInvalid.Error()}
+			}
+			*dst = id
+		}
+	}
+
+	times := map[string]*time.Time{
+		"since": &f.Since,
+		"until": &f.Until,
+	}
+	for name, dst := range times {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, &utils.ValidationError{name: utils.ErrInvalid.Error()}
+			}
+			*dst = t
+		}
+	}
+
+	f.Limit, _ = strconv.Atoi(query.Get("limit")) //nolint: errcheck кривое значение значит по умолчанию
+	return f, nil
+}
+
+// AdminListEvents журнал аудита с фильтрами
+func AdminListEvents(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "AdminListEvents")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	filter, valErr := auditFilterFromQuery(r)
+	if valErr != nil {
+		errWriter.WriteValidationError(valErr)
+		return
+	}
+
+	events, err := listAuditEventsImpl(filter)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// AuditAccessObject DAO for audit_events. Таблица только дописывается:
+// UPDATE и DELETE запрещены триггером
+type AuditAccessObject interface {
+	Write(e *AuditEvent) error
+	List(f *AuditFilter) ([]*AuditEvent, error)
+}
+
+// AuditConn implementation of AuditAccessObject
+type AuditConn struct{}
+
+// AuditEvents interface variable for models methods
+var AuditEvents AuditAccessObject
+
+func init() {
+	AuditEvents = &AuditConn{}
+}
+
+// AuditEvent событие, важное для безопасности аккаунта UserID.
+// ActorID тот, кто его совершил, если это не сам юзер
+type AuditEvent struct {
+	ID        int64
+	UserID    sql.NullInt64
+	ActorID   sql.NullInt64
+	Event     string
+	IP        string
+	Details   map[string]string
+	CreatedAt time.Time
+}
+
+// AuditFilter условия выборки событий, нулевые поля не фильтруют.
+// События идут от новых к старым, следующая страница начинается с BeforeID
+type AuditFilter struct {
+	UserID   int64
+	ActorID  int64
+	Event    string
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// Write дописывает событие, ID и CreatedAt заполняются базой
+func (ac *AuditConn) Write(e *AuditEvent) error {
+	details, err := json.Marshal(e.Details)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "audit details marshal error: %s", err.Error())
+	}
+	if e.Details == nil {
+		details = []byte("{}")
+	}
+
+	err = pqConn.QueryRow(`INSERT INTO audit_events (user_id, actor_id, event, ip, details)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at;`,
+		e.UserID, e.ActorID, e.Event, e.IP, details).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "audit write error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// List события по фильтру
+func (ac *AuditConn) List(f *AuditFilter) ([]*AuditEvent, error) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	where := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.UserID != 0 {
+		where("a.user_id = $%d", f.UserID)
+	}
+	if f.ActorID != 0 {
+		where("a.actor_id = $%d", f.ActorID)
+	}
+	if f.Event != "" {
+		where("a.event = $%d", f.Event)
+	}
+	if f.IP != "" {
+		where("a.ip = $%d", f.IP)
+	}
+	if !f.Since.IsZero() {
+		where("a.created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		where("a.created_at < $%d", f.Until)
+	}
+	if f.BeforeID != 0 {
+		where("a.id < $%d", f.BeforeID)
+	}
+
+	query := `SELECT a.id, a.user_id, a.actor_id, a.event, a.ip, a.details, a.created_at FROM audit_events a`
+	if len(conds) != 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" ORDER BY a.id DESC LIMIT $%d;", len(args))
+
+	rows, err := pqConn.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "audit list error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	events := make([]*AuditEvent, 0)
+	for rows.Next() {
+		e := &AuditEvent{}
+		var details []byte
+		err = rows.Scan(&e.ID, &e.UserID, &e.ActorID, &e.Event, &e.IP, &details, &e.CreatedAt)
+		if err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "audit event scan error: %s", err.Error())
+		}
+		if err = json.Unmarshal(details, &e.Details); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "audit details unmarshal error: %s", err.Error())
+		}
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "audit list read error: %s", err.Error())
+	}
+
+	return events, nil
+}
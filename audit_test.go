@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/mailru/easyjson/opt"
+)
+
+// auditEventsOf события юзера в порядке записи
+func auditEventsOf(userID int64) []string {
+	events := make([]string, 0)
+	for _, e := range AuditEvents.(*auditEventsTest).events {
+		if e.UserID.Int64 == userID {
+			events = append(events, e.Event)
+		}
+	}
+
+	return events
+}
+
+// hasAuditEvents есть ли у юзера события expected в этом порядке, возможно вперемешку с другими
+func hasAuditEvents(userID int64, expected ...string) bool {
+	for _, e := range auditEventsOf(userID) {
+		if len(expected) != 0 && e == expected[0] {
+			expected = expected[1:]
+		}
+	}
+
+	return len(expected) == 0
+}
+
+func loginForm(username, password string) *jmodels.FormUser {
+	return &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: username}, Password: password}
+}
+
+func TestAuditLogin(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	client := &clientInfo{IP: "10.0.0.1"}
+
+	if _, err := createSessionImpl(loginForm("golang", "wrong"), client); err == nil {
+		t.Fatalf("TestAuditLogin wrong password was accepted")
+	}
+	if _, err := createSessionImpl(loginForm("nobody", pass), client); err == nil {
+		t.Fatalf("TestAuditLogin unknown user was accepted")
+	}
+	session, err := createSessionImpl(loginForm("golang", pass), client)
+	if err != nil {
+		t.Fatalf("TestAuditLogin got unexpected error: %v", err)
+	}
+
+	events := AuditEvents.(*auditEventsTest).events
+	if len(events) != 3 {
+		t.Fatalf("TestAuditLogin got %d events, expected: 3", len(events))
+	}
+	if e := events[0]; e.Event != AuditLoginFailed || e.UserID.Int64 != 1 || e.IP != "10.0.0.1" {
+		t.Errorf("TestAuditLogin got unexpected wrong password event: %+v", e)
+	}
+	if e := events[1]; e.Event != AuditLoginFailed || e.UserID.Valid || e.Details["login"] != "nobody" {
+		t.Errorf("TestAuditLogin got unexpected unknown user event: %+v", e)
+	}
+	if e := events[2]; e.Event != AuditLogin || e.Details["session"] != session.PublicID() {
+		t.Errorf("TestAuditLogin got unexpected login event: %+v", e)
+	}
+
+	// журнал недоступен, но войти можно
+	AuditEvents.(*auditEventsTest).SetNextFail(utils.ErrInternal)
+	if _, err = createSessionImpl(loginForm("golang", pass), client); err != nil {
+		t.Errorf("TestAuditLogin got unexpected error with broken audit: %v", err)
+	}
+}
+
+func TestAuditUpdateUser(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+
+	form := &jmodels.FormUserUpdate{
+		Username:    opt.OString("gopher"),
+		OldPassword: opt.OString(pass),
+		NewPassword: opt.OString("rust4ever-maybe"),
+	}
+	if err := updateUserImpl(&models.SessionPayload{ID: 1}, "1234", &clientInfo{}, form); err != nil {
+		t.Fatalf("TestAuditUpdateUser got unexpected error: %v", err)
+	}
+
+	events := auditEventsOf(1)
+	expected := []string{AuditUsernameChange, AuditPasswordChange}
+	if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+		t.Errorf("TestAuditUpdateUser got events: %v, expected: %v", events, expected)
+	}
+	if d := AuditEvents.(*auditEventsTest).events[0].Details; d["old"] != "golang" || d["new"] != "gopher" {
+		t.Errorf("TestAuditUpdateUser got unexpected details: %v", d)
+	}
+
+	// только фото: в журнал не пишется
+	form = &jmodels.FormUserUpdate{PhotoUUID: opt.OString("2eb4a823-3a6d-4cba-8767-4d4946890f4f")}
+	if err := updateUserImpl(&models.SessionPayload{ID: 1}, "1234", &clientInfo{}, form); err != nil {
+		t.Fatalf("TestAuditUpdateUser got unexpected error: %v", err)
+	}
+	if events = auditEventsOf(1); len(events) != 2 {
+		t.Errorf("TestAuditUpdateUser photo change was audited: %v", events)
+	}
+}
+
+func TestGetOwnEvents(t *testing.T) {
+	initTests()
+	audit := AuditEvents.(*auditEventsTest)
+	for _, e := range []*AuditEvent{
+		{UserID: sql.NullInt64{Int64: 1, Valid: true}, Event: AuditLogin},
+		{UserID: sql.NullInt64{Int64: 2, Valid: true}, Event: AuditLogin},
+		{UserID: sql.NullInt64{Int64: 1, Valid: true}, ActorID: sql.NullInt64{Int64: 2, Valid: true}, Event: AuditAdminLogout},
+		{UserID: sql.NullInt64{Int64: 1, Valid: true}, Event: AuditLogout},
+	} {
+		if err := audit.Write(e); err != nil {
+			t.Fatalf("TestGetOwnEvents write error: %v", err)
+		}
+	}
+
+	get := func(target string) (int, []*jmodels.AuditEvent) {
+		r := httptest.NewRequest("GET", target, nil)
+		r = r.WithContext(context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}))
+		w := httptest.NewRecorder()
+		GetOwnEvents(w, r)
+
+		events := make([]*jmodels.AuditEvent, 0)
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+				t.Fatalf("TestGetOwnEvents can not decode response: %v", err)
+			}
+		}
+		return w.Code, events
+	}
+
+	code, events := get("/users/me/events?limit=2")
+	if code != http.StatusOK || len(events) != 2 || events[0].ID != 4 || events[1].ID != 3 {
+		t.Fatalf("TestGetOwnEvents got unexpected result: %d, %+v", code, events)
+	}
+	if events[1].ActorID != 0 || events[1].UserID != 0 {
+		t.Errorf("TestGetOwnEvents admin is visible to user: %+v", events[1])
+	}
+
+	code, events = get("/users/me/events?before=3")
+	if code != http.StatusOK || len(events) != 1 || events[0].ID != 1 {
+		t.Errorf("TestGetOwnEvents got unexpected next page: %d, %+v", code, events)
+	}
+
+	audit.SetNextFail(utils.ErrInternal)
+	if code, _ = get("/users/me/events"); code != http.StatusInternalServerError {
+		t.Errorf("TestGetOwnEvents got unexpected code: %d, expected: %d", code, http.StatusInternalServerError)
+	}
+}
+
+func TestAdminListEvents(t *testing.T) {
+	initAdminTests()
+	audit := AuditEvents.(*auditEventsTest)
+	for _, e := range []*AuditEvent{
+		{UserID: sql.NullInt64{Int64: 2, Valid: true}, Event: AuditLogin, IP: "10.0.0.1"},
+		{UserID: sql.NullInt64{Int64: 3, Valid: true}, Event: AuditLoginFailed, IP: "10.0.0.2"},
+		{UserID: sql.NullInt64{Int64: 2, Valid: true}, Event: AuditLoginFailed, IP: "10.0.0.2"},
+	} {
+		if err := audit.Write(e); err != nil {
+			t.Fatalf("TestAdminListEvents write error: %v", err)
+		}
+	}
+
+	w := adminRequest(1, "GET", "/admin/events?event=login_failed&ip=10.0.0.2&user_id=2", ``)
+	events := make([]*jmodels.AuditEvent, 0)
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("TestAdminListEvents got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if len(events) != 1 || events[0].ID != 3 || events[0].UserID != 2 {
+		t.Errorf("TestAdminListEvents got unexpected events: %+v", events)
+	}
+
+	since := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w = adminRequest(1, "GET", "/admin/events?since="+since, ``)
+	if w.Code != http.StatusOK || w.Body.String() != `[]` {
+		t.Errorf("TestAdminListEvents got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(1, "GET", "/admin/events?since=yesterday", ``)
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"since":"invalid"}` {
+		t.Errorf("TestAdminListEvents got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	if w = adminRequest(2, "GET", "/admin/events", ``); w.Code != http.StatusForbidden {
+		t.Errorf("TestAdminListEvents got unexpected code: %d, expected: %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAuditListModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Now()
+	mock.ExpectQuery(`FROM audit_events a WHERE a.user_id = \$1 AND a.event = \$2 AND a.id < \$3 ORDER BY a.id DESC LIMIT \$4`).
+		WithArgs(2, AuditLogin, 10, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "actor_id", "event", "ip", "details", "created_at"}).
+			AddRow(9, 2, nil, AuditLogin, "127.0.0.1", []byte(`{"session":"03ac674216f3e15c"}`), created))
+
+	pqConn = db
+	AuditEvents = &AuditConn{}
+
+	events, err := AuditEvents.List(&AuditFilter{UserID: 2, Event: AuditLogin, BeforeID: 10, Limit: 5})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("TestAuditListModel got unexpected result: %v, %v", events, err)
+	}
+	if e := events[0]; e.ID != 9 || e.ActorID.Valid || e.Details["session"] != "03ac674216f3e15c" {
+		t.Errorf("TestAuditListModel got unexpected event: %+v", e)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestAuditListModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// clientInfo откуда пришёл запрос
+type clientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// trustedProxies балансеры перед сервисом. Только им верим
+// в X-Forwarded-For и X-Real-IP, остальные могут их подделать
+var trustedProxies []*net.IPNet
+
+// NewTrustedProxies разбирает адреса и подсети доверенных прокси
+func NewTrustedProxies(list []string) ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0, len(list))
+	for _, p := range list {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy %q", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy %q", p)
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newClientInfo достаёт адрес клиента и его user agent
+func newClientInfo(r *http.Request) *clientInfo {
+	return &clientInfo{
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// clientIP адрес клиента. Заголовки прокси смотрим, только если запрос пришёл
+// от доверенного прокси, и берём в X-Forwarded-For самый правый недоверенный
+// адрес: правее него адреса дописали наши прокси, левее мог написать клиент
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Join(r.Header["X-Forwarded-For"], ",")
+	if strings.TrimSpace(forwarded) == "" {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+			return realIP
+		}
+		return ip
+	}
+
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// порядок важен: Edge и Opera притворяются хромом, а хром сафари
+var (
+	knownBrowsers = []struct{ token, name string }{
+		{"Edg", "Edge"},
+		{"OPR", "Opera"},
+		{"Firefox", "Firefox"},
+		{"YaBrowser", "Yandex Browser"},
+		{"Chrome", "Chrome"},
+		{"Safari", "Safari"},
+	}
+	knownPlatforms = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Macintosh", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// deviceLabel человекочитаемое название устройства по user agent,
+// например "Chrome on Windows"
+func deviceLabel(userAgent string) string {
+	browser, platform := "", ""
+	for _, b := range knownBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, p := range knownPlatforms {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	default:
+		return "Unknown device"
+	}
+}
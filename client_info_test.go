@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeviceLabel(t *testing.T) {
+	cases := []struct {
+		userAgent string
+		expected  string
+	}{
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+				"(KHTML, like Gecko) Chrome/74.0.3729.169 Safari/537.36",
+			expected: "Chrome on Windows",
+		},
+		{
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.14; rv:67.0) Gecko/20100101 Firefox/67.0",
+			expected:  "Firefox on macOS",
+		},
+		{
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 12_2 like Mac OS X) AppleWebKit/605.1.15 " +
+				"(KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1",
+			expected: "Safari on iPhone",
+		},
+		{
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+				"(KHTML, like Gecko) Chrome/74.0.3729.169 Safari/537.36 Edg/74.1.96.24",
+			expected: "Edge on Windows",
+		},
+		{
+			userAgent: "curl/7.54.0",
+			expected:  "Unknown device",
+		},
+	}
+
+	for i, c := range cases {
+		if label := deviceLabel(c.userAgent); label != c.expected {
+			t.Errorf("[%d] TestDeviceLabel got: %s, expected: %s", i, label, c.expected)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	var err error
+	trustedProxies, err = NewTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"})
+	if err != nil {
+		t.Fatalf("TestClientIP got unexpected error: %v", err)
+	}
+	defer func() { trustedProxies = nil }()
+
+	cases := []struct {
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		// заголовки от клиента напрямую не смотрим
+		{remoteAddr: "1.2.3.4:12345", forwarded: "5.6.7.8", realIP: "5.6.7.9", expected: "1.2.3.4"},
+		{remoteAddr: "10.0.0.1:12345", expected: "10.0.0.1"},
+		{remoteAddr: "10.0.0.1:12345", realIP: "5.6.7.9", expected: "5.6.7.9"},
+		{remoteAddr: "10.0.0.1:12345", forwarded: "5.6.7.8", realIP: "5.6.7.9", expected: "5.6.7.8"},
+		// левые адреса мог подделать клиент, правые дописали наши прокси
+		{remoteAddr: "10.0.0.1:12345", forwarded: "6.6.6.6, 5.6.7.8, 192.168.1.1", expected: "5.6.7.8"},
+		{remoteAddr: "10.0.0.1:12345", forwarded: "192.168.1.2, 192.168.1.1", expected: "192.168.1.2"},
+		{remoteAddr: "10.0.0.1:12345", forwarded: "kek, 192.168.1.1", expected: "192.168.1.1"},
+	}
+
+	for i, c := range cases {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remoteAddr
+		if c.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", c.forwarded)
+		}
+		if c.realIP != "" {
+			r.Header.Set("X-Real-IP", c.realIP)
+		}
+		if ip := clientIP(r); ip != c.expected {
+			t.Errorf("[%d] TestClientIP got: %s, expected: %s", i, ip, c.expected)
+		}
+	}
+
+	if _, err = NewTrustedProxies([]string{"kek"}); err == nil {
+		t.Errorf("TestClientIP expected error for invalid proxy")
+	}
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/HotCodeGroup/warscript-utils/balancer"
+	"github.com/pkg/errors"
+
+	consulapi "github.com/hashicorp/consul/api"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// PostgresConfig параметры подключения к PostgreSQL
+type PostgresConfig struct {
+	User     string `json:"user"`
+	Pass     string `json:"pass"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Database string `json:"database"`
+}
+
+// RedisConfig параметры подключения к Redis
+type RedisConfig struct {
+	User     string `json:"user"`
+	Pass     string `json:"pass"`
+	Addr     string `json:"addr"`
+	Database string `json:"database"`
+}
+
+// ConsulConfig адрес консула; если пустой, сервис не регистрируется
+// и порты берутся только из конфига
+type ConsulConfig struct {
+	Addr string `json:"addr"`
+}
+
+// VaultConfig доступ к волту; если адрес пустой, креды баз
+// берутся только из конфига
+type VaultConfig struct {
+	Addr  string `json:"addr"`
+	Token string `json:"token"`
+}
+
+// SessionConfig время жизни сессий в секундах, 0 значит значение по умолчанию
+type SessionConfig struct {
+	TTL         int `json:"ttl"`
+	RememberTTL int `json:"remember_ttl"`
+	AbsoluteTTL int `json:"absolute_ttl"`
+}
+
+// PasswordConfig требования к паролям, 0 значит значение по умолчанию
+type PasswordConfig struct {
+	MinLength  int `json:"min_length"`
+	MaxLength  int `json:"max_length"`
+	MinClasses int `json:"min_classes"`
+
+	// CommonListPath файл со списком запрещённых паролей, по одному на строку
+	CommonListPath string `json:"common_list_path"`
+}
+
+// LoginConfig защита от перебора паролей, время в секундах, 0 значит значение по умолчанию
+type LoginConfig struct {
+	MaxFailures   int `json:"max_failures"`
+	IPMaxFailures int `json:"ip_max_failures"`
+	Window        int `json:"window"`
+	Lockout       int `json:"lockout"`
+	MaxLockout    int `json:"max_lockout"`
+}
+
+// RateLimitConfig лимит запросов на маршрут: Requests за Window секунд
+// от одного клиента, клиенты различаются по By (ip или user)
+type RateLimitConfig struct {
+	Requests int    `json:"requests"`
+	Window   int    `json:"window"`
+	By       string `json:"by"`
+}
+
+// UsernameConfig требования к username, 0 значит значение по умолчанию.
+// Если Reserved не задан, используется jmodels.DefaultReservedUsernames
+type UsernameConfig struct {
+	MinLength int      `json:"min_length"`
+	MaxLength int      `json:"max_length"`
+	Reserved  []string `json:"reserved"`
+}
+
+// HashingConfig хеширование паролей, 0 значит значение по умолчанию.
+// Algorithm bcrypt или argon2id, Argon2Memory в KiB
+type HashingConfig struct {
+	Algorithm     string `json:"algorithm"`
+	BcryptCost    int    `json:"bcrypt_cost"`
+	Argon2Time    int    `json:"argon2_time"`
+	Argon2Memory  int    `json:"argon2_memory"`
+	Argon2Threads int    `json:"argon2_threads"`
+}
+
+// MailConfig отправка писем. Driver smtp, file (дописывает письма в Path)
+// или stdout, который используется по умолчанию
+type MailConfig struct {
+	Driver   string `json:"driver"`
+	Path     string `json:"path"`
+	From     string `json:"from"`
+	SMTPAddr string `json:"smtp_addr"`
+	SMTPUser string `json:"smtp_user"`
+	SMTPPass string `json:"smtp_pass"`
+}
+
+// DeletionConfig удаление аккаунтов, время в секундах, 0 значит значение по умолчанию.
+// Выключенный аккаунт можно включить обратно в течение GracePeriod, потом он обезличивается
+type DeletionConfig struct {
+	GracePeriod   int `json:"grace_period"`
+	PurgeInterval int `json:"purge_interval"`
+}
+
+// TwoFactorConfig 2FA: Issuer название сервиса в приложении-аутентификаторе,
+// SecretKey ключ шифрования секретов TOTP в базе
+type TwoFactorConfig struct {
+	Issuer    string `json:"issuer"`
+	SecretKey string `json:"secret_key"`
+}
+
+// AccessTokenConfig подписанные access токены, время в секундах, 0 значит значение
+// по умолчанию. Пока Enabled false, токены не выдаются. KeySecret ключ шифрования
+// закрытых ключей подписи в базе, у всех экземпляров сервиса он должен быть один
+type AccessTokenConfig struct {
+	Enabled          bool   `json:"enabled"`
+	TTL              int    `json:"ttl"`
+	RotationInterval int    `json:"rotation_interval"`
+	Issuer           string `json:"issuer"`
+	KeySecret        string `json:"key_secret"`
+}
+
+// OAuthProviderConfig провайдер входа по OAuth2/OpenID Connect. RedirectURL страница
+// фронтенда, куда провайдер возвращает юзера с code и state. Если Scopes не заданы,
+// запрашиваются openid, email и profile
+type OAuthProviderConfig struct {
+	AuthURL      string   `json:"auth_url"`
+	TokenURL     string   `json:"token_url"`
+	UserInfoURL  string   `json:"userinfo_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// Config конфигурация сервиса
+type Config struct {
+	HTTPPort  int             `json:"http_port"`
+	GRPCPort  int             `json:"grpc_port"`
+	Postgres  PostgresConfig  `json:"postgres"`
+	Redis     RedisConfig     `json:"redis"`
+	Consul    ConsulConfig    `json:"consul"`
+	Vault     VaultConfig     `json:"vault"`
+	Session   SessionConfig   `json:"session"`
+	Login     LoginConfig     `json:"login"`
+	Username  UsernameConfig  `json:"username"`
+	Password  PasswordConfig  `json:"password"`
+	Hashing   HashingConfig   `json:"hashing"`
+	Mail      MailConfig      `json:"mail"`
+	TwoFactor TwoFactorConfig `json:"two_factor"`
+	Deletion  DeletionConfig  `json:"deletion"`
+
+	AccessTokens AccessTokenConfig `json:"access_tokens"`
+
+	// PublicURL адрес фронтенда, от него строятся ссылки в письмах
+	PublicURL string `json:"public_url"`
+	// TokenSecret ключ подписи одноразовых токенов из писем
+	TokenSecret string `json:"token_secret"`
+
+	// RateLimits лимиты по названию маршрута: register, login, username_check
+	RateLimits map[string]RateLimitConfig `json:"rate_limits"`
+
+	// OAuth провайдеры входа по названию, оно же часть адреса /oauth/{provider}
+	OAuth map[string]OAuthProviderConfig `json:"oauth"`
+
+	// TrustedProxies адреса или подсети балансеров, которым верим в X-Forwarded-For.
+	// Пока список пуст, адрес клиента берётся только из соединения
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// ShutdownTimeout сколько секунд ждём завершения запросов при остановке
+	ShutdownTimeout int `json:"shutdown_timeout"`
+}
+
+// ConfigProvider источник конфигурации. Провайдеры применяются
+// по очереди, каждый следующий перетирает то, что задал сам
+type ConfigProvider interface {
+	Load(c *Config) error
+}
+
+// LoadConfig собирает конфиг из провайдеров
+func LoadConfig(providers ...ConfigProvider) (*Config, error) {
+	c := &Config{
+		Postgres: PostgresConfig{
+			Port: "5432",
+		},
+		Redis: RedisConfig{
+			Database: "0",
+		},
+		ShutdownTimeout: 10,
+	}
+
+	for _, p := range providers {
+		if err := p.Load(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// FileConfigProvider читает конфиг из JSON файла
+type FileConfigProvider struct {
+	Path string
+}
+
+// Load применяет значения из файла, если путь задан
+func (p *FileConfigProvider) Load(c *Config) error {
+	if p.Path == "" {
+		return nil
+	}
+
+	f, err := os.Open(p.Path)
+	if err != nil {
+		return errors.Wrap(err, "can not open config file")
+	}
+	defer f.Close()
+
+	if err = json.NewDecoder(f).Decode(c); err != nil {
+		return errors.Wrap(err, "can not decode config file")
+	}
+
+	return nil
+}
+
+// EnvConfigProvider читает конфиг из переменных окружения
+type EnvConfigProvider struct{}
+
+// Load применяет заданные переменные окружения
+func (p *EnvConfigProvider) Load(c *Config) error {
+	strs := map[string]*string{
+		"CONSUL_ADDR":                   &c.Consul.Addr,
+		"VAULT_ADDR":                    &c.Vault.Addr,
+		"VAULT_TOKEN":                   &c.Vault.Token,
+		"WARSCRIPT_USERS_POSTGRES_USER": &c.Postgres.User,
+		"WARSCRIPT_USERS_POSTGRES_PASS": &c.Postgres.Pass,
+		"WARSCRIPT_USERS_POSTGRES_HOST": &c.Postgres.Host,
+		"WARSCRIPT_USERS_POSTGRES_PORT": &c.Postgres.Port,
+		"WARSCRIPT_USERS_POSTGRES_DB":   &c.Postgres.Database,
+		"WARSCRIPT_USERS_REDIS_USER":    &c.Redis.User,
+		"WARSCRIPT_USERS_REDIS_PASS":    &c.Redis.Pass,
+		"WARSCRIPT_USERS_REDIS_ADDR":    &c.Redis.Addr,
+		"WARSCRIPT_USERS_REDIS_DB":      &c.Redis.Database,
+
+		"WARSCRIPT_USERS_PASSWORD_COMMON_LIST": &c.Password.CommonListPath,
+		"WARSCRIPT_USERS_HASH_ALGORITHM":       &c.Hashing.Algorithm,
+		"WARSCRIPT_USERS_MAIL_DRIVER":          &c.Mail.Driver,
+		"WARSCRIPT_USERS_MAIL_PATH":            &c.Mail.Path,
+		"WARSCRIPT_USERS_MAIL_FROM":            &c.Mail.From,
+		"WARSCRIPT_USERS_SMTP_ADDR":            &c.Mail.SMTPAddr,
+		"WARSCRIPT_USERS_SMTP_USER":            &c.Mail.SMTPUser,
+		"WARSCRIPT_USERS_SMTP_PASS":            &c.Mail.SMTPPass,
+		"WARSCRIPT_USERS_PUBLIC_URL":           &c.PublicURL,
+		"WARSCRIPT_USERS_TOKEN_SECRET":         &c.TokenSecret,
+		"WARSCRIPT_USERS_2FA_ISSUER":           &c.TwoFactor.Issuer,
+		"WARSCRIPT_USERS_2FA_SECRET_KEY":       &c.TwoFactor.SecretKey,
+
+		"WARSCRIPT_USERS_ACCESS_TOKENS_ISSUER":     &c.AccessTokens.Issuer,
+		"WARSCRIPT_USERS_ACCESS_TOKENS_KEY_SECRET": &c.AccessTokens.KeySecret,
+	}
+	for key, dst := range strs {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+
+	ints := map[string]*int{
+		"WARSCRIPT_USERS_HTTP_PORT": &c.HTTPPort,
+		"WARSCRIPT_USERS_GRPC_PORT": &c.GRPCPort,
+
+		"WARSCRIPT_USERS_SHUTDOWN_TIMEOUT":        &c.ShutdownTimeout,
+		"WARSCRIPT_USERS_SESSION_TTL":             &c.Session.TTL,
+		"WARSCRIPT_USERS_SESSION_REMEMBER_TTL":    &c.Session.RememberTTL,
+		"WARSCRIPT_USERS_SESSION_ABSOLUTE_TTL":    &c.Session.AbsoluteTTL,
+		"WARSCRIPT_USERS_LOGIN_MAX_FAILURES":      &c.Login.MaxFailures,
+		"WARSCRIPT_USERS_LOGIN_IP_MAX_FAILURES":   &c.Login.IPMaxFailures,
+		"WARSCRIPT_USERS_LOGIN_WINDOW":            &c.Login.Window,
+		"WARSCRIPT_USERS_LOGIN_LOCKOUT":           &c.Login.Lockout,
+		"WARSCRIPT_USERS_LOGIN_MAX_LOCKOUT":       &c.Login.MaxLockout,
+		"WARSCRIPT_USERS_USERNAME_MIN_LENGTH":     &c.Username.MinLength,
+		"WARSCRIPT_USERS_USERNAME_MAX_LENGTH":     &c.Username.MaxLength,
+		"WARSCRIPT_USERS_PASSWORD_MIN_LENGTH":     &c.Password.MinLength,
+		"WARSCRIPT_USERS_PASSWORD_MAX_LENGTH":     &c.Password.MaxLength,
+		"WARSCRIPT_USERS_PASSWORD_MIN_CLASSES":    &c.Password.MinClasses,
+		"WARSCRIPT_USERS_HASH_BCRYPT_COST":        &c.Hashing.BcryptCost,
+		"WARSCRIPT_USERS_HASH_ARGON2_TIME":        &c.Hashing.Argon2Time,
+		"WARSCRIPT_USERS_HASH_ARGON2_MEMORY":      &c.Hashing.Argon2Memory,
+		"WARSCRIPT_USERS_HASH_ARGON2_THREADS":     &c.Hashing.Argon2Threads,
+		"WARSCRIPT_USERS_DELETION_GRACE_PERIOD":   &c.Deletion.GracePeriod,
+		"WARSCRIPT_USERS_DELETION_PURGE_INTERVAL": &c.Deletion.PurgeInterval,
+
+		"WARSCRIPT_USERS_ACCESS_TOKENS_TTL":               &c.AccessTokens.TTL,
+		"WARSCRIPT_USERS_ACCESS_TOKENS_ROTATION_INTERVAL": &c.AccessTokens.RotationInterval,
+	}
+	for key, dst := range ints {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return errors.Wrapf(err, "invalid %s", key)
+			}
+			*dst = n
+		}
+	}
+
+	if v, ok := os.LookupEnv("WARSCRIPT_USERS_TRUSTED_PROXIES"); ok {
+		c.TrustedProxies = strings.Split(v, ",")
+	}
+
+	bools := map[string]*bool{
+		"WARSCRIPT_USERS_ACCESS_TOKENS_ENABLED": &c.AccessTokens.Enabled,
+	}
+	for key, dst := range bools {
+		if v, ok := os.LookupEnv(key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return errors.Wrapf(err, "invalid %s", key)
+			}
+			*dst = b
+		}
+	}
+
+	return nil
+}
+
+// FlagsConfigProvider конфиг из аргументов командной строки.
+// Применяются только явно переданные флаги
+type FlagsConfigProvider struct {
+	fs     *flag.FlagSet
+	values Config
+
+	// ConfigPath путь до файла конфигурации (-config)
+	ConfigPath string
+}
+
+// NewFlagsConfigProvider регистрирует флаги конфигурации в fs
+func NewFlagsConfigProvider(fs *flag.FlagSet) *FlagsConfigProvider {
+	p := &FlagsConfigProvider{fs: fs}
+
+	fs.StringVar(&p.ConfigPath, "config", os.Getenv("WARSCRIPT_USERS_CONFIG"), "path to JSON config file")
+	fs.IntVar(&p.values.HTTPPort, "http-port", 0, "HTTP port")
+	fs.IntVar(&p.values.GRPCPort, "grpc-port", 0, "gRPC port")
+	fs.StringVar(&p.values.Postgres.Host, "postgres-host", "", "PostgreSQL host")
+	fs.StringVar(&p.values.Postgres.Port, "postgres-port", "", "PostgreSQL port")
+	fs.StringVar(&p.values.Postgres.Database, "postgres-db", "", "PostgreSQL database")
+	fs.StringVar(&p.values.Redis.Addr, "redis-addr", "", "Redis address")
+	fs.StringVar(&p.values.Consul.Addr, "consul-addr", "", "Consul address, empty disables registration")
+	fs.StringVar(&p.values.Vault.Addr, "vault-addr", "", "Vault address, empty disables secrets lookup")
+	fs.IntVar(&p.values.ShutdownTimeout, "shutdown-timeout", 0, "graceful shutdown deadline in seconds")
+
+	return p
+}
+
+// Load применяет переданные флаги
+func (p *FlagsConfigProvider) Load(c *Config) error {
+	p.fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "http-port":
+			c.HTTPPort = p.values.HTTPPort
+		case "grpc-port":
+			c.GRPCPort = p.values.GRPCPort
+		case "postgres-host":
+			c.Postgres.Host = p.values.Postgres.Host
+		case "postgres-port":
+			c.Postgres.Port = p.values.Postgres.Port
+		case "postgres-db":
+			c.Postgres.Database = p.values.Postgres.Database
+		case "redis-addr":
+			c.Redis.Addr = p.values.Redis.Addr
+		case "consul-addr":
+			c.Consul.Addr = p.values.Consul.Addr
+		case "vault-addr":
+			c.Vault.Addr = p.values.Vault.Addr
+		case "shutdown-timeout":
+			c.ShutdownTimeout = p.values.ShutdownTimeout
+		}
+	})
+
+	return nil
+}
+
+// VaultConfigProvider достаёт креды баз из волта.
+// Ничего не делает, если адрес волта не задан
+type VaultConfigProvider struct{}
+
+func vaultString(data map[string]interface{}, key string) (string, error) {
+	v, ok := data[key].(string)
+	if !ok {
+		return "", errors.Errorf("vault key %s is not a string", key)
+	}
+
+	return v, nil
+}
+
+func vaultRead(vault *vaultapi.Client, path string, fields map[string]*string) error {
+	secret, err := vault.Logical().Read(path)
+	if err != nil || secret == nil || len(secret.Warnings) != 0 {
+		return errors.Errorf("can not read %s key: %v; %+v", path, err, secret)
+	}
+
+	for key, dst := range fields {
+		v, err := vaultString(secret.Data, key)
+		if err != nil {
+			return errors.Wrap(err, path)
+		}
+		*dst = v
+	}
+
+	return nil
+}
+
+// Load читает warscript-users/postgres, warscript-users/redis и warscript-users/secrets
+func (p *VaultConfigProvider) Load(c *Config) error {
+	if c.Vault.Addr == "" {
+		return nil
+	}
+
+	vaultConfig := vaultapi.DefaultConfig()
+	vaultConfig.Address = c.Vault.Addr
+	vault, err := vaultapi.NewClient(vaultConfig)
+	if err != nil {
+		return errors.Wrap(err, "can not connect vault service")
+	}
+	vault.SetToken(c.Vault.Token)
+
+	err = vaultRead(vault, "warscript-users/postgres", map[string]*string{
+		"user":     &c.Postgres.User,
+		"pass":     &c.Postgres.Pass,
+		"host":     &c.Postgres.Host,
+		"port":     &c.Postgres.Port,
+		"database": &c.Postgres.Database,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = vaultRead(vault, "warscript-users/redis", map[string]*string{
+		"user":     &c.Redis.User,
+		"pass":     &c.Redis.Pass,
+		"addr":     &c.Redis.Addr,
+		"database": &c.Redis.Database,
+	})
+	if err != nil {
+		return err
+	}
+
+	return vaultRead(vault, "warscript-users/secrets", map[string]*string{
+		"token_secret":          &c.TokenSecret,
+		"two_factor_secret_key": &c.TwoFactor.SecretKey,
+	})
+}
+
+// ConsulConfigProvider подбирает свободные порты через консул,
+// если они не заданы явно. Ничего не делает без адреса консула
+type ConsulConfigProvider struct{}
+
+// Load заполняет незаданные порты
+func (p *ConsulConfigProvider) Load(c *Config) error {
+	if c.Consul.Addr == "" || (c.HTTPPort > 0 && c.GRPCPort > 0) {
+		return nil
+	}
+
+	consul, err := newConsulClient(c.Consul.Addr)
+	if err != nil {
+		return err
+	}
+
+	httpPort, grpcPort, err := balancer.GetPorts("warscript-users/bounds", "warscript-users", consul)
+	if err != nil {
+		return errors.Wrap(err, "can not find empty port")
+	}
+
+	if c.HTTPPort <= 0 {
+		c.HTTPPort = httpPort
+	}
+	if c.GRPCPort <= 0 {
+		c.GRPCPort = grpcPort
+	}
+
+	return nil
+}
+
+func newConsulClient(addr string) (*consulapi.Client, error) {
+	consulConfig := consulapi.DefaultConfig()
+	consulConfig.Address = addr
+	consul, err := consulapi.NewClient(consulConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not connect consul service")
+	}
+
+	return consul, nil
+}
+
+// registerServices регистрирует http и grpc сервисы в консуле
+// и возвращает функцию для их дерегистрации, её можно звать несколько раз
+func registerServices(c *Config) (func(), error) {
+	if c.Consul.Addr == "" {
+		return func() {}, nil
+	}
+
+	consul, err := newConsulClient(c.Consul.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	httpServiceID := fmt.Sprintf("warscript-users-http:%d", c.HTTPPort)
+	err = consul.Agent().ServiceRegister(&consulapi.AgentServiceRegistration{
+		ID:      httpServiceID,
+		Name:    "warscript-users-http",
+		Port:    c.HTTPPort,
+		Address: "127.0.0.1",
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "can not register http service")
+	}
+
+	grpcServiceID := fmt.Sprintf("warscript-users-grpc:%d", c.GRPCPort)
+	err = consul.Agent().ServiceRegister(&consulapi.AgentServiceRegistration{
+		ID:      grpcServiceID,
+		Name:    "warscript-users-grpc",
+		Port:    c.GRPCPort,
+		Address: "127.0.0.1",
+	})
+	if err != nil {
+		deregisterService(consul, httpServiceID)
+		return nil, errors.Wrap(err, "can not register grpc service")
+	}
+
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			deregisterService(consul, httpServiceID)
+			deregisterService(consul, grpcServiceID)
+		})
+	}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLoadConfigPriority(t *testing.T) {
+	f, err := ioutil.TempFile("", "warscript-users-config")
+	if err != nil {
+		t.Fatalf("TestLoadConfigPriority can not create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`{"http_port":1,"grpc_port":2,"postgres":{"host":"file","database":"file"},
+		"redis":{"addr":"file"}}`)
+	if err != nil {
+		t.Fatalf("TestLoadConfigPriority can not write temp file: %v", err)
+	}
+	f.Close()
+
+	os.Setenv("WARSCRIPT_USERS_POSTGRES_HOST", "env")
+	os.Setenv("WARSCRIPT_USERS_GRPC_PORT", "20")
+	defer os.Unsetenv("WARSCRIPT_USERS_POSTGRES_HOST")
+	defer os.Unsetenv("WARSCRIPT_USERS_GRPC_PORT")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := NewFlagsConfigProvider(fs)
+	if err = fs.Parse([]string{"-grpc-port", "200", "-redis-addr", "flag"}); err != nil {
+		t.Fatalf("TestLoadConfigPriority can not parse flags: %v", err)
+	}
+
+	c, err := LoadConfig(&FileConfigProvider{Path: f.Name()}, &EnvConfigProvider{}, flags,
+		&VaultConfigProvider{}, &ConsulConfigProvider{})
+	if err != nil {
+		t.Fatalf("TestLoadConfigPriority got unexpected error: %v", err)
+	}
+
+	if c.HTTPPort != 1 || c.GRPCPort != 200 {
+		t.Errorf("TestLoadConfigPriority got unexpected ports: %d, %d", c.HTTPPort, c.GRPCPort)
+	}
+	if c.Postgres.Host != "env" || c.Postgres.Database != "file" || c.Postgres.Port != "5432" {
+		t.Errorf("TestLoadConfigPriority got unexpected postgres config: %+v", c.Postgres)
+	}
+	if c.Redis.Addr != "flag" || c.Redis.Database != "0" {
+		t.Errorf("TestLoadConfigPriority got unexpected redis config: %+v", c.Redis)
+	}
+}
+
+func TestLoadConfigErr(t *testing.T) {
+	if _, err := LoadConfig(&FileConfigProvider{Path: "/not/exists.json"}); err == nil {
+		t.Errorf("TestLoadConfigErr expected error for missing file")
+	}
+
+	os.Setenv("WARSCRIPT_USERS_HTTP_PORT", "kek")
+	defer os.Unsetenv("WARSCRIPT_USERS_HTTP_PORT")
+	if _, err := LoadConfig(&EnvConfigProvider{}); err == nil {
+		t.Errorf("TestLoadConfigErr expected error for invalid port")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// DeletionPolicy сроки удаления аккаунтов. Выключенный аккаунт можно
+// включить обратно в течение GracePeriod, потом он обезличивается.
+// Проверка идёт раз в PurgeInterval
+type DeletionPolicy struct {
+	GracePeriod   time.Duration
+	PurgeInterval time.Duration
+}
+
+// deletionPolicy политика, с которой работает сервис
+var deletionPolicy = NewDeletionPolicy(DeletionConfig{})
+
+// NewDeletionPolicy создаёт политику из конфига, незаданные значения берутся по умолчанию
+func NewDeletionPolicy(c DeletionConfig) *DeletionPolicy {
+	p := &DeletionPolicy{
+		GracePeriod:   30 * 24 * time.Hour,
+		PurgeInterval: time.Hour,
+	}
+
+	if c.GracePeriod > 0 {
+		p.GracePeriod = time.Duration(c.GracePeriod) * time.Second
+	}
+	if c.PurgeInterval > 0 {
+		p.PurgeInterval = time.Duration(c.PurgeInterval) * time.Second
+	}
+
+	return p
+}
+
+// deactivateUserImpl выключает аккаунт юзера и завершает все его сессии
+func deactivateUserImpl(info *models.SessionPayload, form *jmodels.FormPasswordConfirm, client *clientInfo) error {
+	user, err := Users.GetUserByID(info.ID)
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	if form.Password == "" {
+		return &utils.ValidationError{
+			"password": utils.ErrRequired.Error(),
+		}
+	}
+	if !Users.CheckPassword(user, form.Password) {
+		return &utils.ValidationError{
+			"password": utils.ErrInvalid.Error(),
+		}
+	}
+
+	if err = Users.Deactivate(user.ID); err != nil {
+		return errors.Wrap(err, "user deactivate error")
+	}
+	auditUserImpl(AuditDeactivate, user.ID, client, nil)
+
+	if err = Sessions.DeleteAllForUser(user.ID); err != nil {
+		return errors.Wrap(err, "sessions revoke error")
+	}
+
+	return nil
+}
+
+// reactivateUserImpl включает выключенный аккаунт по паролю, пока не прошёл GracePeriod.
+// Сессию не открывает: после этого юзер входит как обычно, в том числе со вторым фактором
+func reactivateUserImpl(form *jmodels.FormUser, client *clientInfo) error {
+	if err := form.Validate(); err != nil {
+		return err
+	}
+
+	user, err := getUserByLoginImpl(form.Username)
+	if err != nil {
+		user = nil
+	}
+
+	subject := loginSubject(user, form.Username)
+	if err = checkLoginLockImpl(subject, client.IP); err != nil {
+		return err
+	}
+
+	if user == nil {
+		return rejectLogin(subject, client, &utils.ValidationError{
+			"username": utils.ErrNotExists.Error(),
+		})
+	}
+
+	if !Users.CheckPassword(user, form.Password) {
+		return rejectLogin(subject, client, &utils.ValidationError{
+			"password": utils.ErrInvalid.Error(),
+		})
+	}
+
+	if user.Active {
+		return nil
+	}
+
+	ok, err := Users.Reactivate(user.ID, time.Now().Add(-deletionPolicy.GracePeriod))
+	if err != nil {
+		return errors.Wrap(err, "user reactivate error")
+	}
+	if !ok {
+		return &utils.ValidationError{
+			"username": utils.ErrNotExists.Error(),
+		}
+	}
+	auditUserImpl(AuditReactivate, user.ID, client, nil)
+
+	return nil
+}
+
+// purgeDeactivatedImpl обезличивает аккаунты, у которых прошёл GracePeriod
+func purgeDeactivatedImpl() error {
+	n, err := Users.Anonymize(time.Now().Add(-deletionPolicy.GracePeriod))
+	if err != nil {
+		return errors.Wrap(err, "users anonymize error")
+	}
+
+	if n > 0 {
+		logger.Infof("%d deactivated users anonymized", n)
+	}
+	return nil
+}
+
+// runPurger раз в PurgeInterval обезличивает аккаунты, пока не закроют stop.
+// Запросы идемпотентны, так что его можно запускать в каждом контейнере
+func runPurger(stop <-chan struct{}) {
+	ticker := time.NewTicker(deletionPolicy.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := purgeDeactivatedImpl(); err != nil {
+			logger.Errorf("can not purge deactivated users: %s", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
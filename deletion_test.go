@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+)
+
+func TestNewDeletionPolicy(t *testing.T) {
+	p := NewDeletionPolicy(DeletionConfig{})
+	if p.GracePeriod != 30*24*time.Hour || p.PurgeInterval != time.Hour {
+		t.Errorf("TestNewDeletionPolicy got unexpected defaults: %+v", p)
+	}
+
+	p = NewDeletionPolicy(DeletionConfig{GracePeriod: 60, PurgeInterval: 10})
+	if p.GracePeriod != time.Minute || p.PurgeInterval != 10*time.Second {
+		t.Errorf("TestNewDeletionPolicy got unexpected policy: %+v", p)
+	}
+}
+
+func TestDeactivateUser(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	Sessions.(*sessionsTest).owners = map[string]int64{"golang-session": 1}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":1}`)
+	ctx := context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1})
+
+	cases := []*UserTestCase{
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"required"}`,
+				Method:       "DELETE",
+				Pattern:      "/users",
+				Function:     DeactivateUser,
+				Context:      ctx,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"password":"kek"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"invalid"}`,
+				Method:       "DELETE",
+				Pattern:      "/users",
+				Function:     DeactivateUser,
+				Context:      ctx,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"password":"golang4ever"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "DELETE",
+				Pattern:      "/users",
+				Function:     DeactivateUser,
+				Context:      ctx,
+			},
+		},
+		{ // выключенный аккаунт не войдёт, но узнает, что его можно включить
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"username":"deactivated"}`,
+				Method:       "POST",
+				Pattern:      "/sessions",
+				Function:     CreateSession,
+			},
+		},
+		{ // а с чужим паролем не узнает
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang5ever"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"invalid"}`,
+				Method:       "POST",
+				Pattern:      "/sessions",
+				Function:     CreateSession,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang5ever"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"invalid"}`,
+				Method:       "POST",
+				Pattern:      "/users/reactivate",
+				Function:     ReactivateUser,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/users/reactivate",
+				Function:     ReactivateUser,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/sessions",
+				Function:     CreateSession,
+			},
+		},
+	}
+	runTableAPITests(t, cases)
+
+	if _, ok := Sessions.(*sessionsTest).sessions["golang-session"]; ok {
+		t.Errorf("TestDeactivateUser sessions were not revoked")
+	}
+	if !hasAuditEvents(1, AuditDeactivate, AuditReactivate) {
+		t.Errorf("TestDeactivateUser got unexpected audit: %v", auditEventsOf(1))
+	}
+}
+
+func TestPurgeDeactivated(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	Users.(*usersTest).users[2] = UserModel{ID: 2, Username: "kek", Password: &pass, Active: true}
+
+	for _, id := range []int64{1, 2} {
+		if err := Users.Deactivate(id); err != nil {
+			t.Fatalf("TestPurgeDeactivated got unexpected error: %v, expected: %v", err, nil)
+		}
+	}
+	// у golang срок уже прошёл
+	Users.(*usersTest).deactivated[1] = time.Now().Add(-deletionPolicy.GracePeriod - time.Minute)
+
+	form := &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: "golang"}, Password: pass}
+	err := reactivateUserImpl(form, &clientInfo{})
+	if valErr, ok := err.(*utils.ValidationError); !ok || (*valErr)["username"] != utils.ErrNotExists.Error() {
+		t.Errorf("TestPurgeDeactivated got unexpected error: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	if err = purgeDeactivatedImpl(); err != nil {
+		t.Fatalf("TestPurgeDeactivated got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	if u := Users.(*usersTest).users[1]; u.Username != "deleted#1" || u.Active {
+		t.Errorf("TestPurgeDeactivated user was not anonymized: %+v", u)
+	}
+	if u := Users.(*usersTest).users[2]; u.Username != "kek" {
+		t.Errorf("TestPurgeDeactivated user in grace period was anonymized: %+v", u)
+	}
+}
+
+func TestAnonymizeModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	before := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE users SET").
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(3))
+	mock.ExpectExec("DELETE FROM user_totp").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_identities").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM personal_tokens").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_roles").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE audit_events SET ip = ''").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE users SET").
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	if n, err := Users.Anonymize(before); n != 2 || err != nil {
+		t.Errorf("TestAnonymizeModel got unexpected result: %d, %v, expected: %d", n, err, 2)
+	}
+	if n, err := Users.Anonymize(before); n != 0 || err != nil {
+		t.Errorf("TestAnonymizeModel got unexpected result: %d, %v, expected: %d", n, err, 0)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestAnonymizeModel there were unfulfilled expectations: %s", err)
+	}
+}
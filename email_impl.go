@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// verifyEmailTTL сколько действует ссылка подтверждения email
+const verifyEmailTTL = 48 * time.Hour
+
+// publicURL адрес фронтенда, от которого строятся ссылки в письмах
+var publicURL = ""
+
+// publicLink ссылка на страницу фронтенда с токеном
+func publicLink(path, token string) string {
+	return strings.TrimRight(publicURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmailImpl отправляет ссылку подтверждения на текущий email юзера.
+// Токен привязан к адресу, так что после смены email старые ссылки не работают
+func sendVerificationEmailImpl(user *UserModel) error {
+	if !user.Email.Valid {
+		return errors.New("user has no email")
+	}
+
+	token, err := tokenSigner.Sign(TokenPurposeVerifyEmail, user.ID,
+		strings.ToLower(user.Email.String), verifyEmailTTL)
+	if err != nil {
+		return errors.Wrap(err, "verification token sign error")
+	}
+
+	if err = notifier.VerifyEmail(user, publicLink("/verify", token)); err != nil {
+		return errors.Wrap(err, "verification email send error")
+	}
+
+	return nil
+}
+
+// verifyEmailImpl подтверждает email по токену из письма. Уже подтверждённый
+// или сменившийся адрес считается недействительным токеном
+func verifyEmailImpl(token string) error {
+	claims, err := tokenSigner.Verify(TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := Users.GetUserByID(claims.UserID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return ErrTokenInvalid
+		}
+		return errors.Wrap(err, "get user error")
+	}
+
+	if user.EmailVerified || !strings.EqualFold(user.GetEmail(), claims.Binding) {
+		return ErrTokenInvalid
+	}
+
+	user.EmailVerified = true
+	if err = Users.Save(user); err != nil {
+		return errors.Wrap(err, "user save error")
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+)
+
+// sentToken достаёт токен из ссылки последнего письма
+func sentToken(t *testing.T, m *mailerTest) string {
+	if len(m.sent) == 0 {
+		t.Fatalf("no mail was sent")
+	}
+
+	body := m.sent[len(m.sent)-1].Body
+	i := strings.Index(body, "?token=")
+	if i < 0 {
+		t.Fatalf("no token in mail: %s", body)
+	}
+
+	return strings.Fields(body[i+len("?token="):])[0]
+}
+
+func TestEmailVerification(t *testing.T) {
+	initTests()
+	mails := &mailerTest{}
+	mailer = mails
+
+	cases := []*UserTestCase{
+		{ // Регистрация с email
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang4ever","email":" golang@mail.ru "}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/users",
+				Function:     CreateUser,
+			},
+		},
+		{ // Кривой email
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"kek","password":"golang4ever","email":"Kek <kek@mail.ru>"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"email":"invalid"}`,
+				Method:       "POST",
+				Pattern:      "/users",
+				Function:     CreateUser,
+			},
+		},
+		{ // Занятый email
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"kek","password":"golang4ever","email":"golang@mail.ru"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"email":"taken"}`,
+				Method:       "POST",
+				Pattern:      "/users",
+				Function:     CreateUser,
+			},
+			FailureUser: ErrEmailTaken,
+		},
+		{ // Подделанный токен
+			Case: testutils.Case{
+				Payload:      []byte(`{"token":"kek.lol"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"token":"invalid"}`,
+				Method:       "POST",
+				Pattern:      "/users/verify",
+				Function:     VerifyEmail,
+			},
+		},
+	}
+	runTableAPITests(t, cases)
+
+	if len(mails.sent) != 1 || mails.sent[0].To != "golang@mail.ru" {
+		t.Fatalf("TestEmailVerification got unexpected mails: %+v", mails.sent)
+	}
+	token := sentToken(t, mails)
+
+	verify := func() int {
+		r := httptest.NewRequest("POST", "/users/verify", bytes.NewReader([]byte(`{"token":"`+token+`"}`)))
+		w := httptest.NewRecorder()
+		VerifyEmail(w, r)
+		return w.Code
+	}
+
+	if code := verify(); code != http.StatusOK {
+		t.Errorf("TestEmailVerification got unexpected code: %d, expected: %d", code, http.StatusOK)
+	}
+	if user, _ := Users.GetUserByEmail("GOLANG@mail.ru"); user == nil || !user.EmailVerified {
+		t.Errorf("TestEmailVerification email was not verified: %+v", user)
+	}
+
+	// токен одноразовый
+	if code := verify(); code != http.StatusBadRequest {
+		t.Errorf("TestEmailVerification got unexpected code on reuse: %d, expected: %d", code, http.StatusBadRequest)
+	}
+
+	// вход по email
+	form := &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: "Golang@mail.ru"}, Password: "golang4ever"}
+	if _, err := createSessionImpl(form, &clientInfo{}); err != nil {
+		t.Errorf("TestEmailVerification can not login by email: %v", err)
+	}
+}
+
+func TestUpdateEmail(t *testing.T) {
+	initTests()
+	mails := &mailerTest{}
+	mailer = mails
+
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	ctx := context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1})
+
+	cases := []*UserTestCase{
+		{ // без пароля email не сменить
+			Case: testutils.Case{
+				Payload:      []byte(`{"email":"golang@mail.ru"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"oldPassword":"required"}`,
+				Method:       "PUT",
+				Pattern:      "/users",
+				Function:     UpdateUser,
+				Context:      ctx,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"email":"golang@mail.ru", "oldPassword":"golang4ever"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "PUT",
+				Pattern:      "/users",
+				Function:     UpdateUser,
+				Context:      ctx,
+			},
+		},
+	}
+	runTableAPITests(t, cases)
+
+	user := Users.(*usersTest).users[1]
+	if user.GetEmail() != "golang@mail.ru" || user.EmailVerified || len(mails.sent) != 1 {
+		t.Fatalf("TestUpdateEmail got unexpected state: %+v, mails: %d", user, len(mails.sent))
+	}
+	oldToken := sentToken(t, mails)
+
+	// после смены адреса старая ссылка не работает
+	user.Email.String = "kek@mail.ru"
+	Users.(*usersTest).users[1] = user
+	if err := verifyEmailImpl(oldToken); err != ErrTokenInvalid {
+		t.Errorf("TestUpdateEmail got unexpected error: %v, expected: %v", err, ErrTokenInvalid)
+	}
+}
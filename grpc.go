@@ -4,7 +4,10 @@ import (
 	"context"
 
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/HotCodeGroup/warscript-users/permissions"
 	"github.com/HotCodeGroup/warscript-utils/models"
 	"github.com/pkg/errors"
 )
@@ -19,19 +22,14 @@ func (m *AuthManager) GetUserByID(ctx context.Context, userID *models.UserID) (*
 		"user_id": userID.ID,
 	})
 
-	usr, err := getInfoUserByIDImpl(userID.ID)
+	usr, err := getUserByIDImpl(userID.ID)
 	if err != nil {
 		logger.Errorf("can not get user by id: %s", err)
 		return nil, errors.Wrap(err, "can not get user by id")
 	}
 
 	logger.Info("successful")
-	return &models.InfoUser{
-		ID:        usr.ID,
-		Username:  usr.Username,
-		PhotoUUID: usr.PhotoUUID,
-		Active:    usr.Active,
-	}, nil
+	return usr, nil
 }
 
 // GetUserByUsername получает одного юзера по username
@@ -41,59 +39,89 @@ func (m *AuthManager) GetUserByUsername(ctx context.Context, username *models.Us
 		"username": username.Username,
 	})
 
-	usr, err := Users.GetUserByUsername(username.Username)
+	usr, err := getUserByUsernameImpl(username.Username)
 	if err != nil {
 		logger.Errorf("can not get user by username: %s", err)
 		return nil, errors.Wrap(err, "can not get user by username")
 	}
 
 	logger.Info("successful")
-	return &models.InfoUser{
-		ID:        usr.ID,
-		Username:  usr.Username,
-		PhotoUUID: usr.GetPhotoUUID(),
-		Active:    usr.Active,
-	}, nil
+	return usr, nil
 }
 
-// GetUserByUsername получает одного юзера по username
+// GetUserBySecret получает одного юзера по секрету для вк
 func (m *AuthManager) GetUserBySecret(ctx context.Context, vkSecret *models.VkSecret) (*models.InfoUser, error) {
 	logger := logger.WithFields(logrus.Fields{
 		"method": "grpc_GetUserBySecret",
 	})
 
-	usr, err := Users.GetUserBySecret(vkSecret.VkSecret)
+	usr, err := getUserBySecretImpl(vkSecret.VkSecret)
 	if err != nil {
 		logger.Errorf("can not get user by secret: %s", err)
 		return nil, errors.Wrap(err, "can not get user by secret")
 	}
 
 	logger.Info("successful")
-	return &models.InfoUser{
-		ID:        usr.ID,
-		Username:  usr.Username,
-		PhotoUUID: usr.GetPhotoUUID(),
-		Active:    usr.Active,
-	}, nil
+	return usr, nil
 }
 
 // GetSessionInfo получает информацию о сессии из редис по токену
 func (m *AuthManager) GetSessionInfo(ctx context.Context, token *models.SessionToken) (*models.SessionPayload, error) {
+	// личный токен живёт долго, в логах ему не место
+	logged := token.Token
+	if isPersonalToken(logged) {
+		logged = personalTokenPrefix + "***"
+	}
 	logger := logger.WithFields(logrus.Fields{
 		"method": "grpc_GetSessionInfo",
-		"token":  token.Token,
+		"token":  logged,
 	})
 
-	payload, err := getSessionImpl(token.Token)
+	payload, scopes, err := authenticateImpl(token.Token)
 	if err != nil {
 		logger.Errorf("can not get session by token: %s", err)
 		return nil, errors.Wrap(err, "can not get session by token")
 	}
 
+	// в SessionPayload места для ролей нет, поэтому они едут в заголовках ответа.
+	// Роли это запрос в базу, так что ищем их, только если клиент попросил
+	if grpc.ServerTransportStreamFromContext(ctx) != nil {
+		header := metadata.MD{}
+		if scopes != nil {
+			header[permissions.ScopesHeader] = scopes
+		}
+
+		if permissions.RolesRequested(ctx) {
+			up, err := userPermissionsImpl(payload.ID)
+			if err != nil {
+				logger.Errorf("can not get session permissions: %s", err)
+				return nil, errors.Wrap(err, "can not get session permissions")
+			}
+
+			header[permissions.RolesHeader] = up.Roles
+			header[permissions.PermissionsHeader] = up.Permissions
+			// личному токену достаются только права из его scopes
+			if scopes != nil {
+				perms := make([]string, 0, len(up.Permissions))
+				for _, p := range up.Permissions {
+					if hasScope(scopes, p) {
+						perms = append(perms, p)
+					}
+				}
+				header[permissions.PermissionsHeader] = perms
+			}
+		}
+
+		if len(header) > 0 {
+			if err = grpc.SetHeader(ctx, header); err != nil {
+				logger.Errorf("can not set permissions header: %s", err)
+				return nil, errors.Wrap(err, "can not set permissions header")
+			}
+		}
+	}
+
 	logger.Info("successful")
-	return &models.SessionPayload{
-		ID: payload.ID,
-	}, nil
+	return payload, nil
 }
 
 // GetUsersByIDs получает массив юзеров по массиву их ID
@@ -102,31 +130,52 @@ func (m *AuthManager) GetUsersByIDs(ctx context.Context, idsM *models.UserIDs) (
 		"method": "grpc_GetUsersByIDs",
 	})
 
-	ids := make([]int64, len(idsM.IDs))
-	for i, id := range idsM.IDs {
-		ids[i] = id.ID
-	}
-
-	users, err := Users.GetUsersByIDs(ids)
+	usersM, err := getUsersByIDsImpl(idsM)
 	if err != nil {
 		logger.Errorf("can not get users by ids: %s", err)
 		return nil, errors.Wrap(err, "can not get users by ids")
 	}
 
-	usersM := &models.InfoUsers{
-		Users: make([]*models.InfoUser, 0, len(users)),
+	logger.Info("successful")
+	return usersM, nil
+}
+
+// PermissionsManager реализует permissions.PermissionsServer
+type PermissionsManager struct{}
+
+// CheckPermission есть ли у юзера право
+func (m *PermissionsManager) CheckPermission(ctx context.Context,
+	check *permissions.PermissionCheck) (*permissions.PermissionCheckResult, error) {
+	logger := logger.WithFields(logrus.Fields{
+		"method":     "grpc_CheckPermission",
+		"user_id":    check.ID,
+		"permission": check.Permission,
+	})
+
+	allowed, err := hasPermissionImpl(check.ID, check.Permission)
+	if err != nil {
+		logger.Errorf("can not check permission: %s", err)
+		return nil, errors.Wrap(err, "can not check permission")
 	}
-	for _, u := range users {
-		uM := &models.InfoUser{
-			ID:        u.ID,
-			Username:  u.Username,
-			PhotoUUID: u.GetPhotoUUID(),
-			Active:    u.Active,
-		}
 
-		usersM.Users = append(usersM.Users, uM)
+	logger.Info("successful")
+	return &permissions.PermissionCheckResult{Allowed: allowed}, nil
+}
+
+// GetUserPermissions роли и права юзера
+func (m *PermissionsManager) GetUserPermissions(ctx context.Context,
+	userID *permissions.UserID) (*permissions.UserPermissions, error) {
+	logger := logger.WithFields(logrus.Fields{
+		"method":  "grpc_GetUserPermissions",
+		"user_id": userID.ID,
+	})
+
+	up, err := userPermissionsImpl(userID.ID)
+	if err != nil {
+		logger.Errorf("can not get user permissions: %s", err)
+		return nil, errors.Wrap(err, "can not get user permissions")
 	}
 
 	logger.Info("successful")
-	return usersM, nil
+	return up, nil
 }
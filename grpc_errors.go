@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Причины ошибок, которые кладутся в детали grpc статуса
+// как wrappers.StringValue, чтобы клиенты не парсили текст ошибки
+const (
+	ReasonNotExists        = "not_exists"
+	ReasonUnauthenticated  = "unauthenticated"
+	ReasonSessionNotExists = "session_not_exists"
+	ReasonTaken            = "taken"
+	ReasonInvalid          = "invalid"
+	ReasonInternal         = "internal"
+)
+
+// errInternalStatus текст, который клиенты видят вместо внутренних ошибок
+var errInternalStatus = errors.New("internal error")
+
+// sessionMethods методы, где отсутствие записи означает
+// невалидный токен, а не отсутствующий ресурс
+var sessionMethods = map[string]bool{
+	"/models.Auth/GetSessionInfo": true,
+}
+
+func newStatusError(code codes.Code, reason string, err error) error {
+	st, detailsErr := status.New(code, err.Error()).WithDetails(&wrappers.StringValue{Value: reason})
+	if detailsErr != nil {
+		return status.Error(code, err.Error())
+	}
+
+	return st.Err()
+}
+
+// grpcError переводит ошибки сервиса в grpc статус с причиной в деталях
+func grpcError(err error, sessionMethod bool) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch errors.Cause(err) {
+	case ErrSessionNotExists:
+		return newStatusError(codes.Unauthenticated, ReasonSessionNotExists, err)
+	case utils.ErrNotExists:
+		if sessionMethod {
+			return newStatusError(codes.Unauthenticated, ReasonUnauthenticated, err)
+		}
+		return newStatusError(codes.NotFound, ReasonNotExists, err)
+	case utils.ErrUnauthorized:
+		return newStatusError(codes.Unauthenticated, ReasonUnauthenticated, err)
+	case utils.ErrTaken:
+		return newStatusError(codes.AlreadyExists, ReasonTaken, err)
+	case utils.ErrInvalid:
+		return newStatusError(codes.InvalidArgument, ReasonInvalid, err)
+	default:
+		// текст ошибок базы и редиса клиентам не отдаём, он остаётся в логах
+		logger.Errorf("grpc internal error: %s", err)
+		return newStatusError(codes.Internal, ReasonInternal, errInternalStatus)
+	}
+}
+
+// grpcErrorReason достаёт причину ошибки из деталей статуса
+func grpcErrorReason(err error) string {
+	st, ok := status.FromError(err)
+	if !ok {
+		return ""
+	}
+
+	for _, d := range st.Details() {
+		if reason, ok := d.(*wrappers.StringValue); ok {
+			return reason.Value
+		}
+	}
+
+	return ""
+}
+
+// ErrorsInterceptor единообразно переводит ошибки всех методов в grpc статусы
+func ErrorsInterceptor(ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, grpcError(err, sessionMethods[info.FullMethod])
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCError(t *testing.T) {
+	cases := []struct {
+		err            error
+		sessionMethod  bool
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			err:            errors.Wrap(utils.ErrNotExists, "can not get user by id"),
+			expectedCode:   codes.NotFound,
+			expectedReason: ReasonNotExists,
+		},
+		{
+			err:            errors.Wrap(utils.ErrNotExists, "can not get session by token"),
+			sessionMethod:  true,
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: ReasonUnauthenticated,
+		},
+		{
+			err:            errors.Wrap(ErrSessionNotExists, "can not get session by token"),
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: ReasonSessionNotExists,
+		},
+		{
+			err:            utils.ErrUnauthorized,
+			expectedCode:   codes.Unauthenticated,
+			expectedReason: ReasonUnauthenticated,
+		},
+		{
+			err:            errors.Wrap(utils.ErrTaken, "save error"),
+			expectedCode:   codes.AlreadyExists,
+			expectedReason: ReasonTaken,
+		},
+		{
+			err:            errors.Wrapf(utils.ErrInternal, "redis get error"),
+			expectedCode:   codes.Internal,
+			expectedReason: ReasonInternal,
+		},
+		{
+			err:            errors.New("something strange"),
+			expectedCode:   codes.Internal,
+			expectedReason: ReasonInternal,
+		},
+		{
+			err:          status.Error(codes.Canceled, "canceled"),
+			expectedCode: codes.Canceled,
+		},
+	}
+
+	for i, c := range cases {
+		err := grpcError(c.err, c.sessionMethod)
+		if code := status.Code(err); code != c.expectedCode {
+			t.Errorf("[%d] TestGRPCError got code: %v, expected: %v", i, code, c.expectedCode)
+		}
+		if reason := grpcErrorReason(err); reason != c.expectedReason {
+			t.Errorf("[%d] TestGRPCError got reason: %s, expected: %s", i, reason, c.expectedReason)
+		}
+		if c.expectedCode == codes.Internal && status.Convert(err).Message() != "internal error" {
+			t.Errorf("[%d] TestGRPCError leaks internal error: %s", i, status.Convert(err).Message())
+		}
+	}
+}
+
+func TestErrorsInterceptor(t *testing.T) {
+	m := &AuthManager{}
+	Users = &usersTest{
+		ids:   2,
+		users: map[int64]UserModel{1: {ID: 1, Username: "kek", Active: true}},
+	}
+	Sessions = &sessionsTest{
+		sessions: map[string][]byte{
+			"1234": []byte(`{"id":1}`),
+		},
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/models.Auth/GetSessionInfo"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return m.GetSessionInfo(ctx, req.(*models.SessionToken))
+	}
+
+	resp, err := ErrorsInterceptor(context.Background(), &models.SessionToken{Token: "1234"}, info, handler)
+	if err != nil || resp.(*models.SessionPayload).ID != 1 {
+		t.Errorf("TestErrorsInterceptor got unexpected result: %v, %v", resp, err)
+	}
+
+	_, err = ErrorsInterceptor(context.Background(), &models.SessionToken{Token: "1235"}, info, handler)
+	if status.Code(err) != codes.Unauthenticated || grpcErrorReason(err) != ReasonSessionNotExists {
+		t.Errorf("TestErrorsInterceptor got unexpected error: %v, expected code: %v", err, codes.Unauthenticated)
+	}
+}
@@ -114,9 +114,18 @@ func TestGetUserByUsername(t *testing.T) {
 func TestGetSessionInfo(t *testing.T) {
 	m := &AuthManager{}
 
+	Users = &usersTest{
+		ids: 3,
+		users: map[int64]UserModel{
+			1: {ID: 1, Username: "kek", Active: true},
+			2: {ID: 2, Username: "lol", Active: false},
+		},
+	}
 	Sessions = &sessionsTest{
 		sessions: map[string][]byte{
 			"1234": []byte(`{"id":1}`),
+			"4321": []byte(`{"id":2}`),
+			"5678": []byte(`{"id":3}`),
 		},
 	}
 
@@ -133,7 +142,15 @@ func TestGetSessionInfo(t *testing.T) {
 		},
 		{
 			token:         "1235",
-			expectedError: utils.ErrNotExists,
+			expectedError: ErrSessionNotExists,
+		},
+		{ // юзер выключен
+			token:         "4321",
+			expectedError: ErrSessionNotExists,
+		},
+		{ // юзера нет
+			token:         "5678",
+			expectedError: ErrSessionNotExists,
 		},
 	}
 
@@ -147,6 +164,11 @@ func TestGetSessionInfo(t *testing.T) {
 			t.Errorf("[%d] GetUserByIDTest returns: %v, wanted: %v", i, resp, c.expected)
 		}
 	}
+
+	// сессия выключенного юзера удаляется
+	if _, ok := Sessions.(*sessionsTest).sessions["4321"]; ok {
+		t.Errorf("TestGetSessionInfo session of inactive user was not deleted")
+	}
 }
 
 func TestGetUsersByIDs(t *testing.T) {
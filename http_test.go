@@ -5,7 +5,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"testing"
+	"time"
 
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
 	"github.com/HotCodeGroup/warscript-utils/logging"
 	"github.com/HotCodeGroup/warscript-utils/middlewares"
 	"github.com/HotCodeGroup/warscript-utils/models"
@@ -13,6 +16,7 @@ import (
 
 	"github.com/HotCodeGroup/warscript-utils/testutils"
 
+	"github.com/mailru/easyjson/opt"
 	"github.com/pkg/errors"
 )
 
@@ -45,6 +49,16 @@ func runAPITest(t *testing.T, i int, c *UserTestCase) {
 	testutils.RunAPITest(t, i, &c.Case)
 }
 
+// newTestSecretBox шифровальщик с заведомо непустым ключом
+func newTestSecretBox(key string) *SecretBox {
+	box, err := NewSecretBox(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return box
+}
+
 func initTests() {
 	Users = &usersTest{
 		ids:   1,
@@ -54,6 +68,44 @@ func initTests() {
 	Sessions = &sessionsTest{
 		sessions: make(map[string][]byte),
 	}
+
+	LoginAttempts = &loginAttemptsTest{
+		failures: make(map[string]int64),
+		locks:    make(map[string]time.Duration),
+	}
+
+	PasswordResets = &passwordResetsTest{
+		tokens: make(map[string]int64),
+		latest: make(map[int64]string),
+	}
+
+	TwoFactors = &twoFactorsTest{
+		settings: make(map[int64]TwoFactorModel),
+		steps:    make(map[int64]int64),
+	}
+
+	Roles = &rolesTest{
+		roles: make(map[int64][]string),
+		grants: map[string][]string{
+			RoleAdmin:     {permissions.ManageUsers, permissions.ModerateGames, permissions.ModerateBots},
+			RoleModerator: {permissions.ModerateGames, permissions.ModerateBots},
+		},
+	}
+
+	tokenSigner = &TokenSigner{key: []byte("test secret")}
+	totpSecrets = newTestSecretBox("test secret")
+
+	AuditEvents = &auditEventsTest{}
+
+	SigningKeys = &signingKeysTest{}
+
+	Identities = &identitiesTest{}
+
+	OAuthStates = &oauthStatesTest{
+		states: make(map[string]*OAuthState),
+	}
+
+	PersonalTokens = &personalTokensTest{}
 }
 
 func TestCreateUser(t *testing.T) {
@@ -62,7 +114,7 @@ func TestCreateUser(t *testing.T) {
 	cases := []*UserTestCase{
 		{ // Всё ок
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"user","password":"deutschland"}`),
+				Payload:      []byte(`{"username":"user","password":"deutschland1"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -72,7 +124,7 @@ func TestCreateUser(t *testing.T) {
 		},
 		{ // На используемый username
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"user","password":"nicht deutschland"}`),
+				Payload:      []byte(`{"username":"user","password":"nicht deutschland1"}`),
 				ExpectedCode: 400,
 				ExpectedBody: `{"username":"taken"}`,
 				Method:       "POST",
@@ -101,6 +153,26 @@ func TestCreateUser(t *testing.T) {
 				Function:     CreateUser,
 			},
 		},
+		{ // Зарезервированное имя
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"Admin","password":"deutschland1"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"username":"reserved"}`,
+				Method:       "POST",
+				Pattern:      "/users",
+				Function:     CreateUser,
+			},
+		},
+		{ // Слабый пароль
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"user","password":"deutschland"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"weak"}`,
+				Method:       "POST",
+				Pattern:      "/users",
+				Function:     CreateUser,
+			},
+		},
 		{ // Неправильный формат JSON
 			Case: testutils.Case{
 				Payload:      []byte(`{"username":"kek слишком очевидно""}`),
@@ -113,7 +185,7 @@ func TestCreateUser(t *testing.T) {
 		},
 		{ // Упала база
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"user","password":"deutschland"}`),
+				Payload:      []byte(`{"username":"user","password":"deutschland1"}`),
 				ExpectedCode: 500,
 				ExpectedBody: `{"message":"user create error: internal server error"}`,
 				Method:       "POST",
@@ -124,7 +196,7 @@ func TestCreateUser(t *testing.T) {
 		},
 		{ // По какой-то невообразимой причине только что созданный юзер не существует в базе
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"user","password":"deutschland"}`),
+				Payload:      []byte(`{"username":"user","password":"deutschland1"}`),
 				ExpectedCode: 500,
 				ExpectedBody: `{"message":"set session error: map[user:deutschland]"}`,
 				Method:       "POST",
@@ -135,7 +207,7 @@ func TestCreateUser(t *testing.T) {
 		},
 		{ // Редис упал
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"user","password":"deutschland"}`),
+				Payload:      []byte(`{"username":"user","password":"deutschland1"}`),
 				ExpectedCode: 500,
 				ExpectedBody: `{"message":"set session error: internal server error"}`,
 				Method:       "POST",
@@ -149,13 +221,39 @@ func TestCreateUser(t *testing.T) {
 	runTableAPITests(t, cases)
 }
 
+func TestUpdateUserLegacyUsername(t *testing.T) {
+	initTests()
+	// имя завели до политики username
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "ad", Active: true}
+	info := &models.SessionPayload{ID: 1}
+	photo := "2eb4a823-3a6d-4cba-8767-4d4946890f4f"
+
+	form := &jmodels.FormUserUpdate{Username: opt.OString("ad"), PhotoUUID: opt.OString(photo)}
+	if err := updateUserImpl(info, "1234", &clientInfo{}, form); err != nil {
+		t.Errorf("TestUpdateUserLegacyUsername got unexpected error: %v", err)
+	}
+	if u := Users.(*usersTest).users[1]; u.PhotoUUID.String != photo {
+		t.Errorf("TestUpdateUserLegacyUsername photo was not saved: %+v", u)
+	}
+
+	// а на новое имя политика действует
+	form = &jmodels.FormUserUpdate{Username: opt.OString("administrator")}
+	err := updateUserImpl(info, "1234", &clientInfo{}, form)
+	if valErr, ok := err.(*utils.ValidationError); !ok || (*valErr)["username"] != "reserved" {
+		t.Errorf("TestUpdateUserLegacyUsername got unexpected error: %v", err)
+	}
+	if u := Users.(*usersTest).users[1]; u.Username != "ad" {
+		t.Errorf("TestUpdateUserLegacyUsername username was changed: %s", u.Username)
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	initTests()
 
 	cases := []*UserTestCase{
 		{ // Такого юзера пока нет
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"kek","password":"lol"}`),
+				Payload:      []byte(`{"username":"kek","password":"lolkek123"}`),
 				ExpectedCode: 401,
 				ExpectedBody: `{"message":"user not exists: get user error: not_exists"}`,
 				Method:       "PUT",
@@ -166,7 +264,7 @@ func TestUpdateUser(t *testing.T) {
 		},
 		{ // Создадим юзера
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"kek","password":"lol"}`),
+				Payload:      []byte(`{"username":"kek","password":"lolkek123"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -241,6 +339,17 @@ func TestUpdateUser(t *testing.T) {
 				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
 			},
 		},
+		{ // новый пароль не проходит политику
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"kek", "oldPassword":"lolkek123", "newPassword":"kek"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"newPassword":"too_short"}`,
+				Method:       "PUT",
+				Pattern:      "/users",
+				Function:     UpdateUser,
+				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+			},
+		},
 		{
 			Case: testutils.Case{
 				Payload:      []byte(`{"username":"kek", "photo_uuid":"ne photoUUID"}`),
@@ -254,7 +363,7 @@ func TestUpdateUser(t *testing.T) {
 		},
 		{
 			Case: testutils.Case{
-				Payload: []byte(`{"username":"kek", "oldPassword":"lol", "newPassword":"lol1",
+				Payload: []byte(`{"username":"kek", "oldPassword":"lolkek123", "newPassword":"lolkek1234",
 			 					"photo_uuid":"2eb4a823-3a6d-4cba-8767-4d4946890f4f"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
@@ -266,7 +375,7 @@ func TestUpdateUser(t *testing.T) {
 		},
 		{ // отвалилась база
 			Case: testutils.Case{
-				Payload: []byte(`{"username":"kek", "oldPassword":"lol", "newPassword":"lol1",
+				Payload: []byte(`{"username":"kek", "oldPassword":"lolkek123", "newPassword":"lolkek1234",
 			 					"photo_uuid":"2eb4a823-3a6d-4cba-8767-4d4946890f4f"}`),
 				ExpectedCode: 500,
 				ExpectedBody: `{"message":"get user error: upala basa"}`,
@@ -301,7 +410,7 @@ func TestCheckUsername(t *testing.T) {
 			Case: testutils.Case{
 				Payload:      []byte(`{"username":"sdas"}`),
 				ExpectedCode: 200,
-				ExpectedBody: `{"used":false}`,
+				ExpectedBody: `{"used":false,"available":true}`,
 				Method:       "POST",
 				Pattern:      "/users/username_check",
 				Function:     CheckUsername,
@@ -309,7 +418,7 @@ func TestCheckUsername(t *testing.T) {
 		},
 		{ // Создадим юзера
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"sdas","password":"dsadasd"}`),
+				Payload:      []byte(`{"username":"sdas","password":"dsadasd1"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -321,7 +430,7 @@ func TestCheckUsername(t *testing.T) {
 			Case: testutils.Case{
 				Payload:      []byte(`{"username":"sdas"}`),
 				ExpectedCode: 200,
-				ExpectedBody: `{"used":true}`,
+				ExpectedBody: `{"used":true,"available":false,"reason":"taken"}`,
 				Method:       "POST",
 				Pattern:      "/users/used",
 				Function:     CheckUsername,
@@ -331,7 +440,17 @@ func TestCheckUsername(t *testing.T) {
 			Case: testutils.Case{
 				Payload:      []byte(`{"username":""}`),
 				ExpectedCode: 200,
-				ExpectedBody: `{"used":false}`,
+				ExpectedBody: `{"used":false,"available":false,"reason":"required"}`,
+				Method:       "POST",
+				Pattern:      "/users/used",
+				Function:     CheckUsername,
+			},
+		},
+		{ // Имя под системное с кириллической у
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"sуstem"}`),
+				ExpectedCode: 200,
+				ExpectedBody: `{"used":false,"available":false,"reason":"mixed_scripts"}`,
 				Method:       "POST",
 				Pattern:      "/users/used",
 				Function:     CheckUsername,
@@ -389,7 +508,7 @@ func TestGetUser(t *testing.T) {
 		},
 		{ // Создадим юзера
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang","password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -440,7 +559,7 @@ func TestCreateSession(t *testing.T) {
 	cases := []*UserTestCase{
 		{ // кривой JSON(без запятой)
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang" "password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang" "password":"golang4ever"}`),
 				ExpectedCode: 400,
 				ExpectedBody: `{"message":"decode body error: invalid character '\"' after object key:value pair"}`,
 				Method:       "POST",
@@ -460,7 +579,7 @@ func TestCreateSession(t *testing.T) {
 		},
 		{ // незареганный юзер
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang", "password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang", "password":"golang4ever"}`),
 				ExpectedCode: 400,
 				ExpectedBody: `{"username":"not_exists"}`,
 				Method:       "POST",
@@ -471,7 +590,7 @@ func TestCreateSession(t *testing.T) {
 		// зарегали юзера
 		{
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang","password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -481,7 +600,7 @@ func TestCreateSession(t *testing.T) {
 		},
 		{ // неправильный пароль
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang", "password":"4ever2312123"}`),
+				Payload:      []byte(`{"username":"golang", "password":"golang4ever2312123"}`),
 				ExpectedCode: 400,
 				ExpectedBody: `{"password":"invalid"}`,
 				Method:       "POST",
@@ -491,7 +610,7 @@ func TestCreateSession(t *testing.T) {
 		},
 		{ // Отломалось хранилище сессий
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang","password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
 				ExpectedCode: 500,
 				ExpectedBody: `{"message":"set session error: vse slomalos"}`,
 				Method:       "POST",
@@ -502,7 +621,7 @@ func TestCreateSession(t *testing.T) {
 		},
 		{ // Всё ок
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang","password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -517,6 +636,13 @@ func TestCreateSession(t *testing.T) {
 
 func TestDeleteSession(t *testing.T) {
 	initTests()
+	token := "6d5b4a1e-3c3f-4e8f-9a51-0c1c2b8d6f70"
+	foreign := "0f9a8b7c-6d5e-4f3a-8b2c-1d0e9f8a7b6c"
+	Sessions = &sessionsTest{
+		sessions: map[string][]byte{token: []byte(`{"id":1}`), foreign: []byte(`{"id":2}`), "users:1": []byte(`{}`)},
+		owners:   map[string]int64{token: 1, foreign: 2},
+	}
+	ctx := context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1})
 
 	cases := []*UserTestCase{
 		{ // без куки совсем
@@ -528,44 +654,79 @@ func TestDeleteSession(t *testing.T) {
 				Function:     DeleteSession,
 			},
 		},
+		{ // кука не токен сессии: чужие ключи редиса не трогаем
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"session_not_exists"}`,
+				Method:       "DELETE",
+				Pattern:      "/sessions",
+				Function:     DeleteSession,
+				Context:      ctx,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "users:1"}},
+			},
+		},
+		{ // чужая сессия
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"session_not_exists"}`,
+				Method:       "DELETE",
+				Pattern:      "/sessions",
+				Function:     DeleteSession,
+				Context:      ctx,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: foreign}},
+			},
+		},
 		{ // Отвалился storage
 			Case: testutils.Case{
 				ExpectedCode: 500,
-				ExpectedBody: `{"message":"session delete error: storage upal"}`,
-				Method:       "POST",
+				ExpectedBody: `{"message":"session owner error: storage upal"}`,
+				Method:       "DELETE",
 				Pattern:      "/sessions",
-				Cookies: []*http.Cookie{
-					{
-						Name:  "JSESSIONID",
-						Value: "12345",
-					},
-				},
-				Function: DeleteSession,
+				Function:     DeleteSession,
+				Context:      ctx,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: token}},
 			},
 			FailureSession: errors.New("storage upal"),
 		},
-		{ // без пароля
+		{ // всё ок
 			Case: testutils.Case{
 				ExpectedCode: 200,
 				ExpectedBody: ``,
-				Method:       "POST",
+				Method:       "DELETE",
 				Pattern:      "/sessions",
-				Cookies: []*http.Cookie{
-					{
-						Name:  "JSESSIONID",
-						Value: "12345",
-					},
-				},
-				Function: DeleteSession,
+				Function:     DeleteSession,
+				Context:      ctx,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: token}},
 			},
 		},
 	}
 
 	runTableAPITests(t, cases)
+
+	sessions := Sessions.(*sessionsTest).sessions
+	if _, ok := sessions[token]; ok {
+		t.Errorf("TestDeleteSession session was not deleted")
+	}
+	if _, ok := sessions[foreign]; !ok {
+		t.Errorf("TestDeleteSession foreign session was deleted")
+	}
+	if _, ok := sessions["users:1"]; !ok {
+		t.Errorf("TestDeleteSession forged cookie key was deleted")
+	}
 }
 
 func TestGetSession(t *testing.T) {
 	initTests()
+	token := "6d5b4a1e-3c3f-4e8f-9a51-0c1c2b8d6f70"
+	Sessions = &sessionsTest{
+		sessions: map[string][]byte{
+			token: []byte(`{"id":1,"created_at":"2019-05-01T12:00:00Z","last_seen":"2019-05-02T12:00:00Z",` +
+				`"ip":"127.0.0.1","user_agent":"curl/7.54.0","device":"Unknown device"}`),
+			"users:2": []byte(`{"id":2}`),
+		},
+		owners: map[string]int64{token: 1},
+	}
+	cookies := []*http.Cookie{{Name: "JSESSIONID", Value: token}}
 
 	cases := []*UserTestCase{
 		{ // без куки совсем
@@ -577,6 +738,17 @@ func TestGetSession(t *testing.T) {
 				Function:     GetSession,
 			},
 		},
+		{ // кука не токен сессии юзера: ключ редиса по ней не читаем
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"session_not_exists"}`,
+				Method:       "GET",
+				Pattern:      "/sessions",
+				Function:     GetSession,
+				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "users:2"}},
+			},
+		},
 		{ // несуществующий юзер
 			Case: testutils.Case{
 				ExpectedCode: 401,
@@ -585,6 +757,7 @@ func TestGetSession(t *testing.T) {
 				Pattern:      "/sessions",
 				Function:     GetSession,
 				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Cookies:      cookies,
 			},
 		},
 		{ // упала база
@@ -595,12 +768,13 @@ func TestGetSession(t *testing.T) {
 				Pattern:      "/sessions",
 				Function:     GetSession,
 				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Cookies:      cookies,
 			},
 			FailureUser: errors.New("basa upala"),
 		},
 		{ // зарегали юзера
 			Case: testutils.Case{
-				Payload:      []byte(`{"username":"golang","password":"4ever"}`),
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
 				ExpectedCode: 200,
 				ExpectedBody: ``,
 				Method:       "POST",
@@ -617,19 +791,155 @@ func TestGetSession(t *testing.T) {
 				Pattern:      "/users",
 				Function:     UpdateUser,
 				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Cookies:      cookies,
 			},
 		},
 		{ // теперь всё ок
 			Case: testutils.Case{
 				ExpectedCode: 200,
-				ExpectedBody: `{"username":"golang","photo_uuid":"2eb4a823-3a6d-4cba-8767-4d4946890f4f","id":1,"active":true,"vk_secret":""}`,
+				ExpectedBody: `{"session":{"id":"bbefc6b8a12d3f94","current":true,"created_at":"2019-05-01T12:00:00Z",` +
+					`"last_seen":"2019-05-02T12:00:00Z","ip":"127.0.0.1","user_agent":"curl/7.54.0","device":"Unknown device"},` +
+					`"email":"","email_verified":false,"id":1,"active":true,"username":"golang","photo_uuid":"2eb4a823-3a6d-4cba-8767-4d4946890f4f"}`,
+				Method:   "DELETE",
+				Pattern:  "/sessions",
+				Function: GetSession,
+				Context:  context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Cookies:  cookies,
+			},
+		},
+	}
+
+	runTableAPITests(t, cases)
+}
+
+func TestGetAllSessions(t *testing.T) {
+	initTests()
+	Sessions = &sessionsTest{
+		sessions: map[string][]byte{
+			"1234": []byte(`{"id":1,"created_at":"2019-05-01T12:00:00Z","last_seen":"2019-05-02T12:00:00Z",` +
+				`"ip":"127.0.0.1","user_agent":"curl/7.54.0","device":"Unknown device"}`),
+			"4321": []byte(`{"id":2}`),
+		},
+		owners: map[string]int64{
+			"1234": 1,
+			"4321": 2,
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1})
+	cases := []*UserTestCase{
+		{ // без сессии
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"session info is not presented"}`,
+				Method:       "GET",
+				Pattern:      "/sessions/all",
+				Function:     GetAllSessions,
+			},
+		},
+		{ // упал редис
+			Case: testutils.Case{
+				ExpectedCode: 500,
+				ExpectedBody: `{"message":"list sessions error: internal server error"}`,
+				Method:       "GET",
+				Pattern:      "/sessions/all",
+				Function:     GetAllSessions,
+				Context:      ctx,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "1234"}},
+			},
+			FailureSession: utils.ErrInternal,
+		},
+		{ // всё ок, чужие сессии не видны
+			Case: testutils.Case{
+				ExpectedCode: 200,
+				ExpectedBody: `[{"id":"03ac674216f3e15c","current":true,"created_at":"2019-05-01T12:00:00Z",` +
+					`"last_seen":"2019-05-02T12:00:00Z","ip":"127.0.0.1","user_agent":"curl/7.54.0","device":"Unknown device"}]`,
+				Method:   "GET",
+				Pattern:  "/sessions/all",
+				Function: GetAllSessions,
+				Context:  ctx,
+				Cookies:  []*http.Cookie{{Name: "JSESSIONID", Value: "1234"}},
+			},
+		},
+	}
+
+	runTableAPITests(t, cases)
+}
+
+func TestDeleteAllSessions(t *testing.T) {
+	initTests()
+	sessions := &sessionsTest{
+		sessions: map[string][]byte{
+			"1234": []byte(`{"id":1}`),
+			"1235": []byte(`{"id":1}`),
+			"4321": []byte(`{"id":2}`),
+		},
+		owners: map[string]int64{
+			"1234": 1,
+			"1235": 1,
+			"4321": 2,
+		},
+	}
+	Sessions = sessions
+
+	ctx := context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1})
+	cases := []*UserTestCase{
+		{ // упал редис
+			Case: testutils.Case{
+				ExpectedCode: 500,
+				ExpectedBody: `{"message":"sessions delete error: internal server error"}`,
 				Method:       "DELETE",
-				Pattern:      "/sessions",
-				Function:     GetSession,
-				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+				Pattern:      "/sessions/all",
+				Function:     DeleteAllSessions,
+				Context:      ctx,
+			},
+			FailureSession: utils.ErrInternal,
+		},
+		{ // всё ок
+			Case: testutils.Case{
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "DELETE",
+				Pattern:      "/sessions/all",
+				Function:     DeleteAllSessions,
+				Context:      ctx,
 			},
 		},
 	}
 
 	runTableAPITests(t, cases)
+
+	if len(sessions.sessions) != 1 || sessions.owners["4321"] != 2 {
+		t.Errorf("TestDeleteAllSessions left unexpected sessions: %v", sessions.sessions)
+	}
+}
+
+func TestUpdatePasswordRevokesSessions(t *testing.T) {
+	initTests()
+	pass := "old"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "kek", Password: &pass, Active: true}
+	sessions := &sessionsTest{
+		sessions: map[string][]byte{
+			"1234": []byte(`{"id":1}`),
+			"1235": []byte(`{"id":1}`),
+		},
+		owners: map[string]int64{
+			"1234": 1,
+			"1235": 1,
+		},
+	}
+	Sessions = sessions
+
+	form := &jmodels.FormUserUpdate{}
+	if err := form.UnmarshalJSON([]byte(`{"oldPassword":"old","newPassword":"new password"}`)); err != nil {
+		t.Fatalf("TestUpdatePasswordRevokesSessions can not decode form: %v", err)
+	}
+
+	if err := updateUserImpl(&models.SessionPayload{ID: 1}, "1234", &clientInfo{}, form); err != nil {
+		t.Errorf("TestUpdatePasswordRevokesSessions got unexpected error: %v", err)
+	}
+
+	if _, ok := sessions.sessions["1234"]; !ok || len(sessions.sessions) != 1 {
+		t.Errorf("TestUpdatePasswordRevokesSessions left unexpected sessions: %v", sessions.sessions)
+	}
 }
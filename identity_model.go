@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// IdentityAccessObject DAO for user_identities
+type IdentityAccessObject interface {
+	// Get юзер, к которому привязан аккаунт subject у провайдера
+	Get(provider, subject string) (*IdentityModel, error)
+	// ListForUser привязанные аккаунты юзера, от старых к новым
+	ListForUser(userID int64) ([]*IdentityModel, error)
+	// Create привязывает аккаунт. utils.ErrTaken, если он уже привязан
+	// или у юзера уже есть аккаунт этого провайдера
+	Create(i *IdentityModel) error
+	// Delete отвязывает аккаунт провайдера от юзера
+	Delete(userID int64, provider string) error
+}
+
+// IdentityConn implementation of IdentityAccessObject
+type IdentityConn struct{}
+
+// Identities interface variable for models methods
+var Identities IdentityAccessObject
+
+func init() {
+	Identities = &IdentityConn{}
+}
+
+// IdentityModel аккаунт у внешнего провайдера, через который юзер входит
+type IdentityModel struct {
+	Provider  string
+	Subject   string
+	UserID    int64
+	Email     string
+	CreatedAt time.Time
+}
+
+// Get возвращает utils.ErrNotExists, если аккаунт ни к кому не привязан
+func (ic *IdentityConn) Get(provider, subject string) (*IdentityModel, error) {
+	i := &IdentityModel{}
+	err := pqConn.QueryRow(`SELECT i.provider, i.subject, i.user_id, i.email, i.created_at
+		FROM user_identities i WHERE i.provider = $1 AND i.subject = $2;`, provider, subject).
+		Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrNotExists
+		}
+
+		return nil, errors.Wrapf(utils.ErrInternal, "identity get error: %s", err.Error())
+	}
+
+	return i, nil
+}
+
+// ListForUser привязанные аккаунты юзера, от старых к новым
+func (ic *IdentityConn) ListForUser(userID int64) ([]*IdentityModel, error) {
+	rows, err := pqConn.Query(`SELECT i.provider, i.subject, i.user_id, i.email, i.created_at
+		FROM user_identities i WHERE i.user_id = $1 ORDER BY i.created_at;`, userID)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "identities get error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	identities := make([]*IdentityModel, 0)
+	for rows.Next() {
+		i := &IdentityModel{}
+		if err = rows.Scan(&i.Provider, &i.Subject, &i.UserID, &i.Email, &i.CreatedAt); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "identity scan error: %s", err.Error())
+		}
+		identities = append(identities, i)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "identities read error: %s", err.Error())
+	}
+
+	return identities, nil
+}
+
+// Create привязывает аккаунт, CreatedAt заполняется базой
+func (ic *IdentityConn) Create(i *IdentityModel) error {
+	err := pqConn.QueryRow(`INSERT INTO user_identities (provider, subject, user_id, email)
+		VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING RETURNING created_at;`,
+		i.Provider, i.Subject, i.UserID, i.Email).Scan(&i.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrTaken
+		}
+
+		return errors.Wrapf(utils.ErrInternal, "identity create error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Delete возвращает utils.ErrNotExists, если аккаунт провайдера не привязан
+func (ic *IdentityConn) Delete(userID int64, provider string) error {
+	res, err := pqConn.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2;`,
+		userID, provider)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "identity delete error: %s", err.Error())
+	}
+
+	deleted, err := affectedOne(res)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return utils.ErrNotExists
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package jmodels
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// maxEmailLength ограничение длины адреса из RFC 5321
+const maxEmailLength = 254
+
+// NormalizeEmail убирает пробелы по краям. Регистр сохраняется,
+// сравнение без учёта регистра делает CITEXT
+func NormalizeEmail(email string) string {
+	return strings.TrimSpace(email)
+}
+
+// ValidEmail голый адрес вида local@domain.tld, без имени и угловых скобок
+func ValidEmail(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return false
+	}
+
+	at := strings.LastIndex(email, "@")
+	return at > 0 && strings.Contains(email[at+1:], ".")
+}
+
+// NormalizeLogin нормализует то, чем входят: username или email. Email только
+// приводится к нижнему регистру: NFKC сделал бы из адреса другой адрес
+func NormalizeLogin(login string) string {
+	if strings.Contains(login, "@") {
+		return strings.ToLower(NormalizeEmail(login))
+	}
+
+	return NormalizeUsername(login)
+}
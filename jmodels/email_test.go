@@ -0,0 +1,25 @@
+package jmodels
+
+import "testing"
+
+func TestValidEmail(t *testing.T) {
+	cases := []struct {
+		email string
+		valid bool
+	}{
+		{email: "kek@mail.ru", valid: true},
+		{email: "kek.lol+tag@mail.co.uk", valid: true},
+		{email: "kek", valid: false},
+		{email: "kek@localhost", valid: false},
+		{email: "@mail.ru", valid: false},
+		{email: "Kek <kek@mail.ru>", valid: false},
+		{email: "kek@mail.ru, lol@mail.ru", valid: false},
+		{email: "kek lol@mail.ru", valid: false},
+	}
+
+	for i, c := range cases {
+		if valid := ValidEmail(c.email); valid != c.valid {
+			t.Errorf("[%d] TestValidEmail %q got: %v, expected: %v", i, c.email, valid, c.valid)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package jmodels
 
 import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/HotCodeGroup/warscript-utils/utils"
 
 	"github.com/google/uuid"
@@ -24,17 +28,24 @@ type InfoUser struct {
 // отдаётся только по токену
 type ProfileInfoUser struct {
 	InfoUser
-	VkSecret string `json:"vk_secret"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
-// FormUser BasicUser, расширенный паролем, используется для входа и регистрации
+// FormUser BasicUser, расширенный паролем, используется для входа и регистрации.
+// При входе в username можно передать email
 type FormUser struct {
 	BasicUser
 	Password string `json:"password"`
+	Remember bool   `json:"remember"`
+	Email    string `json:"email"`
 }
 
-// Validate валидация полей
+// Validate валидация полей. Username нормализуется через NormalizeLogin,
+// чтобы вход и регистрация видели его одинаково
 func (fu *FormUser) Validate() *utils.ValidationError {
+	fu.Username = NormalizeLogin(fu.Username)
+
 	err := utils.ValidationError{}
 	if fu.Username == "" {
 		err["username"] = utils.ErrRequired.Error()
@@ -51,25 +62,57 @@ func (fu *FormUser) Validate() *utils.ValidationError {
 	return &err
 }
 
+// ValidateRegistration валидация формы регистрации:
+// вдобавок к Validate username должен пройти UsernameRules
+func (fu *FormUser) ValidateRegistration() *utils.ValidationError {
+	if err := fu.Validate(); err != nil {
+		return err
+	}
+
+	if fu.Email != "" {
+		fu.Email = NormalizeEmail(fu.Email)
+		if !ValidEmail(fu.Email) {
+			return &utils.ValidationError{
+				"email": utils.ErrInvalid.Error(),
+			}
+		}
+	}
+
+	return UsernameRules.Validate("username", fu.Username)
+}
+
 // FormUserUpdate форма для обновления полей
 type FormUserUpdate struct {
 	Username    opt.String `json:"username"`
 	PhotoUUID   opt.String `json:"photo_uuid"`
 	OldPassword opt.String `json:"oldPassword"`
 	NewPassword opt.String `json:"newPassword"`
+	Email       opt.String `json:"email"`
 }
 
-// Validate валидация формы
+// Validate валидация формы. Политику username проверяет updateUserImpl:
+// старое имя, заведённое до политики, можно прислать обратно без изменений
 func (fu *FormUserUpdate) Validate() error {
 	err := utils.ValidationError{}
-	if fu.Username.IsDefined() && fu.Username.V == "" {
-		err["username"] = utils.ErrInvalid.Error()
+	if fu.Username.IsDefined() {
+		fu.Username.V = NormalizeUsername(fu.Username.V)
+		if fu.Username.V == "" {
+			err["username"] = utils.ErrInvalid.Error()
+		}
 	}
 
 	if fu.NewPassword.IsDefined() && fu.NewPassword.V == "" {
 		err["newPassword"] = utils.ErrInvalid.Error()
 	}
 
+	// пустой email значит отвязать его
+	if fu.Email.IsDefined() && fu.Email.V != "" {
+		fu.Email.V = NormalizeEmail(fu.Email.V)
+		if !ValidEmail(fu.Email.V) {
+			err["email"] = utils.ErrInvalid.Error()
+		}
+	}
+
 	if fu.PhotoUUID.IsDefined() && fu.PhotoUUID.V != "" {
 		if _, uuidErr := uuid.Parse(fu.PhotoUUID.V); uuidErr != nil {
 			err["photo_uuid"] = utils.ErrInvalid.Error()
@@ -83,7 +126,236 @@ func (fu *FormUserUpdate) Validate() error {
 	return &err
 }
 
+// FormVerifyEmail токен подтверждения email из письма
+type FormVerifyEmail struct {
+	Token string `json:"token"`
+}
+
+// FormPasswordReset запрос сброса пароля по username или email
+type FormPasswordReset struct {
+	Login string `json:"login"`
+}
+
+// FormPasswordResetConfirm новый пароль и токен из письма
+type FormPasswordResetConfirm struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// TwoFactorEnrollment секрет для приложения-аутентификатора: URI для QR-кода
+// и он же текстом для ручного ввода
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// FormTwoFactorCode код из приложения-аутентификатора
+type FormTwoFactorCode struct {
+	Code string `json:"code"`
+}
+
+// FormPasswordConfirm пароль для подтверждения опасного действия
+type FormPasswordConfirm struct {
+	Password string `json:"password"`
+}
+
+// FormTwoFactorDisable пароль для отключения 2FA
+type FormTwoFactorDisable struct {
+	Password string `json:"password"`
+}
+
+// RecoveryCodes одноразовые коды на случай потери телефона,
+// показываются юзеру один раз
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallenge ответ на вход с паролем, когда нужен второй фактор
+type TwoFactorChallenge struct {
+	Challenge string `json:"challenge"`
+}
+
+// FormTwoFactorLogin второй шаг входа: challenge и код из приложения или код восстановления
+type FormTwoFactorLogin struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	Remember     bool   `json:"remember"`
+}
+
+// AdminUser юзер в админке: профиль без секрета вк и роли
+type AdminUser struct {
+	InfoUser
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+}
+
+// FormUsername новый username, который ставит админ
+type FormUsername struct {
+	Username string `json:"username"`
+}
+
+// AdminPasswordReset результат принудительного сброса пароля:
+// Notified, если юзеру ушла ссылка для нового пароля
+type AdminPasswordReset struct {
+	Notified bool `json:"notified"`
+}
+
+// SessionMeta информация о том, откуда и когда открыта сессия
+type SessionMeta struct {
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Device    string    `json:"device"`
+}
+
 // SessionPayload структура, которая хранится в session storage
 type SessionPayload struct {
-	ID int64 `json:"id"`
+	SessionMeta
+	ID       int64 `json:"id"`
+	Remember bool  `json:"remember"`
+}
+
+// ActiveSession одна из активных сессий юзера
+type ActiveSession struct {
+	SessionMeta
+	ID      string `json:"id"`
+	Current bool   `json:"current"`
+}
+
+// CurrentSession профиль юзера вместе с информацией о текущей сессии
+type CurrentSession struct {
+	ProfileInfoUser
+	Session ActiveSession `json:"session"`
+}
+
+// UsernameAvailability можно ли занять username. Reason объясняет, почему нельзя:
+// taken, если он уже занят, или ошибка политики
+type UsernameAvailability struct {
+	Used      bool   `json:"used"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AuditEvent событие из журнала аудита. ActorID заполнен, если событие
+// совершил не сам юзер, и виден только в админке
+type AuditEvent struct {
+	ID        int64             `json:"id"`
+	UserID    int64             `json:"user_id,omitempty"`
+	ActorID   int64             `json:"actor_id,omitempty"`
+	Event     string            `json:"event"`
+	IP        string            `json:"ip"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// AccessToken короткоживущий подписанный токен для других сервисов
+type AccessToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// FormRefreshToken refresh токен для клиентов без куки, им служит токен сессии
+type FormRefreshToken struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// FormOAuthStart начало входа через провайдера
+type FormOAuthStart struct {
+	Remember bool `json:"remember"`
+}
+
+// OAuthRedirect адрес провайдера, куда фронтенд отправляет юзера
+type OAuthRedirect struct {
+	URL string `json:"url"`
+}
+
+// FormOAuthCallback то, с чем провайдер вернул юзера на фронтенд
+type FormOAuthCallback struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// Validate валидация полей
+func (f *FormOAuthCallback) Validate() *utils.ValidationError {
+	err := utils.ValidationError{}
+	if f.Code == "" {
+		err["code"] = utils.ErrRequired.Error()
+	}
+
+	if f.State == "" {
+		err["state"] = utils.ErrRequired.Error()
+	}
+
+	if len(err) == 0 {
+		return nil
+	}
+
+	return &err
+}
+
+// Identity аккаунт провайдера, привязанный к юзеру
+type Identity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VkSecret секрет для привязки вк, показывается один раз после выпуска
+type VkSecret struct {
+	VkSecret string `json:"vk_secret"`
+}
+
+// PersonalTokenNameMaxLength сколько символов может быть в названии личного токена
+const PersonalTokenNameMaxLength = 64
+
+// FormPersonalToken выпуск личного токена для ботов и скриптов
+type FormPersonalToken struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// Validate валидация полей. Какие scopes бывают, проверяет сервис
+func (f *FormPersonalToken) Validate(maxDays int) *utils.ValidationError {
+	err := utils.ValidationError{}
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		err["name"] = utils.ErrRequired.Error()
+	} else if utf8.RuneCountInString(f.Name) > PersonalTokenNameMaxLength {
+		err["name"] = utils.ErrInvalid.Error()
+	}
+
+	if len(f.Scopes) == 0 {
+		err["scopes"] = utils.ErrRequired.Error()
+	}
+
+	if f.ExpiresInDays < 0 || f.ExpiresInDays > maxDays {
+		err["expires_in_days"] = utils.ErrInvalid.Error()
+	}
+
+	if len(err) == 0 {
+		return nil
+	}
+
+	return &err
+}
+
+// PersonalToken личный токен без самого секрета
+type PersonalToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// NewPersonalToken только что выпущенный токен, секрет показывается один раз
+type NewPersonalToken struct {
+	PersonalToken
+	Token string `json:"token"`
 }
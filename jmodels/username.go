@@ -0,0 +1,155 @@
+package jmodels
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Ошибки политики username, отдаются клиенту как значение поля в utils.ValidationError
+var (
+	// ErrUsernameTooShort username короче MinLength символов
+	ErrUsernameTooShort = errors.New("too_short")
+	// ErrUsernameTooLong username длиннее MaxLength символов
+	ErrUsernameTooLong = errors.New("too_long")
+	// ErrUsernameCharset в username есть что-то кроме букв, цифр и _-.
+	ErrUsernameCharset = errors.New("invalid_chars")
+	// ErrUsernameMixedScripts в username смешаны алфавиты, например латиница и кириллица
+	ErrUsernameMixedScripts = errors.New("mixed_scripts")
+	// ErrUsernameReserved username совпадает с зарезервированным с точностью до похожих символов
+	ErrUsernameReserved = errors.New("reserved")
+)
+
+// DefaultReservedUsernames имена, которые нельзя занять по умолчанию
+var DefaultReservedUsernames = []string{
+	"admin", "administrator", "root", "system", "support", "moderator",
+	"warscript", "hotcode", "api", "null", "undefined", "me", "settings",
+}
+
+// UsernamePolicy правила для новых username. На вход со старым username не влияет
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+
+	reserved map[string]struct{}
+}
+
+// UsernameRules политика, которой проверяются регистрация и смена username
+var UsernameRules = NewUsernamePolicy(0, 0, nil)
+
+// NewUsernamePolicy создаёт политику, незаданные значения берутся по умолчанию
+func NewUsernamePolicy(minLength, maxLength int, reserved []string) *UsernamePolicy {
+	p := &UsernamePolicy{
+		MinLength: 3,
+		MaxLength: 32,
+		reserved:  make(map[string]struct{}),
+	}
+
+	if minLength > 0 {
+		p.MinLength = minLength
+	}
+	if maxLength > 0 {
+		p.MaxLength = maxLength
+	}
+
+	if reserved == nil {
+		reserved = DefaultReservedUsernames
+	}
+	for _, name := range reserved {
+		p.reserved[UsernameSkeleton(name)] = struct{}{}
+	}
+
+	return p
+}
+
+// NormalizeUsername приводит username к NFKC, чтобы одинаково выглядящие
+// строки из разных последовательностей кодпоинтов хранились одинаково
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(strings.TrimSpace(username))
+}
+
+// confusables символы, которые легко спутать, и их общий вид
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'l', 'ѕ': 's', 'ј': 'j',
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'ι': 'l', 'κ': 'k', 'τ': 't',
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l', '3': 'e', '5': 's', '$': 's', '@': 'a',
+	'_': -1, '-': -1, '.': -1,
+}
+
+// UsernameSkeleton вид username для сравнения с зарезервированными:
+// нижний регистр, похожие символы склеены, разделители выкинуты
+func UsernameSkeleton(username string) string {
+	return strings.Map(func(r rune) rune {
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return r
+	}, strings.ToLower(NormalizeUsername(username)))
+}
+
+func usernameScript(r rune) string {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return "latin"
+	case unicode.Is(unicode.Cyrillic, r):
+		return "cyrillic"
+	case unicode.Is(unicode.Greek, r):
+		return "greek"
+	default:
+		return "other"
+	}
+}
+
+// Check проверяет нормализованный username и возвращает ошибку первого нарушенного правила
+func (p *UsernamePolicy) Check(username string) error {
+	if username == "" {
+		return utils.ErrRequired
+	}
+
+	length := utf8.RuneCountInString(username)
+	if length < p.MinLength {
+		return ErrUsernameTooShort
+	}
+	if length > p.MaxLength {
+		return ErrUsernameTooLong
+	}
+
+	script := ""
+	for i, r := range username {
+		switch {
+		case unicode.IsLetter(r):
+			if s := usernameScript(r); script == "" {
+				script = s
+			} else if s != script {
+				return ErrUsernameMixedScripts
+			}
+		case unicode.IsDigit(r):
+		case i > 0 && strings.ContainsRune("_-.", r):
+		default:
+			return ErrUsernameCharset
+		}
+	}
+
+	if _, ok := p.reserved[UsernameSkeleton(username)]; ok {
+		return ErrUsernameReserved
+	}
+
+	return nil
+}
+
+// Validate то же, что Check, но в виде ошибки валидации поля field
+func (p *UsernamePolicy) Validate(field, username string) *utils.ValidationError {
+	if err := p.Check(username); err != nil {
+		return &utils.ValidationError{
+			field: err.Error(),
+		}
+	}
+
+	return nil
+}
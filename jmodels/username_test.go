@@ -0,0 +1,95 @@
+package jmodels
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+)
+
+func TestUsernamePolicyCheck(t *testing.T) {
+	p := NewUsernamePolicy(0, 0, nil)
+
+	cases := []struct {
+		username string
+		expected error
+	}{
+		{username: "golang", expected: nil},
+		{username: "дойчланд", expected: nil},
+		{username: "kek_lol.2019", expected: nil},
+		{username: "", expected: utils.ErrRequired},
+		{username: "ke", expected: ErrUsernameTooShort},
+		{username: strings.Repeat("k", 33), expected: ErrUsernameTooLong},
+		{username: "kek lol", expected: ErrUsernameCharset},
+		{username: "_kek", expected: ErrUsernameCharset},
+		{username: "kek<script>", expected: ErrUsernameCharset},
+		{username: "kekкек", expected: ErrUsernameMixedScripts},
+		{username: "admin", expected: ErrUsernameReserved},
+		{username: "ADMIN", expected: ErrUsernameReserved},
+		{username: "adm1n", expected: ErrUsernameReserved},
+		{username: "sys_tem", expected: ErrUsernameReserved},
+		{username: "аdmin", expected: ErrUsernameMixedScripts},
+		{username: "асмоп", expected: nil},
+		{username: "гоот", expected: nil},
+	}
+
+	for i, c := range cases {
+		if err := p.Check(NormalizeUsername(c.username)); err != c.expected {
+			t.Errorf("[%d] TestUsernamePolicyCheck got unexpected error: %v, expected: %v", i, err, c.expected)
+		}
+	}
+}
+
+func TestUsernameSkeleton(t *testing.T) {
+	// полноширинные буквы NFKC приводит к обычным, кириллица склеивается с похожей латиницей
+	if NormalizeUsername(" ｇｏｌａｎｇ ") != "golang" {
+		t.Errorf("TestUsernameSkeleton got unexpected normalization: %s", NormalizeUsername(" ｇｏｌａｎｇ "))
+	}
+	if UsernameSkeleton("Ѕуѕtем") != UsernameSkeleton("system") {
+		t.Errorf("TestUsernameSkeleton confusable names have different skeletons")
+	}
+
+	p := NewUsernamePolicy(0, 0, []string{"кеk"})
+	if p.Check("kek") != ErrUsernameReserved || p.Check("admin") != nil {
+		t.Errorf("TestUsernameSkeleton custom reserved list works wrong")
+	}
+}
+
+func TestFormUserUpdateValidateUsername(t *testing.T) {
+	form := &FormUserUpdate{}
+	if err := form.UnmarshalJSON([]byte(`{"username":"Administrator"}`)); err != nil {
+		t.Fatalf("TestFormUserUpdateValidateUsername can not decode form: %v", err)
+	}
+
+	// политику проверяет сервис, сравнив с текущим именем
+	if err := form.Validate(); err != nil || form.Username.V != "Administrator" {
+		t.Errorf("TestFormUserUpdateValidateUsername got unexpected result: %q, %v", form.Username.V, err)
+	}
+
+	if err := form.UnmarshalJSON([]byte(`{"username":"  "}`)); err != nil {
+		t.Fatalf("TestFormUserUpdateValidateUsername can not decode form: %v", err)
+	}
+	err := form.Validate()
+	if valErr, ok := err.(*utils.ValidationError); !ok || (*valErr)["username"] != "invalid" {
+		t.Errorf("TestFormUserUpdateValidateUsername got unexpected error: %v", err)
+	}
+}
+
+func TestFormUserValidateLogin(t *testing.T) {
+	cases := []struct {
+		login    string
+		expected string
+	}{
+		{" ｇｏｌａｎｇ ", "golang"},
+		{" Gopher@Example.com ", "gopher@example.com"},
+		// ﬁ в адресе не должно стать fi
+		{"ﬁle@example.com", "ﬁle@example.com"},
+	}
+
+	for i, c := range cases {
+		form := &FormUser{BasicUser: BasicUser{Username: c.login}, Password: "kek"}
+		if err := form.Validate(); err != nil || form.Username != c.expected {
+			t.Errorf("[%d] TestFormUserValidateLogin got: %q, %v, expected: %q", i, form.Username, err, c.expected)
+		}
+	}
+}
@@ -3,40 +3,63 @@ package main
 import (
 	"context"
 
+	"github.com/HotCodeGroup/warscript-users/permissions"
 	"github.com/HotCodeGroup/warscript-utils/models"
 	"google.golang.org/grpc"
 )
 
+// LocalAuthClient реализация models.AuthClient без похода по сети,
+// используется middleware внутри самого сервиса. Ошибки отдаются
+// без обёрток, чтобы по errors.Cause можно было понять их природу
 type LocalAuthClient struct{}
 
+// GetUserByID получает одного юзера по ID
 func (c *LocalAuthClient) GetUserByID(ctx context.Context,
 	in *models.UserID, opts ...grpc.CallOption) (*models.InfoUser, error) {
-	return nil, nil
+	return getUserByIDImpl(in.ID)
 }
 
+// GetUserByUsername получает одного юзера по username
 func (c *LocalAuthClient) GetUserByUsername(ctx context.Context,
 	in *models.Username, opts ...grpc.CallOption) (*models.InfoUser, error) {
-	return nil, nil
+	return getUserByUsernameImpl(in.Username)
 }
 
+// GetUserBySecret получает одного юзера по секрету для вк
 func (c *LocalAuthClient) GetUserBySecret(ctx context.Context,
 	in *models.VkSecret, opts ...grpc.CallOption) (*models.InfoUser, error) {
-	return nil, nil
+	return getUserBySecretImpl(in.VkSecret)
 }
 
+// GetSessionInfo получает информацию о сессии по токену сессии или личному токену
 func (c *LocalAuthClient) GetSessionInfo(ctx context.Context,
 	in *models.SessionToken, opts ...grpc.CallOption) (*models.SessionPayload, error) {
-	payload, err := getSessionImpl(in.Token)
+	payload, _, err := authenticateImpl(in.Token)
+	return payload, err
+}
+
+// GetUsersByIDs получает массив юзеров по массиву их ID
+func (c *LocalAuthClient) GetUsersByIDs(ctx context.Context,
+	in *models.UserIDs, opts ...grpc.CallOption) (*models.InfoUsers, error) {
+	return getUsersByIDsImpl(in)
+}
+
+// LocalPermissionsClient реализация permissions.PermissionsClient без похода по сети
+type LocalPermissionsClient struct{}
+
+// CheckPermission есть ли у юзера право
+func (c *LocalPermissionsClient) CheckPermission(ctx context.Context,
+	in *permissions.PermissionCheck, opts ...grpc.CallOption) (*permissions.PermissionCheckResult, error) {
+	allowed, err := hasPermissionImpl(in.ID, in.Permission)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.SessionPayload{
-		ID: payload.ID,
-	}, nil
+	return &permissions.PermissionCheckResult{Allowed: allowed}, nil
 }
 
-func (c *LocalAuthClient) GetUsersByIDs(ctx context.Context,
-	in *models.UserIDs, opts ...grpc.CallOption) (*models.InfoUsers, error) {
-	return nil, nil
+// GetUserPermissions роли и права юзера
+func (c *LocalPermissionsClient) GetUserPermissions(ctx context.Context,
+	in *permissions.UserID, opts ...grpc.CallOption) (*permissions.UserPermissions, error) {
+	return userPermissionsImpl(in.ID)
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/HotCodeGroup/warscript-utils/models"
 	"github.com/HotCodeGroup/warscript-utils/utils"
@@ -11,6 +13,10 @@ import (
 
 func TestLocalAuthClientOK(t *testing.T) {
 	c := &LocalAuthClient{}
+	Users = &usersTest{
+		ids:   2,
+		users: map[int64]UserModel{1: {ID: 1, Username: "kek", Active: true}},
+	}
 
 	Sessions = &sessionsTest{
 		sessions: map[string][]byte{
@@ -52,24 +58,113 @@ func TestLocalAuthClientErr(t *testing.T) {
 	}
 }
 
-func TestLocalAuthClientMocks(t *testing.T) {
+func TestLocalAuthClientUsers(t *testing.T) {
 	c := &LocalAuthClient{}
 
-	if _, err := c.GetUserByID(context.Background(), &models.UserID{ID: 1}); err != nil {
-		t.Errorf("TestLocalAuthClientMocks got unexpected error: %v", err)
+	Users = &usersTest{
+		ids: 3,
+		users: map[int64]UserModel{
+			1: {
+				ID:           1,
+				Username:     "kek",
+				Active:       true,
+				VkSecretHash: hashVkSecret("secret"),
+			},
+			2: {
+				ID:        2,
+				Username:  "lol",
+				PhotoUUID: sql.NullString{String: "01010101-0101-0101-0101-010101010101", Valid: true},
+			},
+		},
+	}
+
+	kek := &models.InfoUser{
+		ID:       1,
+		Username: "kek",
+		Active:   true,
+	}
+	lol := &models.InfoUser{
+		ID:        2,
+		Username:  "lol",
+		PhotoUUID: "01010101-0101-0101-0101-010101010101",
+	}
+
+	user, err := c.GetUserByID(context.Background(), &models.UserID{ID: 1})
+	if err != nil || !reflect.DeepEqual(user, kek) {
+		t.Errorf("TestLocalAuthClientUsers GetUserByID got: %v, %v, expected: %v", user, err, kek)
+	}
+
+	user, err = c.GetUserByUsername(context.Background(), &models.Username{Username: "lol"})
+	if err != nil || !reflect.DeepEqual(user, lol) {
+		t.Errorf("TestLocalAuthClientUsers GetUserByUsername got: %v, %v, expected: %v", user, err, lol)
 	}
 
-	if _, err := c.GetUserByUsername(context.Background(), &models.Username{Username: "kek"}); err != nil {
-		t.Errorf("TestLocalAuthClientMocks got unexpected error: %v", err)
+	user, err = c.GetUserBySecret(context.Background(), &models.VkSecret{VkSecret: "secret"})
+	if err != nil || !reflect.DeepEqual(user, kek) {
+		t.Errorf("TestLocalAuthClientUsers GetUserBySecret got: %v, %v, expected: %v", user, err, kek)
 	}
 
-	if _, err := c.GetUsersByIDs(context.Background(), &models.UserIDs{
+	users, err := c.GetUsersByIDs(context.Background(), &models.UserIDs{
 		IDs: []*models.UserID{
-			{ID: 1},
 			{ID: 2},
 			{ID: 3},
 		},
-	}); err != nil {
-		t.Errorf("TestLocalAuthClientMocks got unexpected error: %v", err)
+	})
+	expected := &models.InfoUsers{Users: []*models.InfoUser{lol}}
+	if err != nil || !reflect.DeepEqual(users, expected) {
+		t.Errorf("TestLocalAuthClientUsers GetUsersByIDs got: %v, %v, expected: %v", users, err, expected)
+	}
+}
+
+func TestLocalAuthClientUsersErr(t *testing.T) {
+	c := &LocalAuthClient{}
+
+	Users = &usersTest{
+		ids:   1,
+		users: map[int64]UserModel{},
+	}
+
+	if _, err := c.GetUserByID(context.Background(), &models.UserID{ID: 1}); err != utils.ErrNotExists {
+		t.Errorf("TestLocalAuthClientUsersErr got unexpected error: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	if _, err := c.GetUserBySecret(context.Background(), &models.VkSecret{VkSecret: "kek"}); err != utils.ErrNotExists {
+		t.Errorf("TestLocalAuthClientUsersErr got unexpected error: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	Users.(*usersTest).SetNextFail(utils.ErrInternal)
+	if _, err := c.GetUsersByIDs(context.Background(), &models.UserIDs{}); err != utils.ErrInternal {
+		t.Errorf("TestLocalAuthClientUsersErr got unexpected error: %v, expected: %v", err, utils.ErrInternal)
+	}
+}
+
+func TestLocalAuthClientTouchesSession(t *testing.T) {
+	c := &LocalAuthClient{}
+	Users = &usersTest{
+		ids:   2,
+		users: map[int64]UserModel{1: {ID: 1, Username: "kek", Active: true}},
+	}
+
+	recent := time.Now().Format(time.RFC3339Nano)
+	sessions := &sessionsTest{
+		sessions: map[string][]byte{
+			"old":    []byte(`{"id":1,"last_seen":"2019-05-01T12:00:00Z"}`),
+			"recent": []byte(`{"id":1,"last_seen":"` + recent + `"}`),
+		},
+	}
+	Sessions = sessions
+
+	for _, token := range []string{"old", "recent"} {
+		if _, err := c.GetSessionInfo(context.Background(), &models.SessionToken{Token: token}); err != nil {
+			t.Errorf("TestLocalAuthClientTouchesSession got unexpected error: %v", err)
+		}
+	}
+
+	old, _ := getSessionImpl("old")
+	if time.Since(old.LastSeen) > time.Minute {
+		t.Errorf("TestLocalAuthClientTouchesSession last_seen was not updated: %v", old.LastSeen)
+	}
+	if string(sessions.sessions["recent"]) != `{"id":1,"last_seen":"`+recent+`"}` {
+		t.Errorf("TestLocalAuthClientTouchesSession recent session was rewritten: %s", sessions.sessions["recent"])
 	}
 }
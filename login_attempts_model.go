@@ -0,0 +1,100 @@
+package main
+
+import (
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/go-redis/redis"
+
+	"github.com/pkg/errors"
+)
+
+// LoginAttemptsAccessObject DAO для счётчиков неудачных входов.
+// subject это "user:<id>" для существующего аккаунта, "login:<логин>" для
+// несуществующего (в нижнем регистре) или "ip:<адрес>"
+type LoginAttemptsAccessObject interface {
+	// LockedFor сколько ещё действует самая долгая из блокировок subjects
+	LockedFor(subjects ...string) (time.Duration, error)
+	// Fail увеличивает счётчик неудач и продлевает его на window
+	Fail(subject string, window time.Duration) (int64, error)
+	// Lock блокирует вход для subject на d
+	Lock(subject string, d time.Duration) error
+	// Reset сбрасывает счётчик и блокировку
+	Reset(subject string) error
+}
+
+// LoginAttemptsConn implementation of LoginAttemptsAccessObject
+type LoginAttemptsConn struct{}
+
+// LoginAttempts interface variable for models methods
+var LoginAttempts LoginAttemptsAccessObject
+
+func init() {
+	LoginAttempts = &LoginAttemptsConn{}
+}
+
+func loginFailuresKey(subject string) string {
+	return "login_failures:" + subject
+}
+
+func loginLockKey(subject string) string {
+	return "login_lock:" + subject
+}
+
+// LockedFor возвращает 0, если ни один subject не заблокирован
+func (la *LoginAttemptsConn) LockedFor(subjects ...string) (time.Duration, error) {
+	cmds := make([]*redis.DurationCmd, len(subjects))
+	_, err := rediCli.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, subject := range subjects {
+			cmds[i] = pipe.PTTL(loginLockKey(subject))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "login lock get error: %s", err.Error())
+	}
+
+	var longest time.Duration
+	for _, cmd := range cmds {
+		// у отсутствующего ключа ttl отрицательный
+		if ttl := cmd.Val(); ttl > longest {
+			longest = ttl
+		}
+	}
+
+	return longest, nil
+}
+
+// Fail возвращает число неудач за окно, включая эту
+func (la *LoginAttemptsConn) Fail(subject string, window time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := rediCli.TxPipelined(func(pipe redis.Pipeliner) error {
+		key := loginFailuresKey(subject)
+		incr = pipe.Incr(key)
+		pipe.PExpire(key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "login failure set error: %s", err.Error())
+	}
+
+	return incr.Val(), nil
+}
+
+// Lock ставит блокировку, перетирая предыдущую
+func (la *LoginAttemptsConn) Lock(subject string, d time.Duration) error {
+	if err := rediCli.Set(loginLockKey(subject), 1, d).Err(); err != nil {
+		return errors.Wrapf(utils.ErrInternal, "login lock set error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Reset удаляет счётчик и блокировку
+func (la *LoginAttemptsConn) Reset(subject string) error {
+	if err := rediCli.Del(loginFailuresKey(subject), loginLockKey(subject)).Err(); err != nil {
+		return errors.Wrapf(utils.ErrInternal, "login attempts reset error: %s", err.Error())
+	}
+
+	return nil
+}
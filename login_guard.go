@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LoginPolicy защита от перебора паролей. После MaxFailures неудач по юзеру
+// (или IPMaxFailures по адресу) за Window вход блокируется на Lockout,
+// и каждая следующая неудача удваивает блокировку, но не больше MaxLockout
+type LoginPolicy struct {
+	MaxFailures   int64
+	IPMaxFailures int64
+	Window        time.Duration
+	Lockout       time.Duration
+	MaxLockout    time.Duration
+}
+
+// loginPolicy политика, с которой работает сервис
+var loginPolicy = NewLoginPolicy(LoginConfig{})
+
+// NewLoginPolicy создаёт политику из конфига, незаданные значения берутся по умолчанию
+func NewLoginPolicy(c LoginConfig) *LoginPolicy {
+	p := &LoginPolicy{
+		MaxFailures:   5,
+		IPMaxFailures: 50,
+		Window:        time.Hour,
+		Lockout:       30 * time.Second,
+		MaxLockout:    time.Hour,
+	}
+
+	if c.MaxFailures > 0 {
+		p.MaxFailures = int64(c.MaxFailures)
+	}
+	if c.IPMaxFailures > 0 {
+		p.IPMaxFailures = int64(c.IPMaxFailures)
+	}
+	if c.Window > 0 {
+		p.Window = time.Duration(c.Window) * time.Second
+	}
+	if c.Lockout > 0 {
+		p.Lockout = time.Duration(c.Lockout) * time.Second
+	}
+	if c.MaxLockout > 0 {
+		p.MaxLockout = time.Duration(c.MaxLockout) * time.Second
+	}
+
+	return p
+}
+
+// LockoutFor на сколько заблокировать вход после failures неудач при лимите max.
+// 0 значит, что блокировать ещё рано
+func (p *LoginPolicy) LockoutFor(failures, max int64) time.Duration {
+	if failures < max {
+		return 0
+	}
+
+	lockout := p.Lockout
+	for i := max; i < failures; i++ {
+		lockout *= 2
+		if lockout >= p.MaxLockout {
+			return p.MaxLockout
+		}
+	}
+
+	return lockout
+}
+
+// LoginLockedError вход временно заблокирован, RetryAfter через сколько можно пробовать снова
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login is locked for %s", e.RetryAfter)
+}
+
+// RetryAfterSeconds значение заголовка Retry-After, округлённое вверх
+func (e *LoginLockedError) RetryAfterSeconds() int64 {
+	return int64((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+var (
+	loginFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "warscript_users_login_failures_total",
+		Help: "Failed login attempts by subject type.",
+	}, []string{"subject"})
+	loginLockoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "warscript_users_login_lockouts_total",
+		Help: "Login lockouts by subject type.",
+	}, []string{"subject"})
+	loginLockedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "warscript_users_login_locked_requests_total",
+		Help: "Login attempts rejected because of an active lockout.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(loginFailuresTotal, loginLockoutsTotal, loginLockedTotal)
+}
+
+// loginSubject счётчик неудач для входа по login. У существующего юзера он один
+// на username и email, иначе переключением между ними блокировку можно обойти.
+// login уже нормализован FormUser.Validate
+func loginSubject(user *UserModel, login string) string {
+	if user != nil {
+		return loginUserSubject(user.ID)
+	}
+
+	return "login:" + strings.ToLower(login)
+}
+
+func loginUserSubject(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+func loginIPSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLockImpl возвращает *LoginLockedError, если вход для subject или ip заблокирован
+func checkLoginLockImpl(subject, ip string) error {
+	retryAfter, err := LoginAttempts.LockedFor(subject, loginIPSubject(ip))
+	if err != nil {
+		return errors.Wrap(err, "check login lock error")
+	}
+
+	if retryAfter > 0 {
+		loginLockedTotal.Inc()
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// loginFailedImpl учитывает неудачный вход и блокирует, если лимит исчерпан.
+// Неудачи для несуществующих юзеров тоже считаются, чтобы по ответу
+// нельзя было понять, есть ли такой юзер
+func loginFailedImpl(subject, ip string) error {
+	subjects := []struct {
+		kind, subject string
+		max           int64
+	}{
+		{"login", subject, loginPolicy.MaxFailures},
+		{"ip", loginIPSubject(ip), loginPolicy.IPMaxFailures},
+	}
+
+	for _, s := range subjects {
+		failures, err := LoginAttempts.Fail(s.subject, loginPolicy.Window)
+		if err != nil {
+			return errors.Wrap(err, "login failure count error")
+		}
+		loginFailuresTotal.WithLabelValues(s.kind).Inc()
+
+		if lockout := loginPolicy.LockoutFor(failures, s.max); lockout > 0 {
+			if err = LoginAttempts.Lock(s.subject, lockout); err != nil {
+				return errors.Wrap(err, "login lock error")
+			}
+			loginLockoutsTotal.WithLabelValues(s.kind).Inc()
+			logger.Warnf("login for %s locked for %s after %d failures", s.subject, lockout, failures)
+		}
+	}
+
+	return nil
+}
+
+// loginSucceededImpl сбрасывает счётчик юзера. Счётчик адреса не сбрасываем:
+// иначе с одним своим аккаунтом можно перебирать чужие бесконечно
+func loginSucceededImpl(userID int64) error {
+	if err := LoginAttempts.Reset(loginUserSubject(userID)); err != nil {
+		return errors.Wrap(err, "login attempts reset error")
+	}
+
+	return nil
+}
+
+// unlockLoginImpl снимает блокировку с login (username или email) или адреса.
+// Как и выдача роли из консоли, сначала пишется в аудит без actor
+func unlockLoginImpl(kind, value string) error {
+	var userID int64
+	var subject string
+	switch kind {
+	case "username":
+		login := jmodels.NormalizeLogin(value)
+		user, err := getUserByLoginImpl(login)
+		if err != nil {
+			if errors.Cause(err) != utils.ErrNotExists {
+				return errors.Wrap(err, "get user error")
+			}
+			user = nil
+		}
+		if user != nil {
+			userID = user.ID
+		}
+		subject = loginSubject(user, login)
+	case "ip":
+		subject = loginIPSubject(value)
+	default:
+		return errors.Errorf("unknown unlock subject: %s", kind)
+	}
+
+	if err := auditImpl(AuditAdminUnlock, userID, 0, &clientInfo{}, map[string]string{kind: value}); err != nil {
+		return err
+	}
+
+	return LoginAttempts.Reset(subject)
+}
+
+// unlockUserImpl админ снимает блокировку входа с юзера
+func unlockUserImpl(admin *models.SessionPayload, client *clientInfo, userID int64) error {
+	if _, err := Users.GetUserByID(userID); err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	if err := auditImpl(AuditAdminUnlock, userID, admin.ID, client, nil); err != nil {
+		return err
+	}
+
+	if err := LoginAttempts.Reset(loginUserSubject(userID)); err != nil {
+		return errors.Wrap(err, "login attempts reset error")
+	}
+
+	return nil
+}
+
+// unlockCommand ручная разблокировка входа: unlock username <имя или email> | unlock ip <адрес>
+func unlockCommand(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: unlock username|ip <value>")
+	}
+
+	if err := unlockLoginImpl(args[0], args[1]); err != nil {
+		return err
+	}
+
+	logger.Infof("login for %s %s unlocked", args[0], args[1])
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+
+	"github.com/pkg/errors"
+)
+
+func TestLoginPolicyLockoutFor(t *testing.T) {
+	p := NewLoginPolicy(LoginConfig{MaxFailures: 3, Lockout: 10, MaxLockout: 60})
+
+	cases := []struct {
+		failures int64
+		expected time.Duration
+	}{
+		{failures: 1, expected: 0},
+		{failures: 2, expected: 0},
+		{failures: 3, expected: 10 * time.Second},
+		{failures: 4, expected: 20 * time.Second},
+		{failures: 5, expected: 40 * time.Second},
+		{failures: 6, expected: time.Minute},
+		{failures: 100, expected: time.Minute},
+	}
+
+	for i, c := range cases {
+		if lockout := p.LockoutFor(c.failures, p.MaxFailures); lockout != c.expected {
+			t.Errorf("[%d] TestLoginPolicyLockoutFor got: %v, expected: %v", i, lockout, c.expected)
+		}
+	}
+
+	if s := (&LoginLockedError{RetryAfter: 1500 * time.Millisecond}).RetryAfterSeconds(); s != 2 {
+		t.Errorf("TestLoginPolicyLockoutFor RetryAfterSeconds got: %d, expected: 2", s)
+	}
+}
+
+func TestCreateSessionLockout(t *testing.T) {
+	initTests()
+	defaultPolicy := loginPolicy
+	defer func() { loginPolicy = defaultPolicy }()
+	loginPolicy = NewLoginPolicy(LoginConfig{MaxFailures: 2, Lockout: 30})
+
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true,
+		Email: sql.NullString{String: "golang@mail.ru", Valid: true}}
+
+	logins := []string{"golang", "GoLang@Mail.ru"}
+	attempt := 0
+	login := func(password string) *httptest.ResponseRecorder {
+		// username и email делят один счётчик
+		attempt++
+		body := []byte(`{"username":"` + logins[attempt%2] + `","password":"` + password + `"}`)
+		r := httptest.NewRequest("POST", "/sessions", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		CreateSession(w, r)
+		return w
+	}
+
+	// успешный вход сбрасывает счётчик
+	login("wrong")
+	if w := login("golang4ever"); w.Code != http.StatusOK {
+		t.Fatalf("TestCreateSessionLockout got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := login("wrong"); w.Code != http.StatusBadRequest {
+			t.Errorf("TestCreateSessionLockout got unexpected code: %d, expected: %d", w.Code, http.StatusBadRequest)
+		}
+	}
+
+	// даже правильный пароль не пускает, пока действует блокировка
+	w := login("golang4ever")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("TestCreateSessionLockout got unexpected response: %d, Retry-After: %q",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+
+	if err := unlockLoginImpl("username", " GoLang@mail.RU "); err != nil {
+		t.Fatalf("TestCreateSessionLockout got unexpected error: %v", err)
+	}
+	if w := login("golang4ever"); w.Code != http.StatusOK {
+		t.Errorf("TestCreateSessionLockout got unexpected code after unlock: %d", w.Code)
+	}
+	if !hasAuditEvents(1, AuditAdminUnlock) {
+		t.Errorf("TestCreateSessionLockout unlock was not audited: %v", auditEventsOf(1))
+	}
+
+	if err := unlockLoginImpl("email", "kek"); err == nil {
+		t.Errorf("TestCreateSessionLockout expected error for unknown subject")
+	}
+}
+
+func TestCreateSessionLockoutUnknownUser(t *testing.T) {
+	initTests()
+
+	cases := []*UserTestCase{
+		{ // хранилище счётчиков лежит
+			Case: testutils.Case{
+				Payload:      []byte(`{"username":"golang","password":"golang4ever"}`),
+				ExpectedCode: 500,
+				ExpectedBody: `{"message":"check login lock error: upala basa"}`,
+				Method:       "POST",
+				Pattern:      "/sessions",
+				Function:     CreateSession,
+			},
+		},
+	}
+	LoginAttempts.(*loginAttemptsTest).SetNextFail(errors.New("upala basa"))
+	runTableAPITests(t, cases)
+
+	form := &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: "nobody"}, Password: "kek"}
+	if _, err := createSessionImpl(form, &clientInfo{IP: "10.0.0.1"}); err == nil {
+		t.Fatalf("TestCreateSessionLockoutUnknownUser expected error")
+	}
+
+	failures := LoginAttempts.(*loginAttemptsTest).failures
+	if failures["login:nobody"] != 1 || failures["ip:10.0.0.1"] != 1 {
+		t.Errorf("TestCreateSessionLockoutUnknownUser got unexpected failures: %v", failures)
+	}
+}
+
+func TestLoginAttemptsModel(t *testing.T) {
+	rediCli = newTestRedis()
+	LoginAttempts = &LoginAttemptsConn{}
+
+	for i := int64(1); i <= 3; i++ {
+		failures, err := LoginAttempts.Fail("username:kek", time.Minute)
+		if err != nil || failures != i {
+			t.Errorf("TestLoginAttemptsModel Fail got: %d, %v, expected: %d", failures, err, i)
+		}
+	}
+
+	if err := LoginAttempts.Lock("ip:10.0.0.1", time.Minute); err != nil {
+		t.Errorf("TestLoginAttemptsModel got unexpected error: %v", err)
+	}
+	if err := LoginAttempts.Lock("username:kek", time.Hour); err != nil {
+		t.Errorf("TestLoginAttemptsModel got unexpected error: %v", err)
+	}
+
+	locked, err := LoginAttempts.LockedFor("username:kek", "ip:10.0.0.1", "ip:10.0.0.2")
+	if err != nil || locked != time.Hour {
+		t.Errorf("TestLoginAttemptsModel LockedFor got: %v, %v, expected: %v", locked, err, time.Hour)
+	}
+
+	if err = LoginAttempts.Reset("username:kek"); err != nil {
+		t.Errorf("TestLoginAttemptsModel got unexpected error: %v", err)
+	}
+	if locked, _ = LoginAttempts.LockedFor("username:kek"); locked != 0 {
+		t.Errorf("TestLoginAttemptsModel lock was not reset: %v", locked)
+	}
+	if failures, _ := LoginAttempts.Fail("username:kek", time.Minute); failures != 1 {
+		t.Errorf("TestLoginAttemptsModel counter was not reset: %d", failures)
+	}
+}
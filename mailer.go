@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Драйверы почты из конфига
+const (
+	MailDriverSMTP   = "smtp"
+	MailDriverFile   = "file"
+	MailDriverStdout = "stdout"
+)
+
+// Mail одно письмо
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer способ отправки писем
+type Mailer interface {
+	Send(m *Mail) error
+}
+
+// mailer через что сервис отправляет письма
+var mailer Mailer = &WriterMailer{W: os.Stdout}
+
+// NewMailer создаёт отправителя по конфигу. По умолчанию письма
+// печатаются в stdout, что удобно для локального запуска
+func NewMailer(c MailConfig) (Mailer, error) {
+	switch c.Driver {
+	case "", MailDriverStdout:
+		return &WriterMailer{W: os.Stdout}, nil
+	case MailDriverFile:
+		f, err := os.OpenFile(c.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, errors.Wrap(err, "can not open mail file")
+		}
+		return &WriterMailer{W: f}, nil
+	case MailDriverSMTP:
+		return &SMTPMailer{
+			Addr:     c.SMTPAddr,
+			From:     c.From,
+			Username: c.SMTPUser,
+			Password: c.SMTPPass,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown mail driver: %s", c.Driver)
+	}
+}
+
+// WriterMailer пишет письма в W целиком, для локального запуска и отладки
+type WriterMailer struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+// Send дописывает письмо в W
+func (m *WriterMailer) Send(mail *Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.W, "To: %s\nSubject: %s\nDate: %s\n\n%s\n\n",
+		mail.To, mail.Subject, time.Now().Format(time.RFC1123Z), mail.Body)
+	if err != nil {
+		return errors.Wrap(err, "mail write error")
+	}
+
+	return nil
+}
+
+// SMTPMailer отправляет письма через SMTP сервер с PLAIN авторизацией
+type SMTPMailer struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// Send отправляет письмо
+func (m *SMTPMailer) Send(mail *Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return errors.Wrap(err, "invalid smtp address")
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	// переводы строк в заголовках позволили бы дописать свои заголовки
+	if strings.ContainsAny(mail.To+mail.Subject, "\r\n") {
+		return errors.New("invalid mail headers")
+	}
+
+	msg := "From: " + m.From + "\r\n" +
+		"To: " + mail.To + "\r\n" +
+		"Subject: " + mail.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + mail.Body
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{mail.To}, []byte(msg)); err != nil {
+		return errors.Wrap(err, "smtp send error")
+	}
+
+	return nil
+}
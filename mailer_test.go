@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriterMailer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	m := &WriterMailer{W: buf}
+
+	if err := m.Send(&Mail{To: "kek@mail.ru", Subject: "Привет", Body: "ссылка"}); err != nil {
+		t.Fatalf("TestWriterMailer got unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "To: kek@mail.ru\nSubject: Привет\n") || !strings.Contains(out, "\n\nссылка\n") {
+		t.Errorf("TestWriterMailer got unexpected output: %q", out)
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	f, err := ioutil.TempFile("", "warscript-users-mail")
+	if err != nil {
+		t.Fatalf("TestNewMailer can not create temp file: %v", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	m, err := NewMailer(MailConfig{Driver: MailDriverFile, Path: f.Name()})
+	if err != nil {
+		t.Fatalf("TestNewMailer got unexpected error: %v", err)
+	}
+	if err = m.Send(&Mail{To: "kek@mail.ru", Subject: "kek", Body: "lol"}); err != nil {
+		t.Fatalf("TestNewMailer got unexpected error: %v", err)
+	}
+	if data, _ := ioutil.ReadFile(f.Name()); !bytes.Contains(data, []byte("To: kek@mail.ru")) {
+		t.Errorf("TestNewMailer mail was not written: %q", data)
+	}
+
+	if m, err = NewMailer(MailConfig{Driver: MailDriverSMTP, SMTPAddr: "localhost:25"}); err != nil {
+		t.Errorf("TestNewMailer got unexpected error: %v", err)
+	} else if err = m.Send(&Mail{To: "kek@mail.ru\r\nBcc: all@mail.ru"}); err == nil {
+		t.Errorf("TestNewMailer expected error for header injection")
+	}
+
+	if _, err = NewMailer(MailConfig{Driver: "pigeon"}); err == nil {
+		t.Errorf("TestNewMailer expected error for unknown driver")
+	}
+}
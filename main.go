@@ -1,7 +1,7 @@
 package main
 
 import (
-	"fmt"
+	"flag"
 	"log"
 	"net"
 	"net/http"
@@ -9,13 +9,15 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 
 	"github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
 
-	"github.com/HotCodeGroup/warscript-utils/balancer"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
 	"github.com/HotCodeGroup/warscript-utils/logging"
 	"github.com/HotCodeGroup/warscript-utils/middlewares"
 	"github.com/HotCodeGroup/warscript-utils/models"
@@ -27,7 +29,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	consulapi "github.com/hashicorp/consul/api"
-	vaultapi "github.com/hashicorp/vault/api"
 )
 
 var logger *logrus.Logger
@@ -49,74 +50,165 @@ func main() {
 		return
 	}
 
-	consulConfig := consulapi.DefaultConfig()
-	consulConfig.Address = os.Getenv("CONSUL_ADDR")
-	consul, err := consulapi.NewClient(consulConfig)
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flagsConfig := NewFlagsConfigProvider(flags)
+	//nolint:errcheck
+	flags.Parse(os.Args[1:])
+
+	// файл < окружение < флаги, потом волт и консул, если они заданы
+	config, err := LoadConfig(
+		&FileConfigProvider{Path: flagsConfig.ConfigPath},
+		&EnvConfigProvider{},
+		flagsConfig,
+		&VaultConfigProvider{},
+		&ConsulConfigProvider{},
+	)
+	if err != nil {
+		logger.Errorf("can not load config: %s", err)
+		return
+	}
+	pqConn, err = postgresql.Connect(config.Postgres.User, config.Postgres.Pass,
+		config.Postgres.Host, config.Postgres.Port, config.Postgres.Database)
 	if err != nil {
-		logger.Errorf("can not connect consul service: %s", err)
+		logger.Errorf("can not connect to postgresql database: %s", err.Error())
 		return
 	}
+	defer func() {
+		pqConn.Close()
+		logger.Info("successfully closed warscript-users postgreSQL connection")
+	}()
 
-	httpPort, grpcPort, err := balancer.GetPorts("warscript-users/bounds", "warscript-users", consul)
+	migrator := NewMigrator(pqConn, migrations)
+	if flags.Arg(0) == "migrate" {
+		if err = migrateCommand(migrator, flags.Args()[1:]); err != nil {
+			logger.Errorf("migrate command failed: %+v", err)
+		}
+		return
+	}
+
+	applied, err := migrator.Up()
 	if err != nil {
-		logger.Errorf("can not find empry port: %s", err)
+		logger.Errorf("can not apply migrations: %+v", err)
 		return
 	}
+	for _, mg := range applied {
+		logger.Infof("migration %s applied", mg)
+	}
 
-	vaultConfig := vaultapi.DefaultConfig()
-	vaultConfig.Address = os.Getenv("VAULT_ADDR")
-	vault, err := vaultapi.NewClient(vaultConfig)
+	sessionPolicy = NewSessionPolicy(config.Session)
+	loginPolicy = NewLoginPolicy(config.Login)
+	deletionPolicy = NewDeletionPolicy(config.Deletion)
+	accessTokenPolicy = NewAccessTokenPolicy(config.AccessTokens)
+	if accessTokenPolicy.Enabled {
+		accessKeySecrets, err = NewSecretBox(config.AccessTokens.KeySecret)
+		if err != nil {
+			logger.Errorf("can not configure access tokens key secret: %s", err)
+			return
+		}
+	}
+	if err = CheckRateLimits(config.RateLimits); err != nil {
+		logger.Errorf("can not configure rate limits: %s", err)
+		return
+	}
+	rateLimits = NewRateLimits(config.RateLimits)
+	publicURL = config.PublicURL
+	tokenSigner, err = NewTokenSigner(config.TokenSecret)
+	if err != nil {
+		logger.Errorf("can not configure token signer: %s", err)
+		return
+	}
+	totpSecrets, err = NewSecretBox(config.TwoFactor.SecretKey)
+	if err != nil {
+		logger.Errorf("can not configure 2fa secret key: %s", err)
+		return
+	}
+	if config.TwoFactor.Issuer != "" {
+		totpIssuer = config.TwoFactor.Issuer
+	}
+	mailer, err = NewMailer(config.Mail)
+	if err != nil {
+		logger.Errorf("can not configure mailer: %s", err)
+		return
+	}
+	jmodels.UsernameRules = jmodels.NewUsernamePolicy(config.Username.MinLength,
+		config.Username.MaxLength, config.Username.Reserved)
+	passwordPolicy, err = NewPasswordPolicy(config.Password)
 	if err != nil {
-		logger.Errorf("can not connect vault service: %s", err)
+		logger.Errorf("can not load password policy: %s", err)
+		return
+	}
+	passwordHashers, err = NewPasswordHashers(config.Hashing)
+	if err != nil {
+		logger.Errorf("can not configure password hashing: %s", err)
+		return
+	}
+	oauthProviders, err = NewOAuthProviders(config.OAuth)
+	if err != nil {
+		logger.Errorf("can not configure oauth providers: %s", err)
+		return
+	}
+	trustedProxies, err = NewTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		logger.Errorf("can not configure trusted proxies: %s", err)
 		return
 	}
 
-	vault.SetToken(os.Getenv("VAULT_TOKEN"))
-	postgreConf, err := vault.Logical().Read("warscript-users/postgres")
-	if err != nil || postgreConf == nil || len(postgreConf.Warnings) != 0 {
-		logger.Errorf("can read warscript-users/postges key: %+v; %+v", err, postgreConf)
+	rediCli, err = redis.Connect(config.Redis.User, config.Redis.Pass,
+		config.Redis.Addr, config.Redis.Database)
+	if err != nil {
+		logger.Errorf("can not connect redis: %s", err)
 		return
 	}
-	redisConf, err := vault.Logical().Read("warscript-users/redis")
-	if err != nil || redisConf == nil || len(redisConf.Warnings) != 0 {
-		logger.Errorf("can read config/redis key: %s; %+v", err, redisConf.Warnings)
+	defer func() {
+		rediCli.Close()
+		logger.Info("successfully closed warscript-users redis connection")
+	}()
+
+	if flags.Arg(0) == "role" {
+		if err = roleCommand(flags.Args()[1:]); err != nil {
+			logger.Errorf("role command failed: %s", err)
+		}
 		return
 	}
 
-	httpServiceID := fmt.Sprintf("warscript-users-http:%d", httpPort)
-	err = consul.Agent().ServiceRegister(&consulapi.AgentServiceRegistration{
-		ID:      httpServiceID,
-		Name:    "warscript-users-http",
-		Port:    httpPort,
-		Address: "127.0.0.1",
-	})
-	defer deregisterService(consul, httpServiceID)
+	if flags.Arg(0) == "purge" {
+		if err = purgeDeactivatedImpl(); err != nil {
+			logger.Errorf("purge command failed: %s", err)
+		}
+		return
+	}
 
-	grpcServiceID := fmt.Sprintf("warscript-users-grpc:%d", grpcPort)
-	err = consul.Agent().ServiceRegister(&consulapi.AgentServiceRegistration{
-		ID:      grpcServiceID,
-		Name:    "warscript-users-grpc",
-		Port:    grpcPort,
-		Address: "127.0.0.1",
-	})
-	defer deregisterService(consul, grpcServiceID)
+	if flags.Arg(0) == "unlock" {
+		if err = unlockCommand(flags.Args()[1:]); err != nil {
+			logger.Errorf("unlock command failed: %s", err)
+		}
+		return
+	}
 
-	rediCli, err = redis.Connect(redisConf.Data["user"].(string),
-		redisConf.Data["pass"].(string), redisConf.Data["addr"].(string),
-		redisConf.Data["database"].(string))
-	if err != nil {
-		logger.Errorf("can not connect redis: %s", err)
+	httpPort, grpcPort := config.HTTPPort, config.GRPCPort
+	if httpPort <= 0 || grpcPort <= 0 {
+		logger.Errorf("http and grpc ports are not configured: %d, %d", httpPort, grpcPort)
 		return
 	}
-	defer rediCli.Close()
 
-	pqConn, err = postgresql.Connect(postgreConf.Data["user"].(string), postgreConf.Data["pass"].(string),
-		postgreConf.Data["host"].(string), postgreConf.Data["port"].(string), postgreConf.Data["database"].(string))
+	// ключи подписи нужны до первого запроса
+	if accessTokenPolicy.Enabled {
+		if err = refreshAccessKeysImpl(time.Now()); err != nil {
+			logger.Errorf("can not load access token keys: %s", err)
+			return
+		}
+
+		stopRotation := make(chan struct{})
+		go runAccessKeyRotation(stopRotation)
+		defer close(stopRotation)
+	}
+
+	deregisterServices, err := registerServices(config)
 	if err != nil {
-		logger.Errorf("can not connect to postgresql database: %s", err.Error())
+		logger.Errorf("can not register services: %s", err)
 		return
 	}
-	defer pqConn.Close()
+	defer deregisterServices()
 
 	auth := &AuthManager{}
 	listenGRPCPort, err := net.Listen("tcp", ":"+strconv.Itoa(grpcPort))
@@ -125,55 +217,112 @@ func main() {
 		return
 	}
 
-	serverGRPCAuth := grpc.NewServer()
+	// если какой-то из серверов упал, тоже завершаемся
+	serveErrors := make(chan error, 2)
+
+	serverGRPCAuth := grpc.NewServer(grpc.UnaryInterceptor(ErrorsInterceptor))
 	models.RegisterAuthServer(serverGRPCAuth, auth)
+	permissions.RegisterPermissionsServer(serverGRPCAuth, &PermissionsManager{})
 	logger.Infof("Auth gRPC service successfully started at port %d", grpcPort)
 	go func() {
-		if err = serverGRPCAuth.Serve(listenGRPCPort); err != nil {
-			logger.Fatalf("Auth gRPC service failed at port %d", grpcPort)
-			os.Exit(1)
+		if err := serverGRPCAuth.Serve(listenGRPCPort); err != nil {
+			serveErrors <- errors.Wrapf(err, "Auth gRPC service failed at port %d", grpcPort)
 		}
 	}()
 
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, os.Kill, os.Interrupt, syscall.SIGTERM)
+	r := newRouter()
 
+	http.Handle("/metrics", promhttp.Handler())
+	http.Handle("/", middlewares.RecoverMiddleware(middlewares.AccessLogMiddleware(r, logger), logger))
+
+	serverHTTP := &http.Server{
+		Addr: ":" + strconv.Itoa(httpPort),
+	}
+	logger.Infof("Auth HTTP service successfully started at port %d", httpPort)
 	go func() {
-		<-signals
+		if err := serverHTTP.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErrors <- errors.Wrapf(err, "Auth HTTP service failed at port %d", httpPort)
+		}
+	}()
 
-		// вырубили http
-		deregisterService(consul, httpServiceID)
-		// вырубили grpc
-		deregisterService(consul, grpcServiceID)
-		// отрубили базули
-		rediCli.Close()
-		logger.Info("successfully closed warscript-users redis connection")
-		pqConn.Close()
-		logger.Info("successfully closed warscript-users postgreSQL connection")
+	stopPurger := make(chan struct{})
+	go runPurger(stopPurger)
+	defer close(stopPurger)
 
-		logger.Infof("[SIGNAL] Stopped by signal!")
-		os.Exit(0)
-	}()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
+	select {
+	case sig := <-signals:
+		logger.Infof("[SIGNAL] Stopped by signal %s!", sig)
+	case err = <-serveErrors:
+		logger.Errorf("server error: %s", err)
+	}
+
+	// сначала убираем себя из консула, чтобы на нас перестали слать запросы,
+	// потом дожидаемся текущих, базы закроются в defer'ах
+	deregisterServices()
+	shutdownServers(time.Duration(config.ShutdownTimeout)*time.Second, serverHTTP, serverGRPCAuth)
+}
+
+// newRouter маршруты HTTP API
+func newRouter() *mux.Router {
 	localGRPCAuth := &LocalAuthClient{}
+	localGRPCPermissions := &LocalPermissionsClient{}
 	r := mux.NewRouter().PathPrefix("/v1").Subrouter()
 
-	r.HandleFunc("/sessions", middlewares.WithAuthentication(GetSession, logger, localGRPCAuth)).Methods("GET")
-	r.HandleFunc("/sessions", CreateSession).Methods("POST")
-	r.HandleFunc("/sessions", middlewares.WithAuthentication(DeleteSession, logger, localGRPCAuth)).Methods("DELETE")
+	// учётку, сессии и токены меняют только из сессии, личный токен сюда не пускаем
+	sessionOnly := func(h http.HandlerFunc) http.HandlerFunc {
+		return WithAuthentication(WithSessionOnly(h), localGRPCAuth)
+	}
 
-	r.HandleFunc("/users", CreateUser).Methods("POST")
-	r.HandleFunc("/users", middlewares.WithAuthentication(UpdateUser, logger, localGRPCAuth)).Methods("PUT")
-	r.HandleFunc("/users/{user_id:[0-9]+}", GetUser).Methods("GET")
-	r.HandleFunc("/users/used", middlewares.WithLimiter(CheckUsername, rate.NewLimiter(3, 5), logger)).Methods("POST")
+	r.HandleFunc("/sessions", sessionOnly(GetSession)).Methods("GET")
+	r.HandleFunc("/sessions", WithRateLimit(CreateSession, rateLimits["login"])).Methods("POST")
+	r.HandleFunc("/sessions", sessionOnly(DeleteSession)).Methods("DELETE")
+	r.HandleFunc("/sessions/2fa", WithRateLimit(CreateSessionTwoFactor, rateLimits["login"])).Methods("POST")
+	r.HandleFunc("/sessions/all", WithAuthentication(GetAllSessions, localGRPCAuth)).Methods("GET")
+	r.HandleFunc("/sessions/all", sessionOnly(DeleteAllSessions)).Methods("DELETE")
+	r.HandleFunc("/sessions/token", WithRateLimit(CreateAccessToken, rateLimits["access_token"])).Methods("POST")
+	r.HandleFunc("/oauth/{provider}", WithRateLimit(StartOAuth, rateLimits["login"])).Methods("POST")
+	r.HandleFunc("/oauth/{provider}/callback", WithRateLimit(CompleteOAuth, rateLimits["login"])).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", GetJWKS).Methods("GET")
 
-	http.Handle("/metrics", promhttp.Handler())
-	http.Handle("/", middlewares.RecoverMiddleware(middlewares.AccessLogMiddleware(r, logger), logger))
+	r.HandleFunc("/users", WithRateLimit(CreateUser, rateLimits["register"])).Methods("POST")
+	r.HandleFunc("/users", sessionOnly(UpdateUser)).Methods("PUT")
+	r.HandleFunc("/users", sessionOnly(DeactivateUser)).Methods("DELETE")
+	r.HandleFunc("/users/reactivate", WithRateLimit(ReactivateUser, rateLimits["login"])).Methods("POST")
+	r.HandleFunc("/users/{user_id:[0-9]+}", GetUser).Methods("GET")
+	r.HandleFunc("/users/verify", WithRateLimit(VerifyEmail, rateLimits["email_verify"])).Methods("POST")
+	r.HandleFunc("/users/2fa", sessionOnly(EnrollTwoFactor)).Methods("POST")
+	r.HandleFunc("/users/2fa/confirm", sessionOnly(ConfirmTwoFactor)).Methods("POST")
+	r.HandleFunc("/users/2fa", sessionOnly(DisableTwoFactor)).Methods("DELETE")
+	r.HandleFunc("/users/me/events", WithAuthentication(GetOwnEvents, localGRPCAuth)).Methods("GET")
+	r.HandleFunc("/users/me/identities", WithAuthentication(GetIdentities, localGRPCAuth)).Methods("GET")
+	r.HandleFunc("/users/me/identities/{provider}", sessionOnly(LinkIdentity)).Methods("POST")
+	r.HandleFunc("/users/me/identities/{provider}", sessionOnly(UnlinkIdentity)).Methods("DELETE")
+	r.HandleFunc("/users/me/vk-secret", sessionOnly(RotateVkSecret)).Methods("POST")
+	r.HandleFunc("/users/me/vk-secret", sessionOnly(RevokeVkSecret)).Methods("DELETE")
+	r.HandleFunc("/tokens", WithAuthentication(GetPersonalTokens, localGRPCAuth)).Methods("GET")
+	r.HandleFunc("/tokens", sessionOnly(CreatePersonalToken)).Methods("POST")
+	r.HandleFunc("/tokens/{id:[0-9]+}", WithAuthentication(RevokePersonalToken, localGRPCAuth)).Methods("DELETE")
+	r.HandleFunc("/password-reset", WithRateLimit(RequestPasswordReset, rateLimits["password_reset"])).Methods("POST")
+	r.HandleFunc("/password-reset/confirm",
+		WithRateLimit(ConfirmPasswordReset, rateLimits["password_reset_confirm"])).Methods("POST")
+	r.HandleFunc("/users/used", WithRateLimit(CheckUsername, rateLimits["username_check"])).Methods("POST")
 
-	logger.Infof("Auth HTTP service successfully started at port %d", httpPort)
-	err = http.ListenAndServe(":"+strconv.Itoa(httpPort), nil)
-	if err != nil {
-		logger.Errorf("cant start main server. err: %s", err.Error())
-		return
+	// админка: каждый маршрут сначала проверяет сессию, потом право
+	admin := r.PathPrefix("/admin").Subrouter()
+	adminOnly := func(h http.HandlerFunc) http.HandlerFunc {
+		return WithAuthentication(WithPermission(h, permissions.ManageUsers, localGRPCPermissions), localGRPCAuth)
 	}
+	admin.HandleFunc("/users", adminOnly(AdminSearchUsers)).Methods("GET")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/ban", adminOnly(AdminBanUser)).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/unban", adminOnly(AdminUnbanUser)).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/logout", adminOnly(AdminLogoutUser)).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/unlock", adminOnly(AdminUnlockUser)).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/password-reset", adminOnly(AdminResetPassword)).Methods("POST")
+	admin.HandleFunc("/users/{user_id:[0-9]+}/username", adminOnly(AdminRenameUser)).Methods("PUT")
+	admin.HandleFunc("/events", adminOnly(AdminListEvents)).Methods("GET")
+
+	return r
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+)
+
+type contextKey int
+
+// personalTokenScopesKey ключ контекста, по которому лежат scopes,
+// если запрос пришёл с личным токеном
+const personalTokenScopesKey contextKey = 1
+
+// personalTokenScopes scopes личного токена запроса. false, если вошли сессией
+func personalTokenScopes(r *http.Request) ([]string, bool) {
+	scopes, ok := r.Context().Value(personalTokenScopesKey).([]string)
+	return scopes, ok
+}
+
+// WithAuthentication проверка токена перед исполнением запроса.
+// В отличие от middlewares.WithAuthentication отличает отсутствующую
+// или истёкшую сессию (401) от недоступного хранилища (500).
+// Вместо куки можно прийти с личным токеном в Authorization: Bearer
+func WithAuthentication(next http.HandlerFunc, cli models.AuthClient) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := utils.GetLogger(r, logger, "WithAuthentication")
+		errWriter := utils.NewErrorResponseWriter(w, logger)
+
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			withPersonalToken(next, w, r, errWriter, auth)
+			return
+		}
+
+		cookie, err := r.Cookie("JSESSIONID")
+		if err != nil || cookie == nil {
+			errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "can not load cookie"))
+			return
+		}
+
+		session, err := cli.GetSessionInfo(r.Context(), &models.SessionToken{Token: cookie.Value})
+		if err != nil {
+			if errors.Cause(err) == ErrSessionNotExists {
+				errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "get session error"))
+			} else {
+				errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "get session error"))
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, session)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// bearerToken токен из заголовка Authorization: Bearer <токен>
+func bearerToken(auth string) (string, bool) {
+	parts := strings.SplitN(auth, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
+// withPersonalToken пускает запрос с личным токеном, если его scopes
+// разрешают метод запроса
+func withPersonalToken(next http.HandlerFunc, w http.ResponseWriter, r *http.Request,
+	errWriter *utils.ErrorResponseWriter, auth string) {
+	token, ok := bearerToken(auth)
+	if !ok || !isPersonalToken(token) {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("authorization header is not a personal token"))
+		return
+	}
+
+	session, scopes, err := authenticateImpl(token)
+	if err != nil {
+		if errors.Cause(err) == ErrSessionNotExists {
+			errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "personal token error"))
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "personal token error"))
+		}
+		return
+	}
+
+	if !scopeAllowsMethod(scopes, r.Method) {
+		errWriter.WriteWarn(http.StatusForbidden, errors.Errorf("personal token scopes do not allow %s", r.Method))
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, session)
+	ctx = context.WithValue(ctx, personalTokenScopesKey, scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// WithSessionOnly не пускает личные токены: сессии, пароль, 2FA, привязки
+// и сами токены меняются только из сессии, даже если у токена есть write.
+// Ставится после WithAuthentication
+func WithSessionOnly(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := personalTokenScopes(r); ok {
+			logger := utils.GetLogger(r, logger, "WithSessionOnly")
+			utils.NewErrorResponseWriter(w, logger).WriteWarn(http.StatusForbidden,
+				errors.New("personal token is not allowed here"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithPermission пускает дальше, только если у юзера есть permission.
+// Ставится после WithAuthentication. Личному токену право должно быть ещё
+// и выдано в scopes
+func WithPermission(next http.HandlerFunc, permission string, cli permissions.PermissionsClient) http.HandlerFunc {
+	checked := permissions.WithPermission(next, logger, cli, permission)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scopes, ok := personalTokenScopes(r); ok && !hasScope(scopes, permission) {
+			logger := utils.GetLogger(r, logger, "WithPermission")
+			utils.NewErrorResponseWriter(w, logger).WriteWarn(http.StatusForbidden,
+				errors.Errorf("personal token has no scope %s", permission))
+			return
+		}
+
+		checked.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+)
+
+func TestWithAuthentication(t *testing.T) {
+	Users = &usersTest{
+		ids:   2,
+		users: map[int64]UserModel{1: {ID: 1, Username: "kek", Active: true}},
+	}
+	Sessions = &sessionsTest{
+		sessions: map[string][]byte{
+			"1234": []byte(`{"id":1}`),
+		},
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		if info := SessionInfo(r); info == nil || info.ID != 1 {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := WithAuthentication(ok, &LocalAuthClient{})
+
+	cases := []*UserTestCase{
+		{ // Всё ок
+			Case: testutils.Case{
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "GET",
+				Pattern:      "/sessions",
+				Function:     handler,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "1234"}},
+			},
+		},
+		{ // Нет куки
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"can not load cookie: http: named cookie not present"}`,
+				Method:       "GET",
+				Pattern:      "/sessions",
+				Function:     handler,
+			},
+		},
+		{ // Сессия истекла
+			Case: testutils.Case{
+				ExpectedCode: 401,
+				ExpectedBody: `{"message":"get session error: session_not_exists"}`,
+				Method:       "GET",
+				Pattern:      "/sessions",
+				Function:     handler,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "1235"}},
+			},
+		},
+		{ // Редис упал
+			Case: testutils.Case{
+				ExpectedCode: 500,
+				ExpectedBody: `{"message":"get session error: internal server error"}`,
+				Method:       "GET",
+				Pattern:      "/sessions",
+				Function:     handler,
+				Cookies:      []*http.Cookie{{Name: "JSESSIONID", Value: "1234"}},
+			},
+			FailureSession: utils.ErrInternal,
+		},
+	}
+
+	runTableAPITests(t, cases)
+}
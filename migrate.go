@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// migrationsLockID ключ advisory lock, чтобы несколько контейнеров
+// не накатывали миграции одновременно
+const migrationsLockID = 7355608
+
+// ErrMigrationChecksum применённая миграция была изменена после наката
+var ErrMigrationChecksum = errors.New("migration checksum mismatch")
+
+// ErrMigrationUnknown в базе есть версия, которой нет в сервисе
+var ErrMigrationUnknown = errors.New("unknown migration version")
+
+// Migration одна версия схемы базы данных
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum хеш текста миграции, по нему проверяем, что уже
+// применённую миграцию никто не поменял
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up + m.Down))
+	return hex.EncodeToString(sum[:])
+}
+
+// String человекочитаемое имя миграции для логов
+func (m *Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus состояние одной миграции
+type MigrationStatus struct {
+	Migration *Migration
+	Applied   bool
+}
+
+// Migrator накатывает и откатывает миграции из списка
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// NewMigrator создаёт мигратор с отсортированным по версиям списком миграций
+func NewMigrator(db *sql.DB, migrations []*Migration) *Migrator {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+	}
+}
+
+// prepare берёт отдельное соединение из пула (advisory lock живёт в рамках сессии),
+// берёт lock и только под ним создаёт таблицу учёта миграций: иначе два контейнера,
+// стартующие одновременно, столкнутся на создании таблицы. Отпускать через release
+func (m *Migrator) prepare(ctx context.Context) (*sql.Conn, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "can not get migrations connection: %s", err.Error())
+	}
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationsLockID)
+	if err != nil {
+		//nolint:errcheck
+		conn.Close()
+		return nil, errors.Wrapf(utils.ErrInternal, "can not acquire migrations lock: %s", err.Error())
+	}
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL CONSTRAINT schema_migrations_pk PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ DEFAULT now() NOT NULL
+	);`)
+	if err != nil {
+		m.release(ctx, conn)
+		return nil, errors.Wrapf(utils.ErrInternal, "can not create schema_migrations table: %s", err.Error())
+	}
+
+	return conn, nil
+}
+
+// release отпускает lock и возвращает соединение в пул
+func (m *Migrator) release(ctx context.Context, conn *sql.Conn) {
+	//nolint:errcheck
+	conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, migrationsLockID)
+	//nolint:errcheck
+	conn.Close()
+}
+
+// applied возвращает применённые версии и проверяет их чексуммы
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations ORDER BY version;`)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "can not load applied migrations: %s", err.Error())
+	}
+	defer rows.Close()
+
+	known := make(map[int64]*Migration, len(m.migrations))
+	for _, mg := range m.migrations {
+		known[mg.Version] = mg
+	}
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var checksum string
+		if err = rows.Scan(&version, &checksum); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "applied migration scan error: %s", err.Error())
+		}
+
+		mg, ok := known[version]
+		if !ok {
+			return nil, errors.Wrapf(ErrMigrationUnknown, "version %d", version)
+		}
+		if mg.Checksum() != checksum {
+			return nil, errors.Wrapf(ErrMigrationChecksum, "version %d (%s)", version, mg.Name)
+		}
+
+		applied[version] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "applied migrations rows error: %s", err.Error())
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, mg *Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "can not open migration transaction: %s", err.Error())
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	query := mg.Down
+	if up {
+		query = mg.Up
+	}
+
+	if _, err = tx.Exec(query); err != nil {
+		return errors.Wrapf(utils.ErrInternal, "migration %d (%s) error: %s", mg.Version, mg.Name, err.Error())
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3);`,
+			mg.Version, mg.Name, mg.Checksum())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = $1;`, mg.Version)
+	}
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "migration %d (%s) track error: %s", mg.Version, mg.Name, err.Error())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(utils.ErrInternal, "migration transaction commit error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Up накатывает все ещё не применённые миграции по порядку
+func (m *Migrator) Up() ([]*Migration, error) {
+	ctx := context.Background()
+	conn, err := m.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.release(ctx, conn)
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]*Migration, 0)
+	for _, mg := range m.migrations {
+		if applied[mg.Version] {
+			continue
+		}
+
+		if err = m.run(ctx, conn, mg, true); err != nil {
+			return done, err
+		}
+		done = append(done, mg)
+	}
+
+	return done, nil
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(steps int) ([]*Migration, error) {
+	ctx := context.Background()
+	conn, err := m.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.release(ctx, conn)
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]*Migration, 0)
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mg := m.migrations[i]
+		if !applied[mg.Version] {
+			continue
+		}
+
+		if err = m.run(ctx, conn, mg, false); err != nil {
+			return done, err
+		}
+		done = append(done, mg)
+	}
+
+	return done, nil
+}
+
+// Status возвращает список всех миграций с отметкой о применении
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	ctx := context.Background()
+	conn, err := m.prepare(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer m.release(ctx, conn)
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		statuses = append(statuses, &MigrationStatus{
+			Migration: mg,
+			Applied:   applied[mg.Version],
+		})
+	}
+
+	return statuses, nil
+}
+
+// migrateCommand обработка подкоманды `migrate [up|down N|status]`
+func migrateCommand(m *Migrator, args []string) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		done, err := m.Up()
+		for _, mg := range done {
+			logger.Infof("migration %s applied", mg)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return errors.Errorf("invalid steps count: %s", args[1])
+			}
+			steps = n
+		}
+
+		done, err := m.Down(steps)
+		for _, mg := range done {
+			logger.Infof("migration %s reverted", mg)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			logger.Infof("migration %s applied: %t", st.Migration, st.Applied)
+		}
+		return nil
+	default:
+		return errors.Errorf("unknown migrate command: %s", cmd)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+var testMigrations = []*Migration{
+	{
+		Version: 2,
+		Name:    "second",
+		Up:      `ALTER TABLE kek ADD COLUMN lol TEXT;`,
+		Down:    `ALTER TABLE kek DROP COLUMN lol;`,
+	},
+	{
+		Version: 1,
+		Name:    "first",
+		Up:      `CREATE TABLE kek (id BIGINT);`,
+		Down:    `DROP TABLE kek;`,
+	},
+}
+
+// expectMigrationsPrepare таблица учёта создаётся только под lock
+func expectMigrationsPrepare(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrateUpOK(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	expectMigrationsPrepare(mock)
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}).
+			AddRow(1, testMigrations[1].Checksum()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE kek ADD COLUMN lol").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(2, "second", testMigrations[0].Checksum()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up()
+	if err != nil {
+		t.Errorf("TestMigrateUpOK got unexpected error: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Errorf("TestMigrateUpOK applied unexpected migrations: %v", done)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigrateUpOK there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateUpChecksumMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	expectMigrationsPrepare(mock)
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}).
+			AddRow(1, "kek"))
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = m.Up()
+	if errors.Cause(err) != ErrMigrationChecksum {
+		t.Errorf("TestMigrateUpChecksumMismatch got unexpected error: %v, expected: %v", err, ErrMigrationChecksum)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigrateUpChecksumMismatch there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateUpUnknownVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	expectMigrationsPrepare(mock)
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}).
+			AddRow(3, "kek"))
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = m.Up()
+	if errors.Cause(err) != ErrMigrationUnknown {
+		t.Errorf("TestMigrateUpUnknownVersion got unexpected error: %v, expected: %v", err, ErrMigrationUnknown)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigrateUpUnknownVersion there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateUpExecErr(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	expectMigrationsPrepare(mock)
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE kek").WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Up()
+	if errors.Cause(err) != utils.ErrInternal {
+		t.Errorf("TestMigrateUpExecErr got unexpected error: %v, expected: %v", err, utils.ErrInternal)
+	}
+	if len(done) != 0 {
+		t.Errorf("TestMigrateUpExecErr applied unexpected migrations: %v", done)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigrateUpExecErr there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrateDownOK(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	expectMigrationsPrepare(mock)
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}).
+			AddRow(1, testMigrations[1].Checksum()).
+			AddRow(2, testMigrations[0].Checksum()))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE kek DROP COLUMN lol").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	done, err := m.Down(1)
+	if err != nil {
+		t.Errorf("TestMigrateDownOK got unexpected error: %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Errorf("TestMigrateDownOK reverted unexpected migrations: %v", done)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigrateDownOK there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigratePrepareCreateErr(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	m := NewMigrator(db, testMigrations)
+
+	mock.ExpectExec("pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnError(sql.ErrConnDone)
+	mock.ExpectExec("pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err = m.Status(); errors.Cause(err) != utils.ErrInternal {
+		t.Errorf("TestMigratePrepareCreateErr got unexpected error: %v, expected: %v", err, utils.ErrInternal)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestMigratePrepareCreateErr there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMigrationsVersionsUnique(t *testing.T) {
+	seen := make(map[int64]bool)
+	for _, mg := range migrations {
+		if seen[mg.Version] {
+			t.Errorf("TestMigrationsVersionsUnique duplicated version: %d", mg.Version)
+		}
+		seen[mg.Version] = true
+	}
+}
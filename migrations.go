@@ -0,0 +1,224 @@
+package main
+
+// migrations схема базы сервиса. Уже применённые миграции менять нельзя:
+// их чексумма сверяется при каждом запуске, любые изменения схемы
+// оформляются новой версией в конце списка
+var migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "create_users",
+		Up: `CREATE EXTENSION IF NOT EXISTS citext;
+CREATE TABLE IF NOT EXISTS "users"
+(
+	id bigserial not null
+		constraint user_pk
+			primary key,
+	username CITEXT CONSTRAINT username_empty not null check ( username <> '' ),
+	password BYTEA NOT NULL,
+	active boolean default true not null,
+	photo_uuid UUID DEFAULT NULL,
+	CONSTRAINT unique_username UNIQUE(username)
+);`,
+		Down: `DROP TABLE IF EXISTS "users" CASCADE;`,
+	},
+	{
+		Version: 2,
+		Name:    "users_vk_secret",
+		Up: `ALTER TABLE "users" ADD COLUMN IF NOT EXISTS vk_secret TEXT;
+UPDATE "users" SET vk_secret = substr(md5(random()::text || id::text), 1, 8) WHERE vk_secret IS NULL;
+ALTER TABLE "users" ALTER COLUMN vk_secret SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_vk_secret ON "users" (vk_secret);`,
+		Down: `DROP INDEX IF EXISTS unique_vk_secret;
+ALTER TABLE "users" DROP COLUMN IF EXISTS vk_secret;`,
+	},
+	{
+		Version: 3,
+		Name:    "users_email",
+		Up: `ALTER TABLE "users" ADD COLUMN IF NOT EXISTS email CITEXT DEFAULT NULL;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS email_verified boolean default false not null;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_email ON "users" (email) WHERE email IS NOT NULL;`,
+		Down: `DROP INDEX IF EXISTS unique_email;
+ALTER TABLE "users" DROP COLUMN IF EXISTS email_verified;
+ALTER TABLE "users" DROP COLUMN IF EXISTS email;`,
+	},
+	{
+		Version: 4,
+		Name:    "create_user_totp",
+		Up: `CREATE TABLE IF NOT EXISTS "user_totp"
+(
+	user_id bigint not null
+		constraint user_totp_pk
+			primary key
+		constraint user_totp_user_fk
+			references "users" (id) on delete cascade,
+	secret BYTEA NOT NULL,
+	enabled boolean default false not null,
+	last_step bigint default 0 not null,
+	recovery_codes TEXT[] default '{}' not null
+);`,
+		Down: `DROP TABLE IF EXISTS "user_totp";`,
+	},
+	{
+		Version: 5,
+		Name:    "users_deactivated_at",
+		Up: `ALTER TABLE "users" ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS users_deactivated_at ON "users" (deactivated_at) WHERE deactivated_at IS NOT NULL;`,
+		Down: `DROP INDEX IF EXISTS users_deactivated_at;
+ALTER TABLE "users" DROP COLUMN IF EXISTS deactivated_at;`,
+	},
+	{
+		Version: 6,
+		Name:    "create_user_roles",
+		Up: `CREATE TABLE IF NOT EXISTS "user_roles"
+(
+	user_id bigint not null
+		constraint user_roles_user_fk
+			references "users" (id) on delete cascade,
+	role TEXT not null,
+	constraint user_roles_pk primary key (user_id, role)
+);`,
+		Down: `DROP TABLE IF EXISTS "user_roles";`,
+	},
+	{
+		Version: 7,
+		Name:    "create_audit_events",
+		Up: `CREATE TABLE IF NOT EXISTS "audit_events"
+(
+	id bigserial not null
+		constraint audit_events_pk
+			primary key,
+	user_id bigint,
+	actor_id bigint,
+	event TEXT not null,
+	ip TEXT default '' not null,
+	details JSONB default '{}' not null,
+	created_at TIMESTAMPTZ default now() not null
+);
+CREATE INDEX IF NOT EXISTS audit_events_user ON "audit_events" (user_id, id);
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON "audit_events"
+	FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();`,
+		Down: `DROP TABLE IF EXISTS "audit_events";
+DROP FUNCTION IF EXISTS audit_events_append_only();`,
+	},
+	{
+		Version: 8,
+		Name:    "create_role_permissions",
+		Up: `CREATE TABLE IF NOT EXISTS "role_permissions"
+(
+	role TEXT not null,
+	permission TEXT not null,
+	constraint role_permissions_pk primary key (role, permission)
+);
+INSERT INTO "role_permissions" (role, permission) VALUES
+	('admin', 'users.manage'),
+	('admin', 'games.moderate'),
+	('admin', 'bots.moderate'),
+	('moderator', 'games.moderate'),
+	('moderator', 'bots.moderate')
+ON CONFLICT DO NOTHING;`,
+		Down: `DROP TABLE IF EXISTS "role_permissions";`,
+	},
+	{
+		Version: 9,
+		Name:    "create_signing_keys",
+		Up: `CREATE TABLE IF NOT EXISTS "signing_keys"
+(
+	kid TEXT not null
+		constraint signing_keys_pk
+			primary key,
+	public_key BYTEA not null,
+	private_key BYTEA not null,
+	created_at TIMESTAMPTZ default now() not null
+);`,
+		Down: `DROP TABLE IF EXISTS "signing_keys";`,
+	},
+	{
+		Version: 10,
+		Name:    "create_user_identities",
+		Up: `CREATE TABLE IF NOT EXISTS "user_identities"
+(
+	provider TEXT not null,
+	subject TEXT not null,
+	user_id bigint not null
+		constraint user_identities_user_fk
+			references "users" (id) on delete cascade,
+	email TEXT default '' not null,
+	created_at TIMESTAMPTZ default now() not null,
+	constraint user_identities_pk primary key (provider, subject),
+	constraint user_identities_user_provider unique (user_id, provider)
+);`,
+		Down: `DROP TABLE IF EXISTS "user_identities";`,
+	},
+	{
+		Version: 11,
+		Name:    "users_vk_secret_hash",
+		Up: `ALTER TABLE "users" ADD COLUMN IF NOT EXISTS vk_secret_hash BYTEA;
+UPDATE "users" SET vk_secret_hash = sha256(convert_to(vk_secret, 'UTF8')) WHERE vk_secret_hash IS NULL;
+ALTER TABLE "users" ALTER COLUMN vk_secret_hash SET NOT NULL;
+ALTER TABLE "users" ADD CONSTRAINT unique_vk_secret_hash UNIQUE (vk_secret_hash);
+DROP INDEX IF EXISTS unique_vk_secret;
+ALTER TABLE "users" DROP COLUMN IF EXISTS vk_secret;`,
+		Down: `ALTER TABLE "users" ADD COLUMN IF NOT EXISTS vk_secret TEXT;
+UPDATE "users" SET vk_secret = substr(md5(random()::text || id::text), 1, 8) WHERE vk_secret IS NULL;
+ALTER TABLE "users" ALTER COLUMN vk_secret SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_vk_secret ON "users" (vk_secret);
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS unique_vk_secret_hash;
+ALTER TABLE "users" DROP COLUMN IF EXISTS vk_secret_hash;`,
+	},
+	{
+		Version: 12,
+		Name:    "create_personal_tokens",
+		Up: `CREATE TABLE IF NOT EXISTS "personal_tokens"
+(
+	id BIGSERIAL not null
+		constraint personal_tokens_pk primary key,
+	user_id bigint not null
+		constraint personal_tokens_user_fk
+			references "users" (id) on delete cascade,
+	name TEXT not null,
+	token_hash BYTEA not null
+		constraint unique_personal_token_hash unique,
+	scopes TEXT[] default '{}' not null,
+	created_at TIMESTAMPTZ default now() not null,
+	expires_at TIMESTAMPTZ not null,
+	last_used_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS personal_tokens_user ON "personal_tokens" (user_id);`,
+		Down: `DROP TABLE IF EXISTS "personal_tokens";`,
+	},
+	{
+		Version: 13,
+		Name:    "audit_events_scrub",
+		Up: `CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	-- при обезличивании можно стереть адрес и подробности, но не само событие
+	IF TG_OP = 'UPDATE' AND NEW.id = OLD.id AND NEW.event = OLD.event
+		AND NEW.user_id IS NOT DISTINCT FROM OLD.user_id AND NEW.actor_id IS NOT DISTINCT FROM OLD.actor_id
+		AND NEW.created_at = OLD.created_at AND NEW.ip = ''
+		AND (NEW.details = OLD.details OR NEW.details = '{}'::jsonb) THEN
+		RETURN NEW;
+	END IF;
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;`,
+		Down: `CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;`,
+	},
+	{
+		Version: 14,
+		Name:    "reset_legacy_vk_secrets",
+		// миграция 11 перенесла в хеши старые секреты из 8 hex символов, их можно перебрать.
+		// Все выданные до этой миграции секреты перестают работать, юзер выпускает новый
+		Up: `UPDATE "users" SET vk_secret_hash = sha256(convert_to(random()::text || clock_timestamp()::text
+	|| id::text, 'UTF8'));`,
+		Down: `-- старые секреты не восстановить`,
+	},
+}
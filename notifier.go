@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Notifier доставляет юзеру ссылки из служебных сценариев
+type Notifier interface {
+	// VerifyEmail ссылка подтверждения email
+	VerifyEmail(user *UserModel, link string) error
+	// PasswordReset ссылка сброса пароля
+	PasswordReset(user *UserModel, link string) error
+}
+
+// notifier через что сервис доставляет уведомления
+var notifier Notifier = &MailNotifier{}
+
+// MailNotifier отправляет уведомления письмом на email юзера через mailer
+type MailNotifier struct{}
+
+func (n *MailNotifier) send(user *UserModel, subject, body string) error {
+	if !user.Email.Valid {
+		return errors.New("user has no email")
+	}
+
+	return mailer.Send(&Mail{
+		To:      user.Email.String,
+		Subject: subject,
+		Body:    body,
+	})
+}
+
+// VerifyEmail письмо со ссылкой подтверждения
+func (n *MailNotifier) VerifyEmail(user *UserModel, link string) error {
+	return n.send(user, "Подтверждение email в Warscript", fmt.Sprintf(
+		"Привет, %s!\n\nЧтобы подтвердить email, перейди по ссылке:\n%s\n\n"+
+			"Если ты не регистрировался в Warscript, просто проигнорируй это письмо.",
+		user.Username, link))
+}
+
+// PasswordReset письмо со ссылкой сброса пароля
+func (n *MailNotifier) PasswordReset(user *UserModel, link string) error {
+	return n.send(user, "Сброс пароля в Warscript", fmt.Sprintf(
+		"Привет, %s!\n\nЧтобы задать новый пароль, перейди по ссылке:\n%s\n\n"+
+			"Ссылка действует %d минут и только один раз. "+
+			"Если ты не просил сбросить пароль, просто проигнорируй это письмо.",
+		user.Username, link, int(passwordResetTTL.Minutes())))
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// oauthStateTTL сколько ждём возвращения юзера от провайдера
+const oauthStateTTL = 10 * time.Minute
+
+// oauthMaxResponse больше этого от провайдера не читаем
+const oauthMaxResponse = 1 << 20
+
+// ErrOAuthRejected провайдер не принял код или не отдал, кто юзер
+var ErrOAuthRejected = errors.New("oauth_rejected")
+
+// ExternalIdentity юзер, как его видит провайдер
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// OAuthProvider внешний провайдер входа по OAuth2 authorization code с PKCE
+type OAuthProvider interface {
+	// AuthURL адрес провайдера, на который отправляем юзера
+	AuthURL(state, challenge string) string
+	// Exchange меняет код, с которым юзер вернулся, на его аккаунт у провайдера
+	Exchange(code, verifier string) (*ExternalIdentity, error)
+}
+
+// oauthProviders провайдеры по названию из конфига
+var oauthProviders = map[string]OAuthProvider{}
+
+// NewOAuthProviders создаёт провайдеров из конфига
+func NewOAuthProviders(configs map[string]OAuthProviderConfig) (map[string]OAuthProvider, error) {
+	providers := make(map[string]OAuthProvider, len(configs))
+	for name, c := range configs {
+		p, err := NewOIDCProvider(c)
+		if err != nil {
+			return nil, errors.Wrapf(err, "oauth provider %s", name)
+		}
+		providers[name] = p
+	}
+
+	return providers, nil
+}
+
+// randomToken случайная строка из n байт в base64url
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// pkceChallenge code_challenge метода S256 для verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OIDCProvider провайдер OpenID Connect или любой OAuth2 с userinfo,
+// который отдаёт sub, email, email_verified и preferred_username
+type OIDCProvider struct {
+	config OAuthProviderConfig
+	client *http.Client
+}
+
+// NewOIDCProvider проверяет, что в конфиге есть всё нужное для входа
+func NewOIDCProvider(c OAuthProviderConfig) (*OIDCProvider, error) {
+	required := []struct{ field, value string }{
+		{"auth_url", c.AuthURL},
+		{"token_url", c.TokenURL},
+		{"userinfo_url", c.UserInfoURL},
+		{"client_id", c.ClientID},
+		{"client_secret", c.ClientSecret},
+		{"redirect_url", c.RedirectURL},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return nil, errors.Errorf("%s is not configured", r.field)
+		}
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{
+		config: c,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// AuthURL адрес authorization endpoint с параметрами запроса
+func (p *OIDCProvider) AuthURL(state, challenge string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.config.AuthURL + sep + q.Encode()
+}
+
+// Exchange меняет код на токен провайдера, а токен на userinfo
+func (p *OIDCProvider) Exchange(code, verifier string) (*ExternalIdentity, error) {
+	resp, err := p.client.PostForm(p.config.TokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "token request error")
+	}
+	defer resp.Body.Close()
+
+	// на неверный код провайдеры отвечают 400 или 401
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		return nil, errors.Wrapf(ErrOAuthRejected, "token request failed with status %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	token := &struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, oauthMaxResponse)).Decode(token); err != nil {
+		return nil, errors.Wrap(err, "token response decode error")
+	}
+	if token.AccessToken == "" {
+		return nil, errors.Wrap(ErrOAuthRejected, "access token is not presented")
+	}
+
+	return p.userInfo(token.AccessToken)
+}
+
+func (p *OIDCProvider) userInfo(accessToken string) (*ExternalIdentity, error) {
+	req, err := http.NewRequest("GET", p.config.UserInfoURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "userinfo request create error")
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "userinfo request error")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("userinfo request failed with status %d", resp.StatusCode)
+	}
+
+	// sub бывает и числом, а email_verified строкой
+	claims := make(map[string]interface{})
+	dec := json.NewDecoder(io.LimitReader(resp.Body, oauthMaxResponse))
+	dec.UseNumber()
+	if err = dec.Decode(&claims); err != nil {
+		return nil, errors.Wrap(err, "userinfo decode error")
+	}
+
+	identity := &ExternalIdentity{
+		Subject:       claimString(claims["sub"]),
+		Email:         claimString(claims["email"]),
+		EmailVerified: claims["email_verified"] == true || claims["email_verified"] == "true",
+		Username:      claimString(claims["preferred_username"]),
+	}
+	if identity.Subject == "" {
+		return nil, errors.Wrap(ErrOAuthRejected, "userinfo has no sub")
+	}
+
+	return identity, nil
+}
+
+func claimString(v interface{}) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case json.Number:
+		return c.String()
+	}
+
+	return ""
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/gorilla/mux"
+
+	"github.com/pkg/errors"
+)
+
+// oauthStateCookie кука, которая привязывает state к браузеру, начавшему вход
+const oauthStateCookie = "OAUTHSTATE"
+
+func setOAuthStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/",
+		Value:    state,
+		Expires:  time.Now().Add(oauthStateTTL),
+		HttpOnly: true,
+	})
+}
+
+// writeOAuthStart отдаёт адрес провайдера и ставит куку state
+func writeOAuthStart(w http.ResponseWriter, errWriter *utils.ErrorResponseWriter, authURL, state string, err error) {
+	if err != nil {
+		if errors.Cause(err) == ErrOAuthProviderUnknown {
+			errWriter.WriteWarn(http.StatusNotFound, err)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	setOAuthStateCookie(w, state)
+	utils.WriteApplicationJSON(w, http.StatusOK, &jmodels.OAuthRedirect{URL: authURL})
+}
+
+// StartOAuth начинает вход через провайдера: фронтенд отправляет юзера по url из ответа
+func StartOAuth(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "StartOAuth")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormOAuthStart{}
+	if err := utils.DecodeBodyJSON(r.Body, form); err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	authURL, state, err := startOAuthImpl(mux.Vars(r)["provider"], 0, form.Remember)
+	writeOAuthStart(w, errWriter, authURL, state, err)
+}
+
+// CompleteOAuth принимает code и state, с которыми провайдер вернул юзера на фронтенд.
+// Если юзер начинал вход, открывает сессию, если привязку, то привязывает аккаунт
+func CompleteOAuth(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "CompleteOAuth")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormOAuthCallback{}
+	if err := utils.DecodeBodyJSON(r.Body, form); err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	boundState := ""
+	if cookie, err := r.Cookie(oauthStateCookie); err == nil {
+		boundState = cookie.Value
+	}
+
+	session, remember, err := finishOAuthImpl(mux.Vars(r)["provider"], form, boundState, newClientInfo(r))
+
+	// state одноразовый, кука больше не нужна при любом исходе
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+	})
+
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+			return
+		}
+
+		if tfErr, ok := err.(*TwoFactorRequiredError); ok {
+			utils.WriteApplicationJSON(w, http.StatusAccepted, &jmodels.TwoFactorChallenge{
+				Challenge: tfErr.Challenge,
+			})
+			return
+		}
+
+		switch errors.Cause(err) {
+		case ErrOAuthProviderUnknown:
+			errWriter.WriteWarn(http.StatusNotFound, err)
+		case ErrSessionNotExists, utils.ErrNotExists:
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+		default:
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if session != nil {
+		setSessionCookie(w, session.Token, remember)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// LinkIdentity начинает привязку аккаунта провайдера к текущему юзеру
+func LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "LinkIdentity")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	authURL, state, err := startOAuthImpl(mux.Vars(r)["provider"], info.ID, false)
+	writeOAuthStart(w, errWriter, authURL, state, err)
+}
+
+// GetIdentities привязанные аккаунты провайдеров
+func GetIdentities(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "GetIdentities")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	identities, err := listIdentitiesImpl(info.ID)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, identities)
+}
+
+// UnlinkIdentity отвязывает аккаунт провайдера
+func UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "UnlinkIdentity")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	err := unlinkIdentityImpl(info.ID, mux.Vars(r)["provider"], newClientInfo(r))
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+			return
+		}
+
+		if errors.Cause(err) == utils.ErrNotExists {
+			errWriter.WriteWarn(http.StatusNotFound, err)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// oauthUsernameAttempts сколько username пробуем, прежде чем сдаться
+const oauthUsernameAttempts = 10
+
+// oauthFallbackUsername основа username, если у провайдера нет подходящего
+const oauthFallbackUsername = "player"
+
+// ErrOAuthProviderUnknown провайдера нет в конфиге
+var ErrOAuthProviderUnknown = errors.New("oauth_provider_unknown")
+
+func oauthProviderImpl(name string) (OAuthProvider, error) {
+	p, ok := oauthProviders[name]
+	if !ok {
+		return nil, ErrOAuthProviderUnknown
+	}
+
+	return p, nil
+}
+
+// startOAuthImpl запоминает verifier PKCE и возвращает адрес провайдера и state.
+// Если userID не 0, вернувшийся аккаунт привяжется к этому юзеру
+func startOAuthImpl(name string, userID int64, remember bool) (string, string, error) {
+	p, err := oauthProviderImpl(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier, err := randomToken(32)
+	if err != nil {
+		return "", "", errors.Wrap(err, "pkce verifier generate error")
+	}
+
+	state, err := OAuthStates.Create(&OAuthState{
+		Provider: name,
+		Verifier: verifier,
+		UserID:   userID,
+		Remember: remember,
+	}, oauthStateTTL)
+	if err != nil {
+		return "", "", errors.Wrap(err, "oauth state create error")
+	}
+
+	return p.AuthURL(state, pkceChallenge(verifier)), state, nil
+}
+
+// finishOAuthImpl принимает юзера, вернувшегося от провайдера. boundState state
+// из куки браузера: без него чужой code и state не подсунуть. Для входа
+// возвращает открытую сессию и надо ли её запомнить, для привязки nil
+func finishOAuthImpl(name string, form *jmodels.FormOAuthCallback, boundState string,
+	client *clientInfo) (*Session, bool, error) {
+	if err := form.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	invalidState := &utils.ValidationError{
+		"state": utils.ErrInvalid.Error(),
+	}
+	if subtle.ConstantTimeCompare([]byte(form.State), []byte(boundState)) != 1 {
+		return nil, false, invalidState
+	}
+
+	p, err := oauthProviderImpl(name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	st, err := OAuthStates.Consume(form.State)
+	if err != nil {
+		if errors.Cause(err) == ErrTokenInvalid {
+			return nil, false, invalidState
+		}
+		return nil, false, errors.Wrap(err, "oauth state consume error")
+	}
+	if st.Provider != name {
+		return nil, false, invalidState
+	}
+
+	identity, err := p.Exchange(form.Code, st.Verifier)
+	if err != nil {
+		if errors.Cause(err) == ErrOAuthRejected {
+			logger.Warnf("oauth provider %s rejected code: %s", name, err)
+			return nil, false, &utils.ValidationError{
+				"code": utils.ErrInvalid.Error(),
+			}
+		}
+		return nil, false, errors.Wrap(err, "oauth code exchange error")
+	}
+
+	if st.UserID != 0 {
+		return nil, false, linkIdentityImpl(st.UserID, name, identity, client)
+	}
+
+	user, err := oauthUserImpl(name, identity, client)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !user.Active {
+		return nil, false, &utils.ValidationError{
+			"username": "deactivated",
+		}
+	}
+
+	// провайдер заменяет пароль, но не второй фактор
+	if err = requireSecondFactorImpl(user); err != nil {
+		return nil, false, err
+	}
+
+	session, err := startSessionImpl(user, st.Remember, client)
+	return session, st.Remember, err
+}
+
+// oauthUserImpl юзер, к которому привязан аккаунт провайдера.
+// При первом входе юзер создаётся
+func oauthUserImpl(name string, identity *ExternalIdentity, client *clientInfo) (*UserModel, error) {
+	linked, err := Identities.Get(name, identity.Subject)
+	if err == nil {
+		return Users.GetUserByID(linked.UserID)
+	}
+	if errors.Cause(err) != utils.ErrNotExists {
+		return nil, errors.Wrap(err, "identity get error")
+	}
+
+	user, err := createOAuthUserImpl(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Identities.Create(&IdentityModel{
+		Provider: name,
+		Subject:  identity.Subject,
+		UserID:   user.ID,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		if errors.Cause(err) != utils.ErrTaken {
+			return nil, errors.Wrap(err, "identity create error")
+		}
+
+		// параллельный первый вход успел создать своего юзера: наш
+		// выключаем, его обезличит очистка, и входим в тот
+		if err = Users.Deactivate(user.ID); err != nil {
+			logger.Warnf("can not deactivate duplicate oauth user %d: %s", user.ID, err)
+		}
+		if linked, err = Identities.Get(name, identity.Subject); err != nil {
+			return nil, errors.Wrap(err, "identity get error")
+		}
+		return Users.GetUserByID(linked.UserID)
+	}
+
+	auditUserImpl(AuditRegister, user.ID, client, map[string]string{"provider": name})
+	auditUserImpl(AuditIdentityLink, user.ID, client, map[string]string{"provider": name})
+
+	return user, nil
+}
+
+// createOAuthUserImpl регистрирует юзера по аккаунту провайдера. Пароль случайный:
+// задать свой можно через сброс пароля. Email берётся, только если провайдер
+// его подтвердил. Если им уже владеет другой юзер, аккаунт не создаётся и не
+// привязывается сам: владелец может привязать провайдера, войдя с паролем
+func createOAuthUserImpl(identity *ExternalIdentity) (*UserModel, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, errors.Wrap(err, "password generate error")
+	}
+
+	user := &UserModel{Password: &password}
+	if email := jmodels.NormalizeEmail(identity.Email); identity.EmailVerified && jmodels.ValidEmail(email) {
+		user.Email = sql.NullString{String: email, Valid: true}
+		user.EmailVerified = true
+	}
+
+	for attempt := 0; attempt < oauthUsernameAttempts; attempt++ {
+		user.Username, err = oauthUsername(identity, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if jmodels.UsernameRules.Check(user.Username) != nil {
+			continue
+		}
+
+		err = Users.Create(user)
+		switch errors.Cause(err) {
+		case nil:
+			return user, nil
+		case utils.ErrTaken:
+			continue
+		case ErrEmailTaken:
+			return nil, &utils.ValidationError{
+				"email": utils.ErrTaken.Error(),
+			}
+		default:
+			return nil, errors.Wrap(err, "user create error")
+		}
+	}
+
+	return nil, errors.New("can not pick free username")
+}
+
+// oauthUsername username для попытки attempt: сначала как у провайдера,
+// потом со случайным числовым суффиксом
+func oauthUsername(identity *ExternalIdentity, attempt int) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	// оставляем то, что пропустит политика username
+	base = strings.TrimLeft(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.", r) {
+			return r
+		}
+		return -1
+	}, jmodels.NormalizeUsername(base)), "_-.")
+
+	// место под суффикс оставляем сразу, чтобы username с ним и без отличались только им
+	if max := jmodels.UsernameRules.MaxLength - 5; utf8.RuneCountInString(base) > max && max > 0 {
+		base = string([]rune(base)[:max])
+	}
+	if jmodels.UsernameRules.Check(base) != nil {
+		base = oauthFallbackUsername
+	}
+	if attempt == 0 {
+		return base, nil
+	}
+
+	suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		return "", errors.Wrap(err, "username suffix generate error")
+	}
+	return fmt.Sprintf("%s_%04d", base, suffix.Int64()), nil
+}
+
+// linkIdentityImpl привязывает аккаунт провайдера к юзеру, который начал привязку
+func linkIdentityImpl(userID int64, name string, identity *ExternalIdentity, client *clientInfo) error {
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+	if !user.Active {
+		return ErrSessionNotExists
+	}
+
+	err = Identities.Create(&IdentityModel{
+		Provider: name,
+		Subject:  identity.Subject,
+		UserID:   user.ID,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		if errors.Cause(err) == utils.ErrTaken {
+			return &utils.ValidationError{
+				"provider": utils.ErrTaken.Error(),
+			}
+		}
+		return errors.Wrap(err, "identity create error")
+	}
+	auditUserImpl(AuditIdentityLink, user.ID, client, map[string]string{"provider": name})
+
+	return nil
+}
+
+// listIdentitiesImpl привязанные аккаунты юзера
+func listIdentitiesImpl(userID int64) ([]*jmodels.Identity, error) {
+	identities, err := Identities.ListForUser(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "identities get error")
+	}
+
+	res := make([]*jmodels.Identity, 0, len(identities))
+	for _, i := range identities {
+		res = append(res, &jmodels.Identity{
+			Provider:  i.Provider,
+			Email:     i.Email,
+			CreatedAt: i.CreatedAt,
+		})
+	}
+
+	return res, nil
+}
+
+// unlinkIdentityImpl отвязывает аккаунт провайдера. Последний нельзя отвязать
+// без подтверждённого email: пароль юзера, созданного через провайдера,
+// никто не знает, и войти было бы больше никак
+func unlinkIdentityImpl(userID int64, name string, client *clientInfo) error {
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	identities, err := Identities.ListForUser(userID)
+	if err != nil {
+		return errors.Wrap(err, "identities get error")
+	}
+	if len(identities) == 1 && identities[0].Provider == name && !user.EmailVerified {
+		return &utils.ValidationError{
+			"provider": "last_login_method",
+		}
+	}
+
+	if err = Identities.Delete(userID, name); err != nil {
+		return err
+	}
+	auditUserImpl(AuditIdentityUnlink, userID, client, map[string]string{"provider": name})
+
+	return nil
+}
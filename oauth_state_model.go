@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// consumeOAuthStateScript атомарно забирает state, чтобы ответ провайдера
+// нельзя было принять дважды
+var consumeOAuthStateScript = redis.NewScript(`
+local state = redis.call("GET", KEYS[1])
+if not state then
+	return false
+end
+redis.call("DEL", KEYS[1])
+return state
+`)
+
+// OAuthStateAccessObject DAO для state входа через провайдера.
+// В редисе лежит только хеш state
+type OAuthStateAccessObject interface {
+	// Create сохраняет данные входа и возвращает state для провайдера
+	Create(s *OAuthState, ttl time.Duration) (string, error)
+	// Consume тратит state и возвращает сохранённые по нему данные
+	Consume(state string) (*OAuthState, error)
+}
+
+// OAuthStateConn implementation of OAuthStateAccessObject
+type OAuthStateConn struct{}
+
+// OAuthStates interface variable for models methods
+var OAuthStates OAuthStateAccessObject
+
+func init() {
+	OAuthStates = &OAuthStateConn{}
+}
+
+// OAuthState то, что нужно помнить между уходом юзера к провайдеру и возвратом.
+// Если UserID не 0, аккаунт привязывается к этому юзеру, а не используется для входа
+type OAuthState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	UserID   int64  `json:"user_id,omitempty"`
+	Remember bool   `json:"remember,omitempty"`
+}
+
+func oauthStateKey(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return "oauth_state:" + hex.EncodeToString(sum[:])
+}
+
+// Create state случайный, его знают только браузер юзера и провайдер
+func (sc *OAuthStateConn) Create(s *OAuthState, ttl time.Duration) (string, error) {
+	state, err := randomToken(32)
+	if err != nil {
+		return "", errors.Wrapf(utils.ErrInternal, "oauth state generate error: %s", err.Error())
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.Wrapf(utils.ErrInternal, "oauth state marshal error: %s", err.Error())
+	}
+
+	if err = rediCli.Set(oauthStateKey(state), data, ttl).Err(); err != nil {
+		return "", errors.Wrapf(utils.ErrInternal, "oauth state set error: %s", err.Error())
+	}
+
+	return state, nil
+}
+
+// Consume возвращает ErrTokenInvalid, если state нет, он истёк или уже потрачен
+func (sc *OAuthStateConn) Consume(state string) (*OAuthState, error) {
+	data, err := consumeOAuthStateScript.Run(rediCli, []string{oauthStateKey(state)}).String()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrTokenInvalid
+		}
+		return nil, errors.Wrapf(utils.ErrInternal, "oauth state consume error: %s", err.Error())
+	}
+
+	s := &OAuthState{}
+	if err = json.Unmarshal([]byte(data), s); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "oauth state unmarshal error: %s", err.Error())
+	}
+
+	return s, nil
+}
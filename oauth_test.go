@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/gorilla/mux"
+)
+
+func initOAuthTests() *FakeOAuthProvider {
+	initTests()
+	provider := NewFakeOAuthProvider()
+	oauthProviders = map[string]OAuthProvider{"fake": provider}
+
+	return provider
+}
+
+// oauthRequest запрос к маршрутам входа через провайдера. Если userID не 0,
+// запрос идёт от имени этого юзера
+func oauthRequest(userID int64, method, target, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	asUser := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: userID})
+			h(w, r.WithContext(ctx))
+		}
+	}
+	router.HandleFunc("/oauth/{provider}", StartOAuth).Methods("POST")
+	router.HandleFunc("/oauth/{provider}/callback", CompleteOAuth).Methods("POST")
+	router.HandleFunc("/users/me/identities", asUser(GetIdentities)).Methods("GET")
+	router.HandleFunc("/users/me/identities/{provider}", asUser(LinkIdentity)).Methods("POST")
+	router.HandleFunc("/users/me/identities/{provider}", asUser(UnlinkIdentity)).Methods("DELETE")
+
+	r := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// oauthStart начинает вход или привязку и возвращает адрес провайдера и куку state
+func oauthStart(t *testing.T, userID int64, target, body string) (string, *http.Cookie) {
+	w := oauthRequest(userID, "POST", target, body)
+	redirect := &jmodels.OAuthRedirect{}
+	if err := json.Unmarshal(w.Body.Bytes(), redirect); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("oauth start got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	cookie := responseCookie(w, oauthStateCookie)
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatalf("oauth start did not set state cookie: %v", cookie)
+	}
+
+	return redirect.URL, cookie
+}
+
+// oauthLogin проходит вход через провайдер за identity целиком
+func oauthLogin(t *testing.T, provider *FakeOAuthProvider, identity ExternalIdentity) *httptest.ResponseRecorder {
+	authURL, cookie := oauthStart(t, 0, "/oauth/fake", `{"remember":true}`)
+	code, state := provider.Authorize(authURL, identity)
+
+	return oauthRequest(0, "POST", "/oauth/fake/callback",
+		`{"code":"`+code+`","state":"`+state+`"}`, cookie)
+}
+
+func TestOAuthLogin(t *testing.T) {
+	provider := initOAuthTests()
+	identity := ExternalIdentity{Subject: "42", Email: "Gopher@Example.com", EmailVerified: true, Username: "gopher"}
+
+	w := oauthLogin(t, provider, identity)
+	if w.Code != http.StatusOK {
+		t.Fatalf("TestOAuthLogin got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	session := responseCookie(w, "JSESSIONID")
+	if session == nil || session.Expires.IsZero() {
+		t.Fatalf("TestOAuthLogin session cookie was not set: %v", session)
+	}
+	if state := responseCookie(w, oauthStateCookie); state == nil || state.Value != "" {
+		t.Errorf("TestOAuthLogin state cookie was not cleared: %v", state)
+	}
+
+	users := Users.(*usersTest).users
+	user, ok := users[1]
+	if !ok || len(users) != 1 || user.Username != "gopher" || user.GetEmail() != "Gopher@Example.com" ||
+		!user.EmailVerified || user.Password == nil || len(*user.Password) < 32 {
+		t.Fatalf("TestOAuthLogin got unexpected user: %+v", users)
+	}
+	if linked, err := Identities.Get("fake", "42"); err != nil || linked.UserID != 1 {
+		t.Errorf("TestOAuthLogin identity was not linked: %+v, %v", linked, err)
+	}
+
+	// второй вход в тот же аккаунт
+	if w = oauthLogin(t, provider, identity); w.Code != http.StatusOK || len(users) != 1 {
+		t.Errorf("TestOAuthLogin got unexpected second login: %d, %d users", w.Code, len(users))
+	}
+
+	events := auditEventsOf(1)
+	expected := []string{AuditRegister, AuditIdentityLink, AuditLogin, AuditLogin}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Errorf("TestOAuthLogin got events: %v, expected: %v", events, expected)
+	}
+}
+
+func TestCompleteOAuthErrors(t *testing.T) {
+	provider := initOAuthTests()
+	identity := ExternalIdentity{Subject: "42", Username: "gopher"}
+	provider2 := NewFakeOAuthProvider()
+	oauthProviders["other"] = provider2
+
+	authURL, cookie := oauthStart(t, 0, "/oauth/fake", `{}`)
+	code, state := provider.Authorize(authURL, identity)
+	otherURL, otherCookie := oauthStart(t, 0, "/oauth/other", `{}`)
+	_, otherState := provider2.Authorize(otherURL, identity)
+
+	cases := []struct {
+		name     string
+		target   string
+		body     string
+		cookie   *http.Cookie
+		code     int
+		expected string
+	}{
+		{name: "no cookie", body: `{"code":"` + code + `","state":"` + state + `"}`,
+			code: http.StatusBadRequest, expected: `{"state":"invalid"}`},
+		{name: "other browser", body: `{"code":"` + code + `","state":"` + state + `"}`, cookie: otherCookie,
+			code: http.StatusBadRequest, expected: `{"state":"invalid"}`},
+		{name: "other provider", body: `{"code":"` + code + `","state":"` + otherState + `"}`, cookie: otherCookie,
+			code: http.StatusBadRequest, expected: `{"state":"invalid"}`},
+		{name: "empty", body: `{}`, cookie: cookie,
+			code: http.StatusBadRequest, expected: `{"code":"required","state":"required"}`},
+		{name: "unknown provider", target: "/oauth/nope/callback", body: `{"code":"1","state":"` + state + `"}`,
+			cookie: cookie, code: http.StatusNotFound},
+		{name: "wrong code", body: `{"code":"kek","state":"` + state + `"}`, cookie: cookie,
+			code: http.StatusBadRequest, expected: `{"code":"invalid"}`},
+		// state потрачен неудачной попыткой
+		{name: "spent state", body: `{"code":"` + code + `","state":"` + state + `"}`, cookie: cookie,
+			code: http.StatusBadRequest, expected: `{"state":"invalid"}`},
+	}
+
+	for _, c := range cases {
+		if c.target == "" {
+			c.target = "/oauth/fake/callback"
+		}
+		var cookies []*http.Cookie
+		if c.cookie != nil {
+			cookies = append(cookies, c.cookie)
+		}
+
+		w := oauthRequest(0, "POST", c.target, c.body, cookies...)
+		if w.Code != c.code || (c.expected != "" && w.Body.String() != c.expected) {
+			t.Errorf("[%s] TestCompleteOAuthErrors got unexpected result: %d, %s, expected: %d, %s",
+				c.name, w.Code, w.Body.String(), c.code, c.expected)
+		}
+	}
+
+	// провайдер недоступен
+	provider.SetNextFail(utils.ErrInternal)
+	if w := oauthLogin(t, provider, identity); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestCompleteOAuthErrors got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+
+	if w := oauthRequest(0, "POST", "/oauth/nope", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("TestCompleteOAuthErrors got unexpected start code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+	if len(Users.(*usersTest).users) != 0 {
+		t.Errorf("TestCompleteOAuthErrors user was created: %+v", Users.(*usersTest).users)
+	}
+}
+
+func TestOAuthPKCE(t *testing.T) {
+	provider := initOAuthTests()
+
+	// подменённый verifier провайдер не примет
+	authURL, cookie := oauthStart(t, 0, "/oauth/fake", `{}`)
+	code, state := provider.Authorize(authURL, ExternalIdentity{Subject: "42"})
+	OAuthStates.(*oauthStatesTest).states[state].Verifier = "attacker"
+
+	w := oauthRequest(0, "POST", "/oauth/fake/callback", `{"code":"`+code+`","state":"`+state+`"}`, cookie)
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"code":"invalid"}` {
+		t.Errorf("TestOAuthPKCE got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthNewUser(t *testing.T) {
+	provider := initOAuthTests()
+	users := Users.(*usersTest)
+
+	// username занят: берём с суффиксом
+	users.SetNextFail(utils.ErrTaken)
+	if w := oauthLogin(t, provider, ExternalIdentity{Subject: "1", Username: "gopher"}); w.Code != http.StatusOK {
+		t.Fatalf("TestOAuthNewUser got unexpected code: %d, %s", w.Code, w.Body.String())
+	}
+	if u := users.users[1]; !regexp.MustCompile(`^gopher_[0-9]{4}$`).MatchString(u.Username) {
+		t.Errorf("TestOAuthNewUser got unexpected username: %s", u.Username)
+	}
+
+	// неподтверждённый email не сохраняется, username из него
+	if w := oauthLogin(t, provider, ExternalIdentity{Subject: "2", Email: "rust@example.com"}); w.Code != http.StatusOK {
+		t.Fatalf("TestOAuthNewUser got unexpected code: %d, %s", w.Code, w.Body.String())
+	}
+	if u := users.users[2]; u.Username != "rust" || u.Email.Valid || u.EmailVerified {
+		t.Errorf("TestOAuthNewUser got unexpected user: %+v", u)
+	}
+
+	// email уже у другого юзера: сам не привязываем
+	users.SetNextFail(ErrEmailTaken)
+	w := oauthLogin(t, provider, ExternalIdentity{Subject: "3", Email: "rust@example.com", EmailVerified: true})
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"email":"taken"}` {
+		t.Errorf("TestOAuthNewUser got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if _, err := Identities.Get("fake", "3"); err != utils.ErrNotExists {
+		t.Errorf("TestOAuthNewUser identity was linked: %v", err)
+	}
+}
+
+func TestOAuthUsername(t *testing.T) {
+	cases := []struct {
+		identity ExternalIdentity
+		expected string
+	}{
+		{identity: ExternalIdentity{Username: "gopher"}, expected: "gopher"},
+		{identity: ExternalIdentity{Username: "Го фер!"}, expected: "Гофер"},
+		{identity: ExternalIdentity{Email: "_john.doe+games@example.com"}, expected: "john.doegames"},
+		{identity: ExternalIdentity{Username: "admin"}, expected: oauthFallbackUsername},
+		{identity: ExternalIdentity{Username: "gоpher"}, expected: oauthFallbackUsername},
+		{identity: ExternalIdentity{}, expected: oauthFallbackUsername},
+		{identity: ExternalIdentity{Username: strings.Repeat("a", 40)}, expected: strings.Repeat("a", 27)},
+	}
+
+	for i, c := range cases {
+		if got, err := oauthUsername(&c.identity, 0); err != nil || got != c.expected {
+			t.Errorf("[%d] TestOAuthUsername got unexpected username: %s, %v, expected: %s", i, got, err, c.expected)
+		}
+	}
+
+	got, err := oauthUsername(&ExternalIdentity{Username: strings.Repeat("a", 40)}, 3)
+	if err != nil || !regexp.MustCompile(`^a{27}_[0-9]{4}$`).MatchString(got) {
+		t.Errorf("TestOAuthUsername got unexpected username with suffix: %s, %v", got, err)
+	}
+}
+
+func TestOAuthLoginChecks(t *testing.T) {
+	provider := initOAuthTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	Users.(*usersTest).users[2] = UserModel{ID: 2, Username: "banned", Password: &pass}
+	Identities.(*identitiesTest).identities = []*IdentityModel{
+		{Provider: "fake", Subject: "golang", UserID: 1},
+		{Provider: "fake", Subject: "banned", UserID: 2},
+	}
+	TwoFactors.(*twoFactorsTest).settings[1] = TwoFactorModel{UserID: 1, Enabled: true}
+
+	w := oauthLogin(t, provider, ExternalIdentity{Subject: "golang"})
+	challenge := &jmodels.TwoFactorChallenge{}
+	if err := json.Unmarshal(w.Body.Bytes(), challenge); err != nil || w.Code != http.StatusAccepted ||
+		challenge.Challenge == "" || responseCookie(w, "JSESSIONID") != nil {
+		t.Errorf("TestOAuthLoginChecks 2fa was not required: %d, %s", w.Code, w.Body.String())
+	}
+
+	w = oauthLogin(t, provider, ExternalIdentity{Subject: "banned"})
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"username":"deactivated"}` {
+		t.Errorf("TestOAuthLoginChecks got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	provider := initOAuthTests()
+	pass := "golang4ever"
+	users := Users.(*usersTest).users
+	users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	users[2] = UserModel{ID: 2, Username: "rust", Password: &pass, Active: true}
+	Identities.(*identitiesTest).identities = []*IdentityModel{{Provider: "fake", Subject: "rust", UserID: 2}}
+
+	link := func(subject string) *httptest.ResponseRecorder {
+		authURL, cookie := oauthStart(t, 1, "/users/me/identities/fake", ``)
+		code, state := provider.Authorize(authURL, ExternalIdentity{Subject: subject, Email: "golang@example.com"})
+		return oauthRequest(0, "POST", "/oauth/fake/callback", `{"code":"`+code+`","state":"`+state+`"}`, cookie)
+	}
+
+	// аккаунт провайдера уже у другого юзера
+	if w := link("rust"); w.Code != http.StatusBadRequest || w.Body.String() != `{"provider":"taken"}` {
+		t.Errorf("TestLinkIdentity got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	w := link("golang")
+	if w.Code != http.StatusOK || responseCookie(w, "JSESSIONID") != nil {
+		t.Fatalf("TestLinkIdentity got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if linked, err := Identities.Get("fake", "golang"); err != nil || linked.UserID != 1 {
+		t.Errorf("TestLinkIdentity identity was not linked: %+v, %v", linked, err)
+	}
+	if events := auditEventsOf(1); len(events) != 1 || events[0] != AuditIdentityLink {
+		t.Errorf("TestLinkIdentity got unexpected events: %v", events)
+	}
+
+	// второй аккаунт того же провайдера не привязать
+	if w = link("golang2"); w.Code != http.StatusBadRequest || w.Body.String() != `{"provider":"taken"}` {
+		t.Errorf("TestLinkIdentity got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	w = oauthRequest(1, "GET", "/users/me/identities", ``)
+	identities := make([]*jmodels.Identity, 0)
+	if err := json.Unmarshal(w.Body.Bytes(), &identities); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("TestLinkIdentity got unexpected list: %d, %s", w.Code, w.Body.String())
+	}
+	if len(identities) != 1 || identities[0].Provider != "fake" || identities[0].Email != "golang@example.com" {
+		t.Errorf("TestLinkIdentity got unexpected identities: %s", w.Body.String())
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	initOAuthTests()
+	pass := "golang4ever"
+	users := Users.(*usersTest).users
+	users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	Identities.(*identitiesTest).identities = []*IdentityModel{{Provider: "fake", Subject: "golang", UserID: 1}}
+
+	// без подтверждённого email войти было бы нечем
+	w := oauthRequest(1, "DELETE", "/users/me/identities/fake", ``)
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"provider":"last_login_method"}` {
+		t.Errorf("TestUnlinkIdentity got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true,
+		Email: sql.NullString{String: "golang@example.com", Valid: true}, EmailVerified: true}
+	if w = oauthRequest(1, "DELETE", "/users/me/identities/fake", ``); w.Code != http.StatusOK {
+		t.Errorf("TestUnlinkIdentity got unexpected code: %d, %s", w.Code, w.Body.String())
+	}
+	if events := auditEventsOf(1); len(events) != 1 || events[0] != AuditIdentityUnlink {
+		t.Errorf("TestUnlinkIdentity got unexpected events: %v", events)
+	}
+
+	if w = oauthRequest(1, "DELETE", "/users/me/identities/fake", ``); w.Code != http.StatusNotFound {
+		t.Errorf("TestUnlinkIdentity got unexpected code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+
+	Identities.(*identitiesTest).SetNextFail(utils.ErrInternal)
+	if w = oauthRequest(1, "GET", "/users/me/identities", ``); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestUnlinkIdentity got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestOIDCProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm() //nolint: errcheck
+		if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("code_verifier") != "verifier" ||
+			r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("redirect_uri") != "http://localhost:3000/oauth/test" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`)) //nolint: errcheck
+			return
+		}
+		w.Write([]byte(`{"access_token":"provider-token","token_type":"Bearer"}`)) //nolint: errcheck
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer provider-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"sub":12345,"email":"gopher@example.com","email_verified":"true",` + //nolint: errcheck
+			`"preferred_username":"gopher"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := OAuthProviderConfig{
+		AuthURL:      "https://provider.test/authorize?prompt=select_account",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/userinfo",
+		ClientID:     "warscript",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/oauth/test",
+	}
+	p, err := NewOIDCProvider(config)
+	if err != nil {
+		t.Fatalf("TestOIDCProvider got unexpected error: %v", err)
+	}
+
+	authURL, err := url.Parse(p.AuthURL("state", pkceChallenge("verifier")))
+	if err != nil {
+		t.Fatalf("TestOIDCProvider got invalid auth url: %v", err)
+	}
+	q := authURL.Query()
+	if q.Get("prompt") != "select_account" || q.Get("state") != "state" || q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") != "iMnq5o6zALKXGivsnlom_0F5_WYda32GHkxlV7mq7hQ" ||
+		q.Get("scope") != "openid email profile" || q.Get("client_id") != "warscript" {
+		t.Errorf("TestOIDCProvider got unexpected auth url: %s", authURL)
+	}
+
+	identity, err := p.Exchange("good-code", "verifier")
+	if err != nil {
+		t.Fatalf("TestOIDCProvider got unexpected error: %v", err)
+	}
+	expected := ExternalIdentity{Subject: "12345", Email: "gopher@example.com", EmailVerified: true, Username: "gopher"}
+	if *identity != expected {
+		t.Errorf("TestOIDCProvider got unexpected identity: %+v", identity)
+	}
+
+	if _, err = p.Exchange("bad-code", "verifier"); err == nil || err.Error() != "token request failed with status 400: oauth_rejected" {
+		t.Errorf("TestOIDCProvider got unexpected error: %v", err)
+	}
+
+	config.ClientSecret = ""
+	if _, err = NewOIDCProvider(config); err == nil || err.Error() != "client_secret is not configured" {
+		t.Errorf("TestOIDCProvider got unexpected config error: %v", err)
+	}
+}
+
+func TestOAuthStateModel(t *testing.T) {
+	rediCli = newTestRedis()
+	OAuthStates = &OAuthStateConn{}
+
+	state, err := OAuthStates.Create(&OAuthState{Provider: "fake", Verifier: "verifier", UserID: 1}, time.Minute)
+	if err != nil || len(state) < 40 {
+		t.Fatalf("TestOAuthStateModel got unexpected result: %s, %v", state, err)
+	}
+
+	s, err := OAuthStates.Consume(state)
+	if err != nil || s.Provider != "fake" || s.Verifier != "verifier" || s.UserID != 1 {
+		t.Errorf("TestOAuthStateModel got unexpected state: %+v, %v", s, err)
+	}
+
+	if _, err = OAuthStates.Consume(state); err != ErrTokenInvalid {
+		t.Errorf("TestOAuthStateModel got unexpected error: %v, expected: %v", err, ErrTokenInvalid)
+	}
+}
+
+func TestIdentityCreateModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	created := time.Now()
+	mock.ExpectQuery(`INSERT INTO user_identities .* ON CONFLICT DO NOTHING RETURNING created_at`).
+		WithArgs("fake", "42", 1, "gopher@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(created))
+	mock.ExpectQuery(`INSERT INTO user_identities`).
+		WithArgs("fake", "42", 2, "").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+
+	pqConn = db
+	Identities = &IdentityConn{}
+
+	i := &IdentityModel{Provider: "fake", Subject: "42", UserID: 1, Email: "gopher@example.com"}
+	if err = Identities.Create(i); err != nil || !i.CreatedAt.Equal(created) {
+		t.Errorf("TestIdentityCreateModel got unexpected result: %+v, %v", i, err)
+	}
+
+	if err = Identities.Create(&IdentityModel{Provider: "fake", Subject: "42", UserID: 2}); err != utils.ErrTaken {
+		t.Errorf("TestIdentityCreateModel got unexpected error: %v, expected: %v", err, utils.ErrTaken)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestIdentityCreateModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Алгоритмы хеширования паролей из конфига
+const (
+	HashAlgorithmBcrypt   = "bcrypt"
+	HashAlgorithmArgon2id = "argon2id"
+)
+
+// ErrUnknownHashAlgorithm в конфиге задан неизвестный алгоритм
+var ErrUnknownHashAlgorithm = errors.New("unknown password hash algorithm")
+
+// PasswordHasher один алгоритм хеширования. Формат хеша определяется
+// по префиксу, так что в колонке password могут лежать хеши разных алгоритмов
+type PasswordHasher interface {
+	// Hash хеширует пароль с текущими параметрами
+	Hash(password string) ([]byte, error)
+	// Verify сверяет пароль с хешем этого алгоритма
+	Verify(hash []byte, password string) bool
+	// Recognizes сделан ли хеш этим алгоритмом
+	Recognizes(hash []byte) bool
+	// Outdated сделан ли хеш с параметрами слабее текущих
+	Outdated(hash []byte) bool
+}
+
+// BcryptHasher bcrypt, хеш вида $2a$cost$...
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash хеширует пароль
+func (h *BcryptHasher) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+}
+
+// Verify сверяет пароль с хешем
+func (h *BcryptHasher) Verify(hash []byte, password string) bool {
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// Recognizes bcrypt хеш
+func (h *BcryptHasher) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) ||
+		bytes.HasPrefix(hash, []byte("$2b$")) ||
+		bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+// Outdated cost хеша меньше текущего
+func (h *BcryptHasher) Outdated(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost < h.Cost
+}
+
+// argon2idPrefix префикс хеша в формате PHC:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+const argon2idPrefix = "$argon2id$"
+
+// Argon2Hasher argon2id, Memory в KiB
+type Argon2Hasher struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	KeyLen  uint32
+	SaltLen int
+}
+
+// Hash хеширует пароль со случайной солью
+func (h *Argon2Hasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, errors.Wrap(err, "can not generate salt")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	b64 := base64.RawStdEncoding
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.Memory, h.Time, h.Threads, b64.EncodeToString(salt), b64.EncodeToString(key))), nil
+}
+
+// argon2Params параметры, с которыми сделан хеш
+type argon2Params struct {
+	version, time, memory uint32
+	threads               uint8
+	salt, key             []byte
+}
+
+func parseArgon2Hash(hash []byte) (*argon2Params, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, errors.New("invalid argon2id hash format")
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id params")
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id salt")
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, errors.Wrap(err, "invalid argon2id key")
+	}
+
+	return p, nil
+}
+
+// Verify пересчитывает ключ с параметрами из хеша
+func (h *Argon2Hasher) Verify(hash []byte, password string) bool {
+	p, err := parseArgon2Hash(hash)
+	if err != nil || p.version != argon2.Version {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(key, p.key) == 1
+}
+
+// Recognizes argon2id хеш
+func (h *Argon2Hasher) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+// Outdated хеш сделан с параметрами, отличными от текущих
+func (h *Argon2Hasher) Outdated(hash []byte) bool {
+	p, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	return p.version != argon2.Version || p.time != h.Time || p.memory != h.Memory ||
+		p.threads != h.Threads || uint32(len(p.key)) != h.KeyLen
+}
+
+// PasswordHashers хеширует текущим алгоритмом, а проверяет тем,
+// которым сделан хеш. Старые хеши обновляются при входе
+type PasswordHashers struct {
+	Current PasswordHasher
+	Known   []PasswordHasher
+}
+
+// passwordHashers хешеры, с которыми работает сервис.
+// С пустым конфигом NewPasswordHashers не ошибается
+var passwordHashers, _ = NewPasswordHashers(HashingConfig{})
+
+// NewPasswordHashers создаёт хешеры из конфига, незаданные значения берутся по умолчанию
+func NewPasswordHashers(c HashingConfig) (*PasswordHashers, error) {
+	bcryptHasher := &BcryptHasher{Cost: bcrypt.DefaultCost}
+	if c.BcryptCost > 0 {
+		if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+			return nil, errors.Errorf("bcrypt cost must be in [%d, %d]", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		bcryptHasher.Cost = c.BcryptCost
+	}
+
+	argon2Hasher := &Argon2Hasher{Time: 3, Memory: 64 * 1024, Threads: 2, KeyLen: 32, SaltLen: 16}
+	if c.Argon2Time > 0 {
+		argon2Hasher.Time = uint32(c.Argon2Time)
+	}
+	if c.Argon2Memory > 0 {
+		argon2Hasher.Memory = uint32(c.Argon2Memory)
+	}
+	if c.Argon2Threads > 0 {
+		argon2Hasher.Threads = uint8(c.Argon2Threads)
+	}
+
+	h := &PasswordHashers{Known: []PasswordHasher{bcryptHasher, argon2Hasher}}
+	switch c.Algorithm {
+	case "", HashAlgorithmBcrypt:
+		h.Current = bcryptHasher
+	case HashAlgorithmArgon2id:
+		h.Current = argon2Hasher
+	default:
+		return nil, errors.Wrap(ErrUnknownHashAlgorithm, c.Algorithm)
+	}
+
+	return h, nil
+}
+
+// Hash хеширует пароль текущим алгоритмом
+func (h *PasswordHashers) Hash(password string) ([]byte, error) {
+	return h.Current.Hash(password)
+}
+
+func (h *PasswordHashers) hasherFor(hash []byte) PasswordHasher {
+	for _, hasher := range h.Known {
+		if hasher.Recognizes(hash) {
+			return hasher
+		}
+	}
+
+	return nil
+}
+
+// Verify сверяет пароль с хешем любого известного алгоритма
+func (h *PasswordHashers) Verify(hash []byte, password string) bool {
+	hasher := h.hasherFor(hash)
+	if hasher == nil {
+		return false
+	}
+
+	return hasher.Verify(hash, password)
+}
+
+// NeedsRehash сделан ли хеш не текущим алгоритмом или с устаревшими параметрами
+func (h *PasswordHashers) NeedsRehash(hash []byte) bool {
+	hasher := h.hasherFor(hash)
+	return hasher != h.Current || hasher.Outdated(hash)
+}
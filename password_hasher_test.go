@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashers(t *testing.T) {
+	bcryptOld, err := NewPasswordHashers(HashingConfig{BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatalf("TestPasswordHashers got unexpected error: %v", err)
+	}
+	bcryptNew, _ := NewPasswordHashers(HashingConfig{BcryptCost: bcrypt.MinCost + 1})
+	argon, err := NewPasswordHashers(HashingConfig{Algorithm: HashAlgorithmArgon2id,
+		Argon2Time: 1, Argon2Memory: 1024, Argon2Threads: 1})
+	if err != nil {
+		t.Fatalf("TestPasswordHashers got unexpected error: %v", err)
+	}
+	argonNew, _ := NewPasswordHashers(HashingConfig{Algorithm: HashAlgorithmArgon2id,
+		Argon2Time: 2, Argon2Memory: 1024, Argon2Threads: 1})
+
+	bcryptHash, err := bcryptOld.Hash("deutschland1")
+	if err != nil {
+		t.Fatalf("TestPasswordHashers got unexpected error: %v", err)
+	}
+	argonHash, err := argon.Hash("deutschland1")
+	if err != nil {
+		t.Fatalf("TestPasswordHashers got unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		hashers *PasswordHashers
+		hash    []byte
+		rehash  bool
+	}{
+		{hashers: bcryptOld, hash: bcryptHash, rehash: false},
+		{hashers: bcryptNew, hash: bcryptHash, rehash: true},
+		{hashers: argon, hash: bcryptHash, rehash: true},
+		{hashers: argon, hash: argonHash, rehash: false},
+		{hashers: argonNew, hash: argonHash, rehash: true},
+		{hashers: bcryptOld, hash: argonHash, rehash: true},
+	}
+
+	for i, c := range cases {
+		if !c.hashers.Verify(c.hash, "deutschland1") {
+			t.Errorf("[%d] TestPasswordHashers can not verify correct password", i)
+		}
+		if c.hashers.Verify(c.hash, "deutschland2") {
+			t.Errorf("[%d] TestPasswordHashers verified wrong password", i)
+		}
+		if rehash := c.hashers.NeedsRehash(c.hash); rehash != c.rehash {
+			t.Errorf("[%d] TestPasswordHashers NeedsRehash got: %v, expected: %v", i, rehash, c.rehash)
+		}
+	}
+
+	if argon.Verify([]byte("$argon2id$kek"), "deutschland1") || argon.Verify([]byte("plain"), "plain") {
+		t.Errorf("TestPasswordHashers verified malformed hash")
+	}
+}
+
+func TestNewPasswordHashersErr(t *testing.T) {
+	if _, err := NewPasswordHashers(HashingConfig{Algorithm: "md5"}); err == nil {
+		t.Errorf("TestNewPasswordHashersErr expected error for unknown algorithm")
+	}
+	if _, err := NewPasswordHashers(HashingConfig{BcryptCost: 100}); err == nil {
+		t.Errorf("TestNewPasswordHashersErr expected error for invalid bcrypt cost")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// bcryptMaxLength bcrypt молча обрезает всё, что длиннее 72 байт
+const bcryptMaxLength = 72
+
+// Ошибки политики паролей, отдаются клиенту как значение поля в utils.ValidationError
+var (
+	// ErrPasswordTooShort пароль короче MinLength символов
+	ErrPasswordTooShort = errors.New("too_short")
+	// ErrPasswordTooLong пароль длиннее MaxLength байт
+	ErrPasswordTooLong = errors.New("too_long")
+	// ErrPasswordWeak в пароле меньше MinClasses классов символов
+	ErrPasswordWeak = errors.New("weak")
+	// ErrPasswordSameAsUsername пароль совпадает с username
+	ErrPasswordSameAsUsername = errors.New("same_as_username")
+	// ErrPasswordCommon пароль есть в списке распространённых или утёкших
+	ErrPasswordCommon = errors.New("too_common")
+)
+
+// PasswordPolicy требования к новым паролям. На вход со старым паролем не влияет
+type PasswordPolicy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+
+	common map[string]struct{}
+}
+
+// passwordPolicy политика, с которой работает сервис
+var passwordPolicy = &PasswordPolicy{
+	MinLength:  8,
+	MaxLength:  bcryptMaxLength,
+	MinClasses: 2,
+}
+
+// NewPasswordPolicy создаёт политику из конфига, незаданные значения берутся
+// по умолчанию. Если задан CommonListPath, список паролей читается из файла
+func NewPasswordPolicy(c PasswordConfig) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{
+		MinLength:  passwordPolicy.MinLength,
+		MaxLength:  passwordPolicy.MaxLength,
+		MinClasses: passwordPolicy.MinClasses,
+	}
+
+	if c.MinLength > 0 {
+		p.MinLength = c.MinLength
+	}
+	if c.MaxLength > 0 && c.MaxLength < bcryptMaxLength {
+		p.MaxLength = c.MaxLength
+	}
+	if c.MinClasses > 0 {
+		p.MinClasses = c.MinClasses
+	}
+
+	if c.CommonListPath != "" {
+		common, err := loadCommonPasswords(c.CommonListPath)
+		if err != nil {
+			return nil, err
+		}
+		p.common = common
+	}
+
+	return p, nil
+}
+
+// loadCommonPasswords читает список паролей, по одному на строку.
+// Пустые строки и строки с # пропускаются, сравнение без учёта регистра
+func loadCommonPasswords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not open common passwords list")
+	}
+	defer f.Close()
+
+	common := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		common[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "can not read common passwords list")
+	}
+
+	return common, nil
+}
+
+// passwordClasses сколько классов символов (строчные, заглавные, цифры, остальное) в пароле
+func passwordClasses(password string) int {
+	var lower, upper, digit, other int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+
+	return lower + upper + digit + other
+}
+
+// Check проверяет пароль и возвращает ошибку первого нарушенного правила
+func (p *PasswordPolicy) Check(username, password string) error {
+	if len(password) > p.MaxLength {
+		return ErrPasswordTooLong
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return ErrPasswordTooShort
+	}
+	if passwordClasses(password) < p.MinClasses {
+		return ErrPasswordWeak
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return ErrPasswordSameAsUsername
+	}
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		return ErrPasswordCommon
+	}
+
+	return nil
+}
+
+// Validate то же, что Check, но в виде ошибки валидации поля field
+func (p *PasswordPolicy) Validate(field, username, password string) *utils.ValidationError {
+	if err := p.Check(username, password); err != nil {
+		return &utils.ValidationError{
+			field: err.Error(),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := &PasswordPolicy{
+		MinLength:  8,
+		MaxLength:  bcryptMaxLength,
+		MinClasses: 2,
+		common:     map[string]struct{}{"password1": {}},
+	}
+
+	cases := []struct {
+		username string
+		password string
+		expected error
+	}{
+		{username: "user", password: "deutschland1", expected: nil},
+		{username: "user", password: "дойчланд1", expected: nil},
+		{username: "user", password: "short1", expected: ErrPasswordTooShort},
+		{username: "user", password: "дойч1", expected: ErrPasswordTooShort},
+		{username: "user", password: strings.Repeat("a1", 37), expected: ErrPasswordTooLong},
+		{username: "user", password: "deutschland", expected: ErrPasswordWeak},
+		{username: "Golang4ever", password: "golang4EVER", expected: ErrPasswordSameAsUsername},
+		{username: "user", password: "PASSWORD1", expected: ErrPasswordCommon},
+	}
+
+	for i, c := range cases {
+		if err := p.Check(c.username, c.password); err != c.expected {
+			t.Errorf("[%d] TestPasswordPolicyCheck got unexpected error: %v, expected: %v", i, err, c.expected)
+		}
+	}
+
+	valErr := p.Validate("newPassword", "user", "short1")
+	if valErr == nil || (*valErr)["newPassword"] != "too_short" {
+		t.Errorf("TestPasswordPolicyCheck got unexpected validation error: %v", valErr)
+	}
+}
+
+func TestNewPasswordPolicy(t *testing.T) {
+	f, err := ioutil.TempFile("", "warscript-users-passwords")
+	if err != nil {
+		t.Fatalf("TestNewPasswordPolicy can not create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.WriteString("# top\nqwerty123\n\n  Dragon2019  \n"); err != nil {
+		t.Fatalf("TestNewPasswordPolicy can not write temp file: %v", err)
+	}
+	f.Close()
+
+	p, err := NewPasswordPolicy(PasswordConfig{MinLength: 6, MaxLength: 100, CommonListPath: f.Name()})
+	if err != nil {
+		t.Fatalf("TestNewPasswordPolicy got unexpected error: %v", err)
+	}
+	if p.MinLength != 6 || p.MaxLength != bcryptMaxLength || p.MinClasses != 2 {
+		t.Errorf("TestNewPasswordPolicy got unexpected policy: %+v", p)
+	}
+	if len(p.common) != 2 || p.Check("", "dragon2019") != ErrPasswordCommon {
+		t.Errorf("TestNewPasswordPolicy got unexpected common list: %v", p.common)
+	}
+
+	if _, err = NewPasswordPolicy(PasswordConfig{CommonListPath: "/not/exists.txt"}); err == nil {
+		t.Errorf("TestNewPasswordPolicy expected error for missing file")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// passwordResetTTL сколько действует ссылка сброса пароля
+const passwordResetTTL = 30 * time.Minute
+
+// requestPasswordResetImpl отправляет ссылку сброса на подтверждённый email юзера.
+// Что юзера нет или у него нет подтверждённого email, наружу не сообщается,
+// чтобы по ответу нельзя было узнать, кто зарегистрирован
+func requestPasswordResetImpl(form *jmodels.FormPasswordReset) error {
+	login := jmodels.NormalizeUsername(form.Login)
+	if login == "" {
+		return &utils.ValidationError{
+			"login": utils.ErrRequired.Error(),
+		}
+	}
+
+	user, err := getUserByLoginImpl(login)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return nil
+		}
+		return errors.Wrap(err, "get user error")
+	}
+
+	if !user.Active {
+		logger.Infof("password reset for user %d skipped: account is deactivated", user.ID)
+		return nil
+	}
+
+	if !user.Email.Valid || !user.EmailVerified {
+		logger.Infof("password reset for user %d skipped: no verified email", user.ID)
+		return nil
+	}
+
+	token, err := PasswordResets.Create(user.ID, passwordResetTTL)
+	if err != nil {
+		return errors.Wrap(err, "password reset token error")
+	}
+
+	if err = notifier.PasswordReset(user, publicLink("/password-reset", token)); err != nil {
+		return errors.Wrap(err, "password reset notify error")
+	}
+
+	return nil
+}
+
+// confirmPasswordResetImpl ставит новый пароль по токену и разлогинивает все устройства.
+// Токен тратится только после проверки пароля, чтобы слабый пароль не сжигал ссылку
+func confirmPasswordResetImpl(form *jmodels.FormPasswordResetConfirm, client *clientInfo) error {
+	userID, err := PasswordResets.Get(form.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return ErrTokenInvalid
+		}
+		return errors.Wrap(err, "get user error")
+	}
+
+	if valErr := passwordPolicy.Validate("password", user.Username, form.Password); valErr != nil {
+		return valErr
+	}
+
+	if _, err = PasswordResets.Consume(form.Token); err != nil {
+		return err
+	}
+
+	user.Password = &form.Password
+	if err = Users.Save(user); err != nil {
+		return errors.Wrap(err, "user save error")
+	}
+
+	auditUserImpl(AuditPasswordReset, user.ID, client, nil)
+
+	if err = Sessions.DeleteAllForUser(user.ID); err != nil {
+		return errors.Wrap(err, "sessions revoke error")
+	}
+	if err = PersonalTokens.DeleteAllForUser(user.ID); err != nil {
+		return errors.Wrap(err, "personal tokens revoke error")
+	}
+
+	// юзер доказал, что владеет аккаунтом, блокировка входа больше не нужна
+	if err = loginSucceededImpl(user.ID); err != nil {
+		logger.Warnf("can not reset login failures: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/go-redis/redis"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// createResetScript сохраняет новый токен юзера и удаляет предыдущий:
+// действует только последняя ссылка
+var createResetScript = redis.NewScript(`
+local prev = redis.call("GET", KEYS[2])
+if prev then
+	redis.call("DEL", prev)
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("SET", KEYS[2], KEYS[1], "PX", ARGV[2])
+return 1
+`)
+
+// consumeResetScript атомарно забирает токен, чтобы его нельзя было использовать дважды.
+// Юзер токена ARGV[1] известен заранее, чтобы ключ юзера можно было передать в KEYS
+var consumeResetScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return false
+end
+redis.call("DEL", KEYS[1])
+if redis.call("GET", KEYS[2]) == KEYS[1] then
+	redis.call("DEL", KEYS[2])
+end
+return 1
+`)
+
+// PasswordResetAccessObject DAO для одноразовых токенов сброса пароля.
+// В редисе лежит только хеш токена
+type PasswordResetAccessObject interface {
+	// Create выпускает токен для юзера, предыдущий перестаёт действовать
+	Create(userID int64, ttl time.Duration) (string, error)
+	// Get возвращает юзера токена, не тратя его
+	Get(token string) (int64, error)
+	// Consume тратит токен и возвращает его юзера
+	Consume(token string) (int64, error)
+}
+
+// PasswordResetConn implementation of PasswordResetAccessObject
+type PasswordResetConn struct{}
+
+// PasswordResets interface variable for models methods
+var PasswordResets PasswordResetAccessObject
+
+func init() {
+	PasswordResets = &PasswordResetConn{}
+}
+
+const passwordResetUserPrefix = "password_reset_user:"
+
+func passwordResetKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "password_reset:" + hex.EncodeToString(sum[:])
+}
+
+// Create токен случайный, его знает только получатель письма
+func (pr *PasswordResetConn) Create(userID int64, ttl time.Duration) (string, error) {
+	token := uuid.New().String()
+	id := strconv.FormatInt(userID, 10)
+
+	err := createResetScript.Run(rediCli,
+		[]string{passwordResetKey(token), passwordResetUserPrefix + id},
+		id, int64(ttl/time.Millisecond)).Err()
+	if err != nil {
+		return "", errors.Wrapf(utils.ErrInternal, "password reset set error: %s", err.Error())
+	}
+
+	return token, nil
+}
+
+// Get возвращает ErrTokenInvalid, если токена нет или он истёк
+func (pr *PasswordResetConn) Get(token string) (int64, error) {
+	id, err := rediCli.Get(passwordResetKey(token)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, ErrTokenInvalid
+		}
+		return 0, errors.Wrapf(utils.ErrInternal, "password reset get error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+// Consume возвращает ErrTokenInvalid, если токена нет, он истёк или уже потрачен
+func (pr *PasswordResetConn) Consume(token string) (int64, error) {
+	userID, err := pr.Get(token)
+	if err != nil {
+		return 0, err
+	}
+
+	id := strconv.FormatInt(userID, 10)
+	err = consumeResetScript.Run(rediCli,
+		[]string{passwordResetKey(token), passwordResetUserPrefix + id}, id).Err()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, ErrTokenInvalid
+		}
+		return 0, errors.Wrapf(utils.ErrInternal, "password reset consume error: %s", err.Error())
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+)
+
+func TestPasswordReset(t *testing.T) {
+	initTests()
+	mails := &mailerTest{}
+	mailer = mails
+
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true,
+		Email: sql.NullString{String: "golang@mail.ru", Valid: true}, EmailVerified: true}
+	Users.(*usersTest).users[2] = UserModel{ID: 2, Username: "kek", Password: &pass, Active: true,
+		Email: sql.NullString{String: "kek@mail.ru", Valid: true}}
+	Sessions.(*sessionsTest).owners = map[string]int64{"golang-session": 1}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":1}`)
+
+	cases := []*UserTestCase{
+		{ // Неизвестный юзер не отличить от известного
+			Case: testutils.Case{
+				Payload:      []byte(`{"login":"nobody"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/password-reset",
+				Function:     RequestPasswordReset,
+			},
+		},
+		{ // Email не подтверждён, письмо не уходит
+			Case: testutils.Case{
+				Payload:      []byte(`{"login":"kek"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/password-reset",
+				Function:     RequestPasswordReset,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"login":""}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"login":"required"}`,
+				Method:       "POST",
+				Pattern:      "/password-reset",
+				Function:     RequestPasswordReset,
+			},
+		},
+		{ // Запрос по email
+			Case: testutils.Case{
+				Payload:      []byte(`{"login":"Golang@mail.ru"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "POST",
+				Pattern:      "/password-reset",
+				Function:     RequestPasswordReset,
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"token":"kek","password":"deutschland1"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"token":"invalid"}`,
+				Method:       "POST",
+				Pattern:      "/password-reset/confirm",
+				Function:     ConfirmPasswordReset,
+			},
+		},
+	}
+	runTableAPITests(t, cases)
+
+	if len(mails.sent) != 1 || mails.sent[0].To != "golang@mail.ru" {
+		t.Fatalf("TestPasswordReset got unexpected mails: %+v", mails.sent)
+	}
+	token := sentToken(t, mails)
+
+	confirm := func(password string) int {
+		r := httptest.NewRequest("POST", "/password-reset/confirm",
+			bytes.NewReader([]byte(`{"token":"`+token+`","password":"`+password+`"}`)))
+		w := httptest.NewRecorder()
+		ConfirmPasswordReset(w, r)
+		return w.Code
+	}
+
+	// слабый пароль не сжигает токен
+	if code := confirm("golang"); code != http.StatusBadRequest {
+		t.Errorf("TestPasswordReset got unexpected code: %d, expected: %d", code, http.StatusBadRequest)
+	}
+	if code := confirm("deutschland1"); code != http.StatusOK {
+		t.Errorf("TestPasswordReset got unexpected code: %d, expected: %d", code, http.StatusOK)
+	}
+
+	user := Users.(*usersTest).users[1]
+	if *user.Password != "deutschland1" {
+		t.Errorf("TestPasswordReset password was not changed")
+	}
+	if _, err := Sessions.GetSession("golang-session"); err != ErrSessionNotExists {
+		t.Errorf("TestPasswordReset got unexpected error: %v, expected: %v", err, ErrSessionNotExists)
+	}
+	if events := auditEventsOf(1); len(events) != 1 || events[0] != AuditPasswordReset {
+		t.Errorf("TestPasswordReset got unexpected audit: %v", events)
+	}
+
+	// токен одноразовый
+	if code := confirm("deutschland2"); code != http.StatusBadRequest {
+		t.Errorf("TestPasswordReset got unexpected code on reuse: %d, expected: %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestPasswordResetModel(t *testing.T) {
+	rediCli = newTestRedis()
+	resets := &PasswordResetConn{}
+
+	first, err := resets.Create(1, time.Minute)
+	if err != nil {
+		t.Fatalf("TestPasswordResetModel got unexpected error: %v, expected: %v", err, nil)
+	}
+	second, err := resets.Create(1, time.Minute)
+	if err != nil {
+		t.Fatalf("TestPasswordResetModel got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	// новая ссылка отменяет старую
+	if _, err = resets.Get(first); err != ErrTokenInvalid {
+		t.Errorf("TestPasswordResetModel got unexpected error: %v, expected: %v", err, ErrTokenInvalid)
+	}
+
+	id, err := resets.Get(second)
+	if err != nil || id != 1 {
+		t.Errorf("TestPasswordResetModel got unexpected result: %d, %v, expected: %d", id, err, 1)
+	}
+
+	id, err = resets.Consume(second)
+	if err != nil || id != 1 {
+		t.Errorf("TestPasswordResetModel got unexpected result: %d, %v, expected: %d", id, err, 1)
+	}
+	if _, err = resets.Consume(second); err != ErrTokenInvalid {
+		t.Errorf("TestPasswordResetModel got unexpected error: %v, expected: %v", err, ErrTokenInvalid)
+	}
+	if n, _ := rediCli.Exists(passwordResetUserPrefix + "1").Result(); n != 0 {
+		t.Errorf("TestPasswordResetModel user key was not deleted")
+	}
+
+	// токен хранится только хешем
+	keys, _ := rediCli.Keys("*" + second + "*").Result()
+	if len(keys) != 0 {
+		t.Errorf("TestPasswordResetModel token is stored in plain text: %v", keys)
+	}
+}
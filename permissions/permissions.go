@@ -0,0 +1,87 @@
+// Package permissions права юзеров Warscript. Сервис юзеров хранит роли
+// и отвечает по grpc, кому что можно, остальные сервисы спрашивают его
+// через PermissionsClient или WithPermission
+package permissions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// Права, которые выдаются ролями
+const (
+	// ManageUsers админка юзеров: бан, сброс пароля, переименование
+	ManageUsers = "users.manage"
+	// ModerateGames модерация игр
+	ModerateGames = "games.moderate"
+	// ModerateBots модерация ботов
+	ModerateBots = "bots.moderate"
+)
+
+// Ключи grpc заголовков, в которых GetSessionInfo отдаёт роли и права юзера.
+// Значений по одному на роль или право. Роли и права приходят, только если
+// запрос шёл с контекстом из WithRoles
+const (
+	RolesHeader       = "warscript-roles"
+	PermissionsHeader = "warscript-permissions"
+	// ScopesHeader есть, только если вошли личным токеном: его scopes,
+	// права в PermissionsHeader тогда уже ограничены ими
+	ScopesHeader = "warscript-scopes"
+	// WantRolesHeader ключ метаданных запроса, которым просят роли и права
+	WantRolesHeader = "warscript-want-roles"
+)
+
+// WithRoles контекст для GetSessionInfo, в ответ на который придут
+// RolesHeader и PermissionsHeader. Без него роли в базе не ищутся
+func WithRoles(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, WantRolesHeader, "1")
+}
+
+// RolesRequested просили ли в запросе роли и права
+func RolesRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md.Get(WantRolesHeader)) > 0
+}
+
+// Scopes личного токена помимо прав. Без ScopeWrite токен годится только
+// для чтения, без обоих ни для чего, кроме перечисленных в нём прав
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// WithPermission пускает дальше, только если у юзера из сессии есть permission.
+// Ставится после middlewares.WithAuthentication
+//nolint: interfacer
+func WithPermission(next http.HandlerFunc, l *logrus.Logger, cli PermissionsClient,
+	permission string) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := utils.GetLogger(r, l, "WithPermission")
+		errWriter := utils.NewErrorResponseWriter(w, logger)
+
+		info, ok := r.Context().Value(middlewares.SessionInfoKey).(*models.SessionPayload)
+		if !ok || info == nil {
+			errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+			return
+		}
+
+		res, err := cli.CheckPermission(r.Context(), &PermissionCheck{ID: info.ID, Permission: permission})
+		if err != nil {
+			errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "check permission error"))
+			return
+		}
+		if !res.Allowed {
+			errWriter.WriteWarn(http.StatusForbidden, errors.Errorf("user %d has no permission %s", info.ID, permission))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
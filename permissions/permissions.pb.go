@@ -0,0 +1,335 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: permissions.proto
+
+package permissions
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type UserID struct {
+	ID                   int64    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserID) Reset()         { *m = UserID{} }
+func (m *UserID) String() string { return proto.CompactTextString(m) }
+func (*UserID) ProtoMessage()    {}
+func (*UserID) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46cca66312ac1c30, []int{0}
+}
+
+func (m *UserID) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserID.Unmarshal(m, b)
+}
+func (m *UserID) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserID.Marshal(b, m, deterministic)
+}
+func (m *UserID) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserID.Merge(m, src)
+}
+func (m *UserID) XXX_Size() int {
+	return xxx_messageInfo_UserID.Size(m)
+}
+func (m *UserID) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserID.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserID proto.InternalMessageInfo
+
+func (m *UserID) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+type PermissionCheck struct {
+	ID                   int64    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Permission           string   `protobuf:"bytes,2,opt,name=permission,proto3" json:"permission,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PermissionCheck) Reset()         { *m = PermissionCheck{} }
+func (m *PermissionCheck) String() string { return proto.CompactTextString(m) }
+func (*PermissionCheck) ProtoMessage()    {}
+func (*PermissionCheck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46cca66312ac1c30, []int{1}
+}
+
+func (m *PermissionCheck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PermissionCheck.Unmarshal(m, b)
+}
+func (m *PermissionCheck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PermissionCheck.Marshal(b, m, deterministic)
+}
+func (m *PermissionCheck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PermissionCheck.Merge(m, src)
+}
+func (m *PermissionCheck) XXX_Size() int {
+	return xxx_messageInfo_PermissionCheck.Size(m)
+}
+func (m *PermissionCheck) XXX_DiscardUnknown() {
+	xxx_messageInfo_PermissionCheck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PermissionCheck proto.InternalMessageInfo
+
+func (m *PermissionCheck) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *PermissionCheck) GetPermission() string {
+	if m != nil {
+		return m.Permission
+	}
+	return ""
+}
+
+type PermissionCheckResult struct {
+	Allowed              bool     `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PermissionCheckResult) Reset()         { *m = PermissionCheckResult{} }
+func (m *PermissionCheckResult) String() string { return proto.CompactTextString(m) }
+func (*PermissionCheckResult) ProtoMessage()    {}
+func (*PermissionCheckResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46cca66312ac1c30, []int{2}
+}
+
+func (m *PermissionCheckResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PermissionCheckResult.Unmarshal(m, b)
+}
+func (m *PermissionCheckResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PermissionCheckResult.Marshal(b, m, deterministic)
+}
+func (m *PermissionCheckResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PermissionCheckResult.Merge(m, src)
+}
+func (m *PermissionCheckResult) XXX_Size() int {
+	return xxx_messageInfo_PermissionCheckResult.Size(m)
+}
+func (m *PermissionCheckResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_PermissionCheckResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PermissionCheckResult proto.InternalMessageInfo
+
+func (m *PermissionCheckResult) GetAllowed() bool {
+	if m != nil {
+		return m.Allowed
+	}
+	return false
+}
+
+type UserPermissions struct {
+	ID                   int64    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Roles                []string `protobuf:"bytes,2,rep,name=roles,proto3" json:"roles,omitempty"`
+	Permissions          []string `protobuf:"bytes,3,rep,name=permissions,proto3" json:"permissions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UserPermissions) Reset()         { *m = UserPermissions{} }
+func (m *UserPermissions) String() string { return proto.CompactTextString(m) }
+func (*UserPermissions) ProtoMessage()    {}
+func (*UserPermissions) Descriptor() ([]byte, []int) {
+	return fileDescriptor_46cca66312ac1c30, []int{3}
+}
+
+func (m *UserPermissions) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserPermissions.Unmarshal(m, b)
+}
+func (m *UserPermissions) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserPermissions.Marshal(b, m, deterministic)
+}
+func (m *UserPermissions) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserPermissions.Merge(m, src)
+}
+func (m *UserPermissions) XXX_Size() int {
+	return xxx_messageInfo_UserPermissions.Size(m)
+}
+func (m *UserPermissions) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserPermissions.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserPermissions proto.InternalMessageInfo
+
+func (m *UserPermissions) GetID() int64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *UserPermissions) GetRoles() []string {
+	if m != nil {
+		return m.Roles
+	}
+	return nil
+}
+
+func (m *UserPermissions) GetPermissions() []string {
+	if m != nil {
+		return m.Permissions
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*UserID)(nil), "permissions.UserID")
+	proto.RegisterType((*PermissionCheck)(nil), "permissions.PermissionCheck")
+	proto.RegisterType((*PermissionCheckResult)(nil), "permissions.PermissionCheckResult")
+	proto.RegisterType((*UserPermissions)(nil), "permissions.UserPermissions")
+}
+
+func init() { proto.RegisterFile("permissions.proto", fileDescriptor_46cca66312ac1c30) }
+
+var fileDescriptor_46cca66312ac1c30 = []byte{
+	// 222 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x2c, 0x48, 0x2d, 0xca,
+	0xcd, 0x2c, 0x2e, 0xce, 0xcc, 0xcf, 0x2b, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x46,
+	0x12, 0x52, 0x92, 0xe0, 0x62, 0x0b, 0x2d, 0x4e, 0x2d, 0xf2, 0x74, 0x11, 0xe2, 0xe3, 0x62, 0xf2,
+	0x74, 0x91, 0x60, 0x54, 0x60, 0xd4, 0x60, 0x0e, 0x62, 0xf2, 0x74, 0x51, 0x72, 0xe4, 0xe2, 0x0f,
+	0x80, 0x2b, 0x74, 0xce, 0x48, 0x4d, 0xce, 0x46, 0x57, 0x22, 0x24, 0xc7, 0xc5, 0x85, 0x30, 0x4b,
+	0x82, 0x49, 0x81, 0x51, 0x83, 0x33, 0x08, 0x49, 0x44, 0xc9, 0x90, 0x4b, 0x14, 0xcd, 0x88, 0xa0,
+	0xd4, 0xe2, 0xd2, 0x9c, 0x12, 0x21, 0x09, 0x2e, 0xf6, 0xc4, 0x9c, 0x9c, 0xfc, 0xf2, 0xd4, 0x14,
+	0xb0, 0x69, 0x1c, 0x41, 0x30, 0xae, 0x52, 0x24, 0x17, 0x3f, 0xc8, 0x3d, 0x08, 0x6d, 0xc5, 0x18,
+	0xb6, 0x8a, 0x70, 0xb1, 0x16, 0xe5, 0xe7, 0xa4, 0x16, 0x4b, 0x30, 0x29, 0x30, 0x6b, 0x70, 0x06,
+	0x41, 0x38, 0x42, 0x0a, 0x5c, 0xc8, 0xfe, 0x92, 0x60, 0x06, 0xcb, 0x21, 0x0b, 0x19, 0xad, 0x66,
+	0xe4, 0xe2, 0x46, 0x36, 0x37, 0x98, 0x8b, 0x1f, 0xec, 0x26, 0x84, 0x98, 0x90, 0x8c, 0x1e, 0x72,
+	0x70, 0xa1, 0xb9, 0x5d, 0x4a, 0x09, 0x9f, 0x2c, 0xd4, 0x67, 0xee, 0x5c, 0x42, 0xee, 0xa9, 0x25,
+	0xe8, 0x5e, 0x10, 0x46, 0xd1, 0x09, 0x09, 0x70, 0x29, 0x19, 0x0c, 0x41, 0x24, 0x2d, 0x49, 0x6c,
+	0xe0, 0xc8, 0x32, 0x06, 0x0c, 0x00, 0x42, 0x76, 0x87, 0x4a, 0xc1, 0x01, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// PermissionsClient is the client API for Permissions service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PermissionsClient interface {
+	CheckPermission(ctx context.Context, in *PermissionCheck, opts ...grpc.CallOption) (*PermissionCheckResult, error)
+	GetUserPermissions(ctx context.Context, in *UserID, opts ...grpc.CallOption) (*UserPermissions, error)
+}
+
+type permissionsClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewPermissionsClient(cc *grpc.ClientConn) PermissionsClient {
+	return &permissionsClient{cc}
+}
+
+func (c *permissionsClient) CheckPermission(ctx context.Context, in *PermissionCheck, opts ...grpc.CallOption) (*PermissionCheckResult, error) {
+	out := new(PermissionCheckResult)
+	err := c.cc.Invoke(ctx, "/permissions.Permissions/CheckPermission", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *permissionsClient) GetUserPermissions(ctx context.Context, in *UserID, opts ...grpc.CallOption) (*UserPermissions, error) {
+	out := new(UserPermissions)
+	err := c.cc.Invoke(ctx, "/permissions.Permissions/GetUserPermissions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PermissionsServer is the server API for Permissions service.
+type PermissionsServer interface {
+	CheckPermission(context.Context, *PermissionCheck) (*PermissionCheckResult, error)
+	GetUserPermissions(context.Context, *UserID) (*UserPermissions, error)
+}
+
+func RegisterPermissionsServer(s *grpc.Server, srv PermissionsServer) {
+	s.RegisterService(&_Permissions_serviceDesc, srv)
+}
+
+func _Permissions_CheckPermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PermissionCheck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).CheckPermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/permissions.Permissions/CheckPermission",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).CheckPermission(ctx, req.(*PermissionCheck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Permissions_GetUserPermissions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PermissionsServer).GetUserPermissions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/permissions.Permissions/GetUserPermissions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PermissionsServer).GetUserPermissions(ctx, req.(*UserID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Permissions_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "permissions.Permissions",
+	HandlerType: (*PermissionsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckPermission",
+			Handler:    _Permissions_CheckPermission_Handler,
+		},
+		{
+			MethodName: "GetUserPermissions",
+			Handler:    _Permissions_GetUserPermissions_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "permissions.proto",
+}
@@ -0,0 +1,70 @@
+package permissions
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/HotCodeGroup/warscript-utils/logging"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// permissionsTest отвечает, что у юзера 1 есть ModerateGames
+type permissionsTest struct {
+	err error
+}
+
+func (p *permissionsTest) CheckPermission(ctx context.Context, in *PermissionCheck,
+	opts ...grpc.CallOption) (*PermissionCheckResult, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return &PermissionCheckResult{Allowed: in.ID == 1 && in.Permission == ModerateGames}, nil
+}
+
+func (p *permissionsTest) GetUserPermissions(ctx context.Context, in *UserID,
+	opts ...grpc.CallOption) (*UserPermissions, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestWithPermission(t *testing.T) {
+	logger, _ := logging.NewLogger(ioutil.Discard, "")
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	cases := []struct {
+		session    *models.SessionPayload
+		permission string
+		err        error
+		expected   int
+	}{
+		{session: &models.SessionPayload{ID: 1}, permission: ModerateGames, expected: http.StatusOK},
+		{session: &models.SessionPayload{ID: 1}, permission: ManageUsers, expected: http.StatusForbidden},
+		{session: &models.SessionPayload{ID: 2}, permission: ModerateGames, expected: http.StatusForbidden},
+		{session: nil, permission: ModerateGames, expected: http.StatusUnauthorized},
+		{session: &models.SessionPayload{ID: 1}, permission: ModerateGames,
+			err: errors.New("connection refused"), expected: http.StatusInternalServerError},
+	}
+
+	for i, c := range cases {
+		handler := WithPermission(ok, logger, &permissionsTest{err: c.err}, c.permission)
+
+		r := httptest.NewRequest("GET", "/games/1", nil)
+		if c.session != nil {
+			r = r.WithContext(context.WithValue(r.Context(), middlewares.SessionInfoKey, c.session))
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != c.expected {
+			t.Errorf("[%d] TestWithPermission got unexpected code: %d, expected: %d", i, w.Code, c.expected)
+		}
+	}
+}
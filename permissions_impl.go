@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// userPermissionsImpl роли юзера и права, которые они дают. У неактивного
+// юзера прав нет, но роли остаются, чтобы после разбана не выдавать их заново
+func userPermissionsImpl(userID int64) (*permissions.UserPermissions, error) {
+	user, err := Users.GetUserByID(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user error")
+	}
+
+	roles, err := Roles.List(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get roles error")
+	}
+
+	perms := make([]string, 0)
+	if user.Active {
+		perms, err = Roles.Permissions(userID)
+		if err != nil {
+			return nil, errors.Wrap(err, "get permissions error")
+		}
+	}
+
+	return &permissions.UserPermissions{
+		ID:          userID,
+		Roles:       roles,
+		Permissions: perms,
+	}, nil
+}
+
+// hasPermissionImpl есть ли у юзера право. У несуществующего юзера прав нет
+func hasPermissionImpl(userID int64, permission string) (bool, error) {
+	up, err := userPermissionsImpl(userID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, p := range up.Permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// initPermissionsTests юзер 1 админ, 2 модератор, 3 забаненный модератор, 4 без ролей
+func initPermissionsTests() {
+	initTests()
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "admin1", Active: true}
+	Users.(*usersTest).users[2] = UserModel{ID: 2, Username: "moder", Active: true}
+	Users.(*usersTest).users[3] = UserModel{ID: 3, Username: "banned", Active: false}
+	Users.(*usersTest).users[4] = UserModel{ID: 4, Username: "golang", Active: true}
+	Roles.(*rolesTest).roles[1] = []string{RoleAdmin}
+	Roles.(*rolesTest).roles[2] = []string{RoleModerator}
+	Roles.(*rolesTest).roles[3] = []string{RoleModerator}
+}
+
+func TestCheckPermission(t *testing.T) {
+	initPermissionsTests()
+	m := &PermissionsManager{}
+
+	cases := []struct {
+		userID     int64
+		permission string
+		allowed    bool
+	}{
+		{userID: 1, permission: permissions.ManageUsers, allowed: true},
+		{userID: 1, permission: permissions.ModerateBots, allowed: true},
+		{userID: 2, permission: permissions.ModerateGames, allowed: true},
+		{userID: 2, permission: permissions.ManageUsers, allowed: false},
+		{userID: 3, permission: permissions.ModerateGames, allowed: false}, // забанен
+		{userID: 4, permission: permissions.ModerateGames, allowed: false},
+		{userID: 5, permission: permissions.ModerateGames, allowed: false}, // юзера нет
+	}
+
+	for i, c := range cases {
+		res, err := m.CheckPermission(context.Background(),
+			&permissions.PermissionCheck{ID: c.userID, Permission: c.permission})
+		if err != nil {
+			t.Errorf("[%d] TestCheckPermission got unexpected error: %v", i, err)
+			continue
+		}
+		if res.Allowed != c.allowed {
+			t.Errorf("[%d] TestCheckPermission got: %v, expected: %v", i, res.Allowed, c.allowed)
+		}
+	}
+
+	Roles.(*rolesTest).SetNextFail(utils.ErrInternal)
+	if _, err := m.CheckPermission(context.Background(),
+		&permissions.PermissionCheck{ID: 1, Permission: permissions.ManageUsers}); err == nil {
+		t.Errorf("TestCheckPermission expected error on roles failure")
+	}
+}
+
+func TestGetUserPermissions(t *testing.T) {
+	initPermissionsTests()
+	m := &PermissionsManager{}
+
+	up, err := m.GetUserPermissions(context.Background(), &permissions.UserID{ID: 2})
+	if err != nil {
+		t.Fatalf("TestGetUserPermissions got unexpected error: %v", err)
+	}
+	expected := &permissions.UserPermissions{
+		ID:          2,
+		Roles:       []string{RoleModerator},
+		Permissions: []string{permissions.ModerateBots, permissions.ModerateGames},
+	}
+	if !reflect.DeepEqual(up, expected) {
+		t.Errorf("TestGetUserPermissions got: %v, expected: %v", up, expected)
+	}
+
+	// у забаненного роли видны, а прав нет
+	up, err = m.GetUserPermissions(context.Background(), &permissions.UserID{ID: 3})
+	if err != nil || len(up.Roles) != 1 || len(up.Permissions) != 0 {
+		t.Errorf("TestGetUserPermissions inactive user got: %v, %v", up, err)
+	}
+
+	if _, err = m.GetUserPermissions(context.Background(), &permissions.UserID{ID: 5}); err == nil {
+		t.Errorf("TestGetUserPermissions expected error for missing user")
+	}
+}
+
+func TestGetSessionInfoPermissionsHeader(t *testing.T) {
+	initPermissionsTests()
+	Sessions.(*sessionsTest).sessions["admin-session"] = []byte(`{"id":1}`)
+
+	lis := bufconn.Listen(1 << 16)
+	server := grpc.NewServer(grpc.UnaryInterceptor(ErrorsInterceptor))
+	models.RegisterAuthServer(server, &AuthManager{})
+	permissions.RegisterPermissionsServer(server, &PermissionsManager{})
+	go server.Serve(lis) //nolint: errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }))
+	if err != nil {
+		t.Fatalf("TestGetSessionInfoPermissionsHeader dial error: %v", err)
+	}
+	defer conn.Close()
+
+	// без просьбы в базу за ролями не ходим
+	Roles.(*rolesTest).SetNextFail(utils.ErrInternal)
+	var header metadata.MD
+	payload, err := models.NewAuthClient(conn).GetSessionInfo(context.Background(),
+		&models.SessionToken{Token: "admin-session"}, grpc.Header(&header))
+	if err != nil || payload.ID != 1 || len(header.Get(permissions.RolesHeader)) != 0 {
+		t.Fatalf("TestGetSessionInfoPermissionsHeader got unexpected result: %v, %v, %v", payload, header, err)
+	}
+	Roles.(*rolesTest).SetNextFail(nil)
+
+	payload, err = models.NewAuthClient(conn).GetSessionInfo(permissions.WithRoles(context.Background()),
+		&models.SessionToken{Token: "admin-session"}, grpc.Header(&header))
+	if err != nil || payload.ID != 1 {
+		t.Fatalf("TestGetSessionInfoPermissionsHeader got unexpected result: %v, %v", payload, err)
+	}
+	if roles := header.Get(permissions.RolesHeader); !reflect.DeepEqual(roles, []string{RoleAdmin}) {
+		t.Errorf("TestGetSessionInfoPermissionsHeader got roles: %v", roles)
+	}
+	if perms := header.Get(permissions.PermissionsHeader); len(perms) != 3 {
+		t.Errorf("TestGetSessionInfoPermissionsHeader got permissions: %v", perms)
+	}
+
+	res, err := permissions.NewPermissionsClient(conn).CheckPermission(context.Background(),
+		&permissions.PermissionCheck{ID: 2, Permission: permissions.ManageUsers})
+	if err != nil || res.Allowed {
+		t.Errorf("TestGetSessionInfoPermissionsHeader CheckPermission got: %v, %v", res, err)
+	}
+}
+
+func TestRolePermissionsModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT DISTINCT p.permission FROM role_permissions").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"permission"}).
+			AddRow(permissions.ModerateBots).AddRow(permissions.ModerateGames))
+
+	pqConn = db
+	Roles = &RoleConn{}
+
+	perms, err := Roles.Permissions(2)
+	expected := []string{permissions.ModerateBots, permissions.ModerateGames}
+	if err != nil || !reflect.DeepEqual(perms, expected) {
+		t.Errorf("TestRolePermissionsModel got: %v, %v, expected: %v", perms, err, expected)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestRolePermissionsModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// personalTokenPrefix с него начинаются личные токены: так их не спутать
+// с токеном сессии, и сканеры секретов находят их в коде ботов
+const personalTokenPrefix = "wst_"
+
+// personalTokenBytes сколько случайных байт в личном токене
+const personalTokenBytes = 32
+
+// Сколько дней живёт личный токен по умолчанию и максимум
+const (
+	personalTokenDefaultDays = 30
+	personalTokenMaxDays     = 365
+)
+
+// isPersonalToken похож ли токен на личный
+func isPersonalToken(token string) bool {
+	return strings.HasPrefix(token, personalTokenPrefix)
+}
+
+// hashPersonalToken токен случайный и длинный, так что хватает sha256 без соли
+func hashPersonalToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// hasScope есть ли scope среди scopes
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopeAllowsMethod можно ли с такими scopes сделать запрос method.
+// Запись включает чтение
+func scopeAllowsMethod(scopes []string, method string) bool {
+	if hasScope(scopes, permissions.ScopeWrite) {
+		return true
+	}
+
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return hasScope(scopes, permissions.ScopeRead)
+	}
+
+	return false
+}
+
+// createPersonalTokenImpl выпускает личный токен. В scopes, кроме чтения
+// и записи, можно положить только те права, что есть у юзера сейчас
+func createPersonalTokenImpl(info *models.SessionPayload, form *jmodels.FormPersonalToken,
+	client *clientInfo) (*jmodels.NewPersonalToken, error) {
+	if err := form.Validate(personalTokenMaxDays); err != nil {
+		return nil, err
+	}
+
+	perms, err := Roles.Permissions(info.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get permissions error")
+	}
+
+	scopes := make([]string, 0, len(form.Scopes))
+	for _, s := range form.Scopes {
+		if s != permissions.ScopeRead && s != permissions.ScopeWrite && !hasScope(perms, s) {
+			return nil, &utils.ValidationError{
+				"scopes": utils.ErrInvalid.Error(),
+			}
+		}
+		if !hasScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	days := form.ExpiresInDays
+	if days == 0 {
+		days = personalTokenDefaultDays
+	}
+
+	token, err := randomToken(personalTokenBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "personal token generate error")
+	}
+	token = personalTokenPrefix + token
+
+	t := &PersonalTokenModel{
+		UserID:    info.ID,
+		Name:      form.Name,
+		Hash:      hashPersonalToken(token),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Duration(days) * 24 * time.Hour),
+	}
+	if err = PersonalTokens.Create(t); err != nil {
+		return nil, errors.Wrap(err, "personal token create error")
+	}
+	auditUserImpl(AuditTokenCreate, info.ID, client, map[string]string{
+		"token": strconv.FormatInt(t.ID, 10),
+		"name":  t.Name,
+	})
+
+	return &jmodels.NewPersonalToken{
+		PersonalToken: *newPersonalTokenInfo(t),
+		Token:         token,
+	}, nil
+}
+
+func newPersonalTokenInfo(t *PersonalTokenModel) *jmodels.PersonalToken {
+	info := &jmodels.PersonalToken{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+	}
+	if t.LastUsedAt.Valid {
+		info.LastUsedAt = &t.LastUsedAt.Time
+	}
+
+	return info
+}
+
+// listPersonalTokensImpl токены юзера, включая истёкшие
+func listPersonalTokensImpl(userID int64) ([]*jmodels.PersonalToken, error) {
+	tokens, err := PersonalTokens.ListForUser(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "personal tokens get error")
+	}
+
+	res := make([]*jmodels.PersonalToken, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, newPersonalTokenInfo(t))
+	}
+
+	return res, nil
+}
+
+// revokePersonalTokenImpl отзывает токен юзера
+func revokePersonalTokenImpl(userID, id int64, client *clientInfo) error {
+	if err := PersonalTokens.Delete(userID, id); err != nil {
+		return err
+	}
+	auditUserImpl(AuditTokenRevoke, userID, client, map[string]string{
+		"token": strconv.FormatInt(id, 10),
+	})
+
+	return nil
+}
+
+// authPersonalTokenImpl проверяет личный токен. Неизвестный, истёкший
+// и токен выключенного юзера дают ErrSessionNotExists, как и сессия
+func authPersonalTokenImpl(token string) (*PersonalTokenModel, error) {
+	t, err := PersonalTokens.GetByHash(hashPersonalToken(token))
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return nil, ErrSessionNotExists
+		}
+		return nil, errors.Wrap(err, "personal token get error")
+	}
+
+	now := time.Now()
+	if !now.Before(t.ExpiresAt) {
+		return nil, ErrSessionNotExists
+	}
+
+	// токены, в отличие от сессий, при выключении не удаляются:
+	// после включения аккаунта они снова работают
+	user, err := Users.GetUserByID(t.UserID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return nil, ErrSessionNotExists
+		}
+		return nil, errors.Wrap(err, "get user error")
+	}
+	if !user.Active {
+		return nil, ErrSessionNotExists
+	}
+
+	if !t.LastUsedAt.Valid || now.Sub(t.LastUsedAt.Time) >= lastSeenUpdateInterval {
+		if err = PersonalTokens.Touch(t.ID, now); err != nil {
+			logger.Warnf("can not update personal token last use: %s", err)
+		}
+	}
+
+	return t, nil
+}
+
+// authenticateImpl проверяет токен сессии или личный токен.
+// Для сессии scopes nil: ей можно всё, что можно юзеру
+func authenticateImpl(token string) (*models.SessionPayload, []string, error) {
+	if !isPersonalToken(token) {
+		payload, err := getSessionInfoImpl(token)
+		return payload, nil, err
+	}
+
+	t, err := authPersonalTokenImpl(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &models.SessionPayload{ID: t.UserID}, t.Scopes, nil
+}
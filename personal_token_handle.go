@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/gorilla/mux"
+
+	"github.com/pkg/errors"
+)
+
+// CreatePersonalToken выпускает личный токен. Токен отдаётся только в этом ответе.
+// Выпустить токен можно только из сессии, иначе токен мог бы расширить сам себя
+func CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "CreatePersonalToken")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	form := &jmodels.FormPersonalToken{}
+	if err := utils.DecodeBodyJSON(r.Body, form); err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	token, err := createPersonalTokenImpl(info, form, newClientInfo(r))
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteApplicationJSON(w, http.StatusOK, token)
+}
+
+// GetPersonalTokens личные токены юзера
+func GetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "GetPersonalTokens")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	tokens, err := listPersonalTokensImpl(info.ID)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, tokens)
+}
+
+// RevokePersonalToken отзывает личный токен
+func RevokePersonalToken(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "RevokePersonalToken")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusNotFound, errors.Wrap(err, "wrong id"))
+		return
+	}
+
+	if err = revokePersonalTokenImpl(info.ID, id, newClientInfo(r)); err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			errWriter.WriteWarn(http.StatusNotFound, err)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
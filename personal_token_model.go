@@ -0,0 +1,151 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// PersonalTokenAccessObject DAO for personal_tokens
+type PersonalTokenAccessObject interface {
+	// Create сохраняет токен, ID и CreatedAt заполняются базой
+	Create(t *PersonalTokenModel) error
+	// GetByHash токен по хешу секрета, в том числе истёкший
+	GetByHash(hash []byte) (*PersonalTokenModel, error)
+	// ListForUser токены юзера, от новых к старым
+	ListForUser(userID int64) ([]*PersonalTokenModel, error)
+	// Delete отзывает токен юзера
+	Delete(userID, id int64) error
+	// DeleteAllForUser отзывает все токены юзера
+	DeleteAllForUser(userID int64) error
+	// Touch запоминает, когда токен последний раз использовали
+	Touch(id int64, at time.Time) error
+}
+
+// PersonalTokenConn implementation of PersonalTokenAccessObject
+type PersonalTokenConn struct{}
+
+// PersonalTokens interface variable for models methods
+var PersonalTokens PersonalTokenAccessObject
+
+func init() {
+	PersonalTokens = &PersonalTokenConn{}
+}
+
+// PersonalTokenModel личный токен юзера. Сам секрет не хранится, только его хеш
+type PersonalTokenModel struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Hash       []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt pq.NullTime
+}
+
+// Create возвращает utils.ErrTaken, если такой хеш уже есть
+func (pc *PersonalTokenConn) Create(t *PersonalTokenModel) error {
+	err := pqConn.QueryRow(`INSERT INTO personal_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING RETURNING id, created_at;`,
+		t.UserID, t.Name, t.Hash, pq.Array(t.Scopes), t.ExpiresAt).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return utils.ErrTaken
+		}
+
+		return errors.Wrapf(utils.ErrInternal, "personal token create error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func scanPersonalToken(row interface {
+	Scan(dest ...interface{}) error
+}) (*PersonalTokenModel, error) {
+	t := &PersonalTokenModel{}
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Hash, pq.Array(&t.Scopes),
+		&t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt)
+	return t, err
+}
+
+// GetByHash возвращает utils.ErrNotExists, если токена нет
+func (pc *PersonalTokenConn) GetByHash(hash []byte) (*PersonalTokenModel, error) {
+	t, err := scanPersonalToken(pqConn.QueryRow(`SELECT t.id, t.user_id, t.name, t.token_hash, t.scopes,
+		t.created_at, t.expires_at, t.last_used_at FROM personal_tokens t WHERE t.token_hash = $1;`, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrNotExists
+		}
+
+		return nil, errors.Wrapf(utils.ErrInternal, "personal token get error: %s", err.Error())
+	}
+
+	return t, nil
+}
+
+// ListForUser токены юзера, от новых к старым
+func (pc *PersonalTokenConn) ListForUser(userID int64) ([]*PersonalTokenModel, error) {
+	rows, err := pqConn.Query(`SELECT t.id, t.user_id, t.name, t.token_hash, t.scopes,
+		t.created_at, t.expires_at, t.last_used_at FROM personal_tokens t
+		WHERE t.user_id = $1 ORDER BY t.id DESC;`, userID)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "personal tokens get error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	tokens := make([]*PersonalTokenModel, 0)
+	for rows.Next() {
+		var t *PersonalTokenModel
+		if t, err = scanPersonalToken(rows); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "personal token scan error: %s", err.Error())
+		}
+		tokens = append(tokens, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "personal tokens read error: %s", err.Error())
+	}
+
+	return tokens, nil
+}
+
+// Delete возвращает utils.ErrNotExists, если у юзера нет такого токена
+func (pc *PersonalTokenConn) Delete(userID, id int64) error {
+	res, err := pqConn.Exec(`DELETE FROM personal_tokens WHERE user_id = $1 AND id = $2;`, userID, id)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "personal token delete error: %s", err.Error())
+	}
+
+	deleted, err := affectedOne(res)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return utils.ErrNotExists
+	}
+
+	return nil
+}
+
+// DeleteAllForUser отзывает все токены юзера, например после смены пароля
+func (pc *PersonalTokenConn) DeleteAllForUser(userID int64) error {
+	_, err := pqConn.Exec(`DELETE FROM personal_tokens WHERE user_id = $1;`, userID)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "personal tokens delete error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Touch обновляет last_used_at
+func (pc *PersonalTokenConn) Touch(id int64, at time.Time) error {
+	_, err := pqConn.Exec(`UPDATE personal_tokens SET last_used_at = $2 WHERE id = $1;`, id, at)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "personal token touch error: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-users/permissions"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/gorilla/mux"
+	"github.com/mailru/easyjson/opt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// personalTokenRequest запрос юзера userID, вошедшего сессией
+func personalTokenRequest(userID int64, method, target, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	asUser := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: userID})
+			h(w, r.WithContext(ctx))
+		}
+	}
+	router.HandleFunc("/tokens", asUser(GetPersonalTokens)).Methods("GET")
+	router.HandleFunc("/tokens", asUser(CreatePersonalToken)).Methods("POST")
+	router.HandleFunc("/tokens/{id:[0-9]+}", asUser(RevokePersonalToken)).Methods("DELETE")
+
+	r := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// issuePersonalToken выпускает токен юзеру в обход ручки
+func issuePersonalToken(t *testing.T, userID int64, scopes ...string) string {
+	token, err := createPersonalTokenImpl(&models.SessionPayload{ID: userID},
+		&jmodels.FormPersonalToken{Name: "bot", Scopes: scopes}, &clientInfo{})
+	if err != nil {
+		t.Fatalf("issuePersonalToken got unexpected error: %v", err)
+	}
+
+	return token.Token
+}
+
+func TestCreatePersonalToken(t *testing.T) {
+	initPermissionsTests()
+
+	w := personalTokenRequest(4, "POST", "/tokens", `{"name":" bot ","scopes":["read","read","write"]}`)
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("TestCreatePersonalToken got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	token := &jmodels.NewPersonalToken{}
+	if err := json.Unmarshal(w.Body.Bytes(), token); err != nil {
+		t.Fatalf("TestCreatePersonalToken got bad body: %v", err)
+	}
+	if !strings.HasPrefix(token.Token, personalTokenPrefix) || token.Name != "bot" ||
+		!reflect.DeepEqual(token.Scopes, []string{"read", "write"}) {
+		t.Errorf("TestCreatePersonalToken got unexpected token: %+v", token)
+	}
+	if days := token.ExpiresAt.Sub(time.Now()) / (24 * time.Hour); days != personalTokenDefaultDays-1 {
+		t.Errorf("TestCreatePersonalToken got unexpected expiry: %v", token.ExpiresAt)
+	}
+
+	stored := PersonalTokens.(*personalTokensTest).tokens
+	if len(stored) != 1 || !bytes.Equal(stored[0].Hash, hashPersonalToken(token.Token)) {
+		t.Errorf("TestCreatePersonalToken token was not stored hashed: %v", stored)
+	}
+	if events := auditEventsOf(4); len(events) != 1 || events[0] != AuditTokenCreate {
+		t.Errorf("TestCreatePersonalToken got unexpected events: %v", events)
+	}
+
+	// право в scopes только своё
+	if w = personalTokenRequest(1, "POST", "/tokens",
+		`{"name":"admin bot","scopes":["users.manage"],"expires_in_days":365}`); w.Code != http.StatusOK {
+		t.Errorf("TestCreatePersonalToken got unexpected code: %d, %s", w.Code, w.Body.String())
+	}
+
+	cases := []struct {
+		body     string
+		expected string
+	}{
+		{`{"name":"","scopes":["read"]}`, `{"name":"required"}`},
+		{`{"name":"bot","scopes":[]}`, `{"scopes":"required"}`},
+		{`{"name":"bot","scopes":["read"],"expires_in_days":366}`, `{"expires_in_days":"invalid"}`},
+		{`{"name":"bot","scopes":["admin"]}`, `{"scopes":"invalid"}`},
+		{`{"name":"bot","scopes":["users.manage"]}`, `{"scopes":"invalid"}`},
+	}
+	for i, c := range cases {
+		w = personalTokenRequest(4, "POST", "/tokens", c.body)
+		if w.Code != http.StatusBadRequest || w.Body.String() != c.expected {
+			t.Errorf("[%d] TestCreatePersonalToken got: %d, %s, expected: %s", i, w.Code, w.Body.String(), c.expected)
+		}
+	}
+
+	PersonalTokens.(*personalTokensTest).SetNextFail(utils.ErrInternal)
+	w = personalTokenRequest(4, "POST", "/tokens", `{"name":"bot","scopes":["read"]}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("TestCreatePersonalToken got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestListRevokePersonalTokens(t *testing.T) {
+	initPermissionsTests()
+	issuePersonalToken(t, 4, permissions.ScopeRead)
+	issuePersonalToken(t, 4, permissions.ScopeWrite)
+	issuePersonalToken(t, 1, permissions.ScopeRead)
+
+	w := personalTokenRequest(4, "GET", "/tokens", ``)
+	tokens := make([]*jmodels.PersonalToken, 0)
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("TestListRevokePersonalTokens got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if len(tokens) != 2 || tokens[0].ID != 2 || tokens[1].ID != 1 || tokens[0].LastUsedAt != nil {
+		t.Errorf("TestListRevokePersonalTokens got unexpected tokens: %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), `"token"`) {
+		t.Errorf("TestListRevokePersonalTokens list must not show secrets: %s", w.Body.String())
+	}
+
+	// чужой токен не отозвать
+	if w = personalTokenRequest(4, "DELETE", "/tokens/3", ``); w.Code != http.StatusNotFound {
+		t.Errorf("TestListRevokePersonalTokens got unexpected code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+	if w = personalTokenRequest(4, "DELETE", "/tokens/1", ``); w.Code != http.StatusOK {
+		t.Errorf("TestListRevokePersonalTokens got unexpected code: %d, %s", w.Code, w.Body.String())
+	}
+	if events := auditEventsOf(4); len(events) != 3 || events[2] != AuditTokenRevoke {
+		t.Errorf("TestListRevokePersonalTokens got unexpected events: %v", events)
+	}
+	if w = personalTokenRequest(4, "DELETE", "/tokens/1", ``); w.Code != http.StatusNotFound {
+		t.Errorf("TestListRevokePersonalTokens got unexpected code: %d, expected: %d", w.Code, http.StatusNotFound)
+	}
+
+	PersonalTokens.(*personalTokensTest).SetNextFail(utils.ErrInternal)
+	if w = personalTokenRequest(4, "GET", "/tokens", ``); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestListRevokePersonalTokens got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestPersonalTokenAuthentication(t *testing.T) {
+	initPermissionsTests()
+	readToken := issuePersonalToken(t, 4, permissions.ScopeRead)
+	writeToken := issuePersonalToken(t, 4, permissions.ScopeWrite)
+	adminToken := issuePersonalToken(t, 1, permissions.ManageUsers)
+	readAdminToken := issuePersonalToken(t, 1, permissions.ScopeRead)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strconv.FormatInt(SessionInfo(r).ID, 10))) //nolint: errcheck
+	}
+	handler := WithAuthentication(ok, &LocalAuthClient{})
+	guarded := WithAuthentication(WithPermission(ok, permissions.ManageUsers, &LocalPermissionsClient{}),
+		&LocalAuthClient{})
+	create := WithAuthentication(WithSessionOnly(CreatePersonalToken), &LocalAuthClient{})
+
+	cases := []struct {
+		handler  http.HandlerFunc
+		method   string
+		auth     string
+		expected int
+	}{
+		{handler, "GET", "Bearer " + readToken, http.StatusOK},
+		{handler, "GET", "bearer " + writeToken, http.StatusOK},
+		{handler, "POST", "Bearer " + writeToken, http.StatusOK},
+		{handler, "POST", "Bearer " + readToken, http.StatusForbidden},
+		{handler, "GET", "Bearer " + personalTokenPrefix + "unknown", http.StatusUnauthorized},
+		{handler, "GET", "Bearer 1234", http.StatusUnauthorized}, // токен сессии так не принимается
+		{handler, "GET", "Basic " + readToken, http.StatusUnauthorized},
+		{handler, "GET", readToken, http.StatusUnauthorized},
+		{guarded, "GET", "Bearer " + adminToken, http.StatusForbidden}, // нет read
+		{guarded, "GET", "Bearer " + readAdminToken, http.StatusForbidden},
+		{create, "POST", "Bearer " + writeToken, http.StatusForbidden},
+	}
+	for i, c := range cases {
+		r := httptest.NewRequest(c.method, "/", strings.NewReader(`{"name":"bot","scopes":["write"]}`))
+		r.Header.Set("Authorization", c.auth)
+		w := httptest.NewRecorder()
+		c.handler(w, r)
+		if w.Code != c.expected {
+			t.Errorf("[%d] TestPersonalTokenAuthentication got: %d, expected: %d", i, w.Code, c.expected)
+		}
+	}
+
+	// право и метод проверяются вместе
+	allToken := issuePersonalToken(t, 1, permissions.ScopeWrite, permissions.ManageUsers)
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+allToken)
+	w := httptest.NewRecorder()
+	guarded(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "1" {
+		t.Errorf("TestPersonalTokenAuthentication got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+
+	for _, tok := range PersonalTokens.(*personalTokensTest).tokens {
+		if tok.UserID == 4 && !tok.LastUsedAt.Valid {
+			t.Errorf("TestPersonalTokenAuthentication token %d last use was not recorded", tok.ID)
+		}
+	}
+
+	PersonalTokens.(*personalTokensTest).SetNextFail(utils.ErrInternal)
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+readToken)
+	w = httptest.NewRecorder()
+	handler(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("TestPersonalTokenAuthentication got unexpected code: %d, expected: %d",
+			w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestAuthPersonalToken(t *testing.T) {
+	initPermissionsTests()
+	token := issuePersonalToken(t, 4, permissions.ScopeRead)
+	c := &LocalAuthClient{}
+
+	payload, err := c.GetSessionInfo(context.Background(), &models.SessionToken{Token: token})
+	if err != nil || payload.ID != 4 {
+		t.Errorf("TestAuthPersonalToken got unexpected result: %v, %v", payload, err)
+	}
+
+	// токен выключенного юзера не работает, пока его не включат
+	users := Users.(*usersTest).users
+	users[4] = UserModel{ID: 4, Username: "golang", Active: false}
+	if _, err = c.GetSessionInfo(context.Background(), &models.SessionToken{Token: token}); err != ErrSessionNotExists {
+		t.Errorf("TestAuthPersonalToken got: %v, expected: %v", err, ErrSessionNotExists)
+	}
+	users[4] = UserModel{ID: 4, Username: "golang", Active: true}
+	if _, err = c.GetSessionInfo(context.Background(), &models.SessionToken{Token: token}); err != nil {
+		t.Errorf("TestAuthPersonalToken got unexpected error: %v", err)
+	}
+
+	PersonalTokens.(*personalTokensTest).tokens[0].ExpiresAt = time.Now().Add(-time.Second)
+	if _, err = c.GetSessionInfo(context.Background(), &models.SessionToken{Token: token}); err != ErrSessionNotExists {
+		t.Errorf("TestAuthPersonalToken got: %v, expected: %v", err, ErrSessionNotExists)
+	}
+}
+
+func TestGetSessionInfoPersonalTokenHeader(t *testing.T) {
+	initPermissionsTests()
+	token := issuePersonalToken(t, 1, permissions.ScopeRead, permissions.ModerateBots)
+
+	lis := bufconn.Listen(1 << 16)
+	server := grpc.NewServer(grpc.UnaryInterceptor(ErrorsInterceptor))
+	models.RegisterAuthServer(server, &AuthManager{})
+	go server.Serve(lis) //nolint: errcheck
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) { return lis.Dial() }))
+	if err != nil {
+		t.Fatalf("TestGetSessionInfoPersonalTokenHeader dial error: %v", err)
+	}
+	defer conn.Close()
+
+	var header metadata.MD
+	payload, err := models.NewAuthClient(conn).GetSessionInfo(permissions.WithRoles(context.Background()),
+		&models.SessionToken{Token: token}, grpc.Header(&header))
+	if err != nil || payload.ID != 1 {
+		t.Fatalf("TestGetSessionInfoPersonalTokenHeader got unexpected result: %v, %v", payload, err)
+	}
+	if perms := header.Get(permissions.PermissionsHeader); !reflect.DeepEqual(perms, []string{permissions.ModerateBots}) {
+		t.Errorf("TestGetSessionInfoPersonalTokenHeader got permissions: %v", perms)
+	}
+	scopes := header.Get(permissions.ScopesHeader)
+	if !reflect.DeepEqual(scopes, []string{permissions.ScopeRead, permissions.ModerateBots}) {
+		t.Errorf("TestGetSessionInfoPersonalTokenHeader got scopes: %v", scopes)
+	}
+}
+
+func TestPersonalTokenSessionOnlyRoutes(t *testing.T) {
+	initPermissionsTests()
+	token := issuePersonalToken(t, 4, permissions.ScopeRead, permissions.ScopeWrite)
+	Sessions.(*sessionsTest).sessions["users:4"] = []byte(`{}`)
+	router := newRouter()
+
+	routes := []struct {
+		method string
+		target string
+	}{
+		{"GET", "/v1/sessions"},
+		{"DELETE", "/v1/sessions"},
+		{"DELETE", "/v1/sessions/all"},
+		{"PUT", "/v1/users"},
+		{"DELETE", "/v1/users"},
+		{"POST", "/v1/users/2fa"},
+		{"POST", "/v1/users/2fa/confirm"},
+		{"DELETE", "/v1/users/2fa"},
+		{"POST", "/v1/users/me/identities/google"},
+		{"DELETE", "/v1/users/me/identities/google"},
+		{"POST", "/v1/users/me/vk-secret"},
+		{"DELETE", "/v1/users/me/vk-secret"},
+		{"POST", "/v1/tokens"},
+	}
+	for i, c := range routes {
+		r := httptest.NewRequest(c.method, c.target, strings.NewReader(`{}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "users:4"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("[%d] TestPersonalTokenSessionOnlyRoutes %s %s got: %d, expected: %d",
+				i, c.method, c.target, w.Code, http.StatusForbidden)
+		}
+	}
+	if _, ok := Sessions.(*sessionsTest).sessions["users:4"]; !ok {
+		t.Errorf("TestPersonalTokenSessionOnlyRoutes forged cookie key was deleted")
+	}
+
+	// остальное write токену доступно
+	r := httptest.NewRequest("GET", "/v1/tokens", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("TestPersonalTokenSessionOnlyRoutes got: %d, expected: %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestPersonalTokensRevokedWithPassword(t *testing.T) {
+	pass := "golang4ever"
+	admin := &models.SessionPayload{ID: 1}
+	cases := []struct {
+		name   string
+		revoke func() error
+	}{
+		{"password change", func() error {
+			return updateUserImpl(&models.SessionPayload{ID: 4}, "", &clientInfo{}, &jmodels.FormUserUpdate{
+				OldPassword: opt.OString(pass),
+				NewPassword: opt.OString("rust4ever-maybe"),
+			})
+		}},
+		{"password reset", func() error {
+			token, err := PasswordResets.Create(4, time.Hour)
+			if err != nil {
+				return err
+			}
+			return confirmPasswordResetImpl(&jmodels.FormPasswordResetConfirm{Token: token,
+				Password: "deutschland1"}, &clientInfo{})
+		}},
+		{"admin logout", func() error {
+			return forceLogoutImpl(admin, &clientInfo{}, 4)
+		}},
+		{"admin password reset", func() error {
+			_, err := forcePasswordResetImpl(admin, &clientInfo{}, 4)
+			return err
+		}},
+		{"admin ban", func() error {
+			return setUserActiveImpl(admin, &clientInfo{}, 4, false)
+		}},
+	}
+
+	for _, c := range cases {
+		initPermissionsTests()
+		Users.(*usersTest).users[4] = UserModel{ID: 4, Username: "golang", Password: &pass, Active: true}
+		issuePersonalToken(t, 4, "read")
+		other := issuePersonalToken(t, 2, "read")
+
+		if err := c.revoke(); err != nil {
+			t.Fatalf("TestPersonalTokensRevokedWithPassword %s got unexpected error: %v", c.name, err)
+		}
+
+		tokens := PersonalTokens.(*personalTokensTest).tokens
+		if len(tokens) != 1 || tokens[0].UserID != 2 {
+			t.Errorf("TestPersonalTokensRevokedWithPassword %s got unexpected tokens: %+v", c.name, tokens)
+		}
+		if _, _, err := authenticateImpl(other); err != nil {
+			t.Errorf("TestPersonalTokensRevokedWithPassword %s revoked another user token: %v", c.name, err)
+		}
+	}
+}
+
+func TestPersonalTokenModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	pqConn = db
+	PersonalTokens = &PersonalTokenConn{}
+	now := time.Now()
+
+	mock.ExpectQuery("INSERT INTO personal_tokens").
+		WithArgs(1, "bot", hashPersonalToken("token"), sqlmock.AnyArg(), now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+	tok := &PersonalTokenModel{UserID: 1, Name: "bot", Hash: hashPersonalToken("token"),
+		Scopes: []string{"read", "write"}, ExpiresAt: now}
+	if err = PersonalTokens.Create(tok); err != nil || tok.ID != 5 {
+		t.Errorf("TestPersonalTokenModel Create got unexpected result: %d, %v", tok.ID, err)
+	}
+
+	mock.ExpectQuery("SELECT").WithArgs(hashPersonalToken("token")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "token_hash", "scopes",
+			"created_at", "expires_at", "last_used_at"}).
+			AddRow(5, 1, "bot", hashPersonalToken("token"), "{read,write}", now, now, nil))
+	got, err := PersonalTokens.GetByHash(hashPersonalToken("token"))
+	if err != nil || got.ID != 5 || !reflect.DeepEqual(got.Scopes, []string{"read", "write"}) || got.LastUsedAt.Valid {
+		t.Errorf("TestPersonalTokenModel GetByHash got unexpected result: %+v, %v", got, err)
+	}
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err = PersonalTokens.GetByHash([]byte("kek")); err != utils.ErrNotExists {
+		t.Errorf("TestPersonalTokenModel GetByHash got: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	mock.ExpectExec("DELETE FROM personal_tokens").WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err = PersonalTokens.Delete(1, 5); err != utils.ErrNotExists {
+		t.Errorf("TestPersonalTokenModel Delete got: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	mock.ExpectExec("DELETE FROM personal_tokens").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	if err = PersonalTokens.DeleteAllForUser(1); err != nil {
+		t.Errorf("TestPersonalTokenModel DeleteAllForUser got unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestPersonalTokenModel there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+
+	"github.com/pkg/errors"
+)
+
+// По чему различаем клиентов при ограничении запросов
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+)
+
+// RateLimit не больше Requests запросов за Window от одного клиента на маршрут Route
+type RateLimit struct {
+	Route    string
+	Requests int64
+	Window   time.Duration
+	By       string
+}
+
+// defaultRateLimits лимиты маршрутов по умолчанию
+var defaultRateLimits = map[string]RateLimitConfig{
+	"register":               {Requests: 10, Window: 3600, By: RateLimitByIP},
+	"login":                  {Requests: 30, Window: 60, By: RateLimitByIP},
+	"username_check":         {Requests: 60, Window: 60, By: RateLimitByIP},
+	"password_reset":         {Requests: 5, Window: 3600, By: RateLimitByIP},
+	"password_reset_confirm": {Requests: 10, Window: 3600, By: RateLimitByIP},
+	"email_verify":           {Requests: 10, Window: 3600, By: RateLimitByIP},
+	"access_token":           {Requests: 60, Window: 60, By: RateLimitByIP},
+}
+
+// rateLimits лимиты, с которыми работает сервис, по названию маршрута
+var rateLimits = NewRateLimits(nil)
+
+// CheckRateLimits проверяет, что клиенты различаются по тому, что умеем проверять
+func CheckRateLimits(c map[string]RateLimitConfig) error {
+	for route, rc := range c {
+		if rc.By != "" && rc.By != RateLimitByIP && rc.By != RateLimitByUser {
+			return errors.Errorf("unknown rate limit mode %q for %s", rc.By, route)
+		}
+	}
+
+	return nil
+}
+
+// NewRateLimits создаёт лимиты из конфига. Конфиг маршрута целиком
+// заменяет значение по умолчанию, незаданные поля берутся из него
+func NewRateLimits(c map[string]RateLimitConfig) map[string]*RateLimit {
+	limits := make(map[string]*RateLimit, len(defaultRateLimits))
+	for route, def := range defaultRateLimits {
+		limits[route] = newRateLimit(route, def, c[route])
+	}
+	for route, rc := range c {
+		if _, ok := limits[route]; !ok {
+			limits[route] = newRateLimit(route, RateLimitConfig{Requests: 60, Window: 60, By: RateLimitByIP}, rc)
+		}
+	}
+
+	return limits
+}
+
+func newRateLimit(route string, def, c RateLimitConfig) *RateLimit {
+	if c.Requests > 0 {
+		def.Requests = c.Requests
+	}
+	if c.Window > 0 {
+		def.Window = c.Window
+	}
+	if c.By != "" {
+		def.By = c.By
+	}
+
+	return &RateLimit{
+		Route:    route,
+		Requests: int64(def.Requests),
+		Window:   time.Duration(def.Window) * time.Second,
+		By:       def.By,
+	}
+}
+
+// clientKey кем считать клиента. Юзер определяется только по проверенной
+// сессии или личному токену: иначе каждый выдуманный токен получал бы
+// свой счётчик. Без них клиент различается по адресу
+func (l *RateLimit) clientKey(r *http.Request) string {
+	if l.By == RateLimitByUser {
+		if info := SessionInfo(r); info != nil {
+			return "user:" + strconv.FormatInt(info.ID, 10)
+		}
+
+		if token := requestCredential(r); token != "" {
+			if info, _, err := authenticateImpl(token); err == nil {
+				return "user:" + strconv.FormatInt(info.ID, 10)
+			}
+		}
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+// requestCredential токен сессии из куки или личный токен из Authorization
+func requestCredential(r *http.Request) string {
+	if token, ok := bearerToken(r.Header.Get("Authorization")); ok {
+		return token
+	}
+
+	if cookie, err := r.Cookie("JSESSIONID"); err == nil {
+		return cookie.Value
+	}
+
+	return ""
+}
+
+// WithRateLimit ограничивает частоту запросов каждого клиента к next.
+// Если хранилище счётчиков недоступно, запрос пропускается:
+// лучше на время остаться без лимита, чем без регистрации и входа
+func WithRateLimit(next http.HandlerFunc, limit *RateLimit) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := utils.GetLogger(r, logger, "WithRateLimit")
+		errWriter := utils.NewErrorResponseWriter(w, logger)
+
+		count, resetAfter, err := RateLimits.Hit(limit.Route+":"+limit.clientKey(r), limit.Window)
+		if err != nil {
+			logger.Errorf("rate limit check error: %s", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(limit.Requests, 10))
+		if count > limit.Requests {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", strconv.FormatInt(int64((resetAfter+time.Second-1)/time.Second), 10))
+			errWriter.WriteWarn(http.StatusTooManyRequests, errors.New("too many requests"))
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(limit.Requests-count, 10))
+		next.ServeHTTP(w, r)
+	})
+}
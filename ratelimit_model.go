@@ -0,0 +1,56 @@
+package main
+
+import (
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/go-redis/redis"
+
+	"github.com/pkg/errors"
+)
+
+// rateLimitScript считает запросы в окне фиксированной длины.
+// Возвращает число запросов в текущем окне и сколько мс до его конца
+var rateLimitScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// RateLimitAccessObject DAO для счётчиков запросов. Счётчики лежат в редисе,
+// поэтому лимит общий для всех запущенных контейнеров
+type RateLimitAccessObject interface {
+	// Hit учитывает запрос по key и возвращает число запросов в окне
+	// вместе с временем до конца окна
+	Hit(key string, window time.Duration) (int64, time.Duration, error)
+}
+
+// RateLimitConn implementation of RateLimitAccessObject
+type RateLimitConn struct{}
+
+// RateLimits interface variable for models methods
+var RateLimits RateLimitAccessObject
+
+func init() {
+	RateLimits = &RateLimitConn{}
+}
+
+// Hit окно начинается с первого запроса и живёт window
+func (rl *RateLimitConn) Hit(key string, window time.Duration) (int64, time.Duration, error) {
+	res, err := rateLimitScript.Run(rediCli, []string{"rate_limit:" + key},
+		int64(window/time.Millisecond)).Result()
+	if err != nil {
+		return 0, 0, errors.Wrapf(utils.ErrInternal, "rate limit hit error: %s", err.Error())
+	}
+
+	vals, ok := res.([]interface{})
+	if !ok || len(vals) != 2 {
+		return 0, 0, errors.Wrapf(utils.ErrInternal, "rate limit unexpected reply: %v", res)
+	}
+	count, _ := vals[0].(int64)
+	ttl, _ := vals[1].(int64)
+
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+
+	"github.com/pkg/errors"
+)
+
+func TestNewRateLimits(t *testing.T) {
+	limits := NewRateLimits(map[string]RateLimitConfig{
+		"login":  {Requests: 5},
+		"custom": {By: RateLimitByUser},
+	})
+
+	login := limits["login"]
+	if login.Requests != 5 || login.Window != time.Minute || login.By != RateLimitByIP {
+		t.Errorf("TestNewRateLimits got unexpected login limit: %+v", login)
+	}
+	if register := limits["register"]; register.Requests != 10 || register.Window != time.Hour {
+		t.Errorf("TestNewRateLimits got unexpected register limit: %+v", register)
+	}
+	if custom := limits["custom"]; custom.Requests != 60 || custom.By != RateLimitByUser {
+		t.Errorf("TestNewRateLimits got unexpected custom limit: %+v", custom)
+	}
+}
+
+func TestRateLimitClientKey(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[7] = UserModel{ID: 7, Username: "golang", Password: &pass, Active: true}
+	Sessions.(*sessionsTest).sessions["golang-session"] = []byte(`{"id":7}`)
+
+	r := httptest.NewRequest("POST", "/users/used", nil)
+	r.RemoteAddr = "10.0.0.1:4242"
+
+	byUser := &RateLimit{By: RateLimitByUser}
+	byIP := &RateLimit{By: RateLimitByIP}
+
+	// без сессии различаем по адресу
+	if key := byUser.clientKey(r); key != "ip:10.0.0.1" {
+		t.Errorf("TestRateLimitClientKey got: %s, expected: ip:10.0.0.1", key)
+	}
+
+	// выдуманный токен не даёт нового счётчика
+	r.Header.Set("Authorization", "Bearer "+personalTokenPrefix+"forged")
+	r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "forged"})
+	if key := byUser.clientKey(r); key != "ip:10.0.0.1" {
+		t.Errorf("TestRateLimitClientKey got: %s, expected: ip:10.0.0.1", key)
+	}
+
+	r = httptest.NewRequest("POST", "/users/used", nil)
+	r.RemoteAddr = "10.0.0.1:4242"
+	r.AddCookie(&http.Cookie{Name: "JSESSIONID", Value: "golang-session"})
+	if key := byUser.clientKey(r); key != "user:7" {
+		t.Errorf("TestRateLimitClientKey got: %s, expected: user:7", key)
+	}
+	if key := byIP.clientKey(r); key != "ip:10.0.0.1" {
+		t.Errorf("TestRateLimitClientKey got: %s, expected: ip:10.0.0.1", key)
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 8}))
+	if key := byUser.clientKey(r); key != "user:8" {
+		t.Errorf("TestRateLimitClientKey got: %s, expected: user:8", key)
+	}
+}
+
+func TestCheckRateLimits(t *testing.T) {
+	if err := CheckRateLimits(map[string]RateLimitConfig{"login": {By: RateLimitByUser}}); err != nil {
+		t.Errorf("TestCheckRateLimits got unexpected error: %v", err)
+	}
+	if err := CheckRateLimits(map[string]RateLimitConfig{"login": {By: "api_key"}}); err == nil {
+		t.Errorf("TestCheckRateLimits expected error for api_key")
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	limits := &rateLimitsTest{hits: make(map[string]int64)}
+	RateLimits = limits
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := WithRateLimit(ok, &RateLimit{Route: "username_check", Requests: 2, Window: time.Minute, By: RateLimitByIP})
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/users/used", nil)
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request("10.0.0.1"); w.Code != http.StatusOK {
+			t.Errorf("[%d] TestWithRateLimit got unexpected code: %d", i, w.Code)
+		}
+	}
+
+	w := request("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("TestWithRateLimit got unexpected response: %d, Retry-After: %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// другой клиент не страдает из-за первого
+	if w = request("10.0.0.2"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("TestWithRateLimit got unexpected response for another client: %d", w.Code)
+	}
+
+	// хранилище лежит - пропускаем
+	limits.SetNextFail(errors.New("upala basa"))
+	if w = request("10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("TestWithRateLimit got unexpected code on storage error: %d", w.Code)
+	}
+}
+
+func TestRateLimitModel(t *testing.T) {
+	rediCli = newTestRedis()
+	RateLimits = &RateLimitConn{}
+
+	for i := int64(1); i <= 3; i++ {
+		count, ttl, err := RateLimits.Hit("login:ip:10.0.0.1", time.Minute)
+		if err != nil || count != i || ttl <= 0 || ttl > time.Minute {
+			t.Errorf("TestRateLimitModel Hit got: %d, %v, %v, expected: %d", count, ttl, err, i)
+		}
+	}
+
+	if count, _, _ := RateLimits.Hit("login:ip:10.0.0.2", time.Minute); count != 1 {
+		t.Errorf("TestRateLimitModel got unexpected count for another key: %d", count)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// Роли, которые заводит миграция create_role_permissions.
+// Какие права даёт роль, лежит в role_permissions
+const (
+	// RoleAdmin оператор сервиса, ему доступен /v1/admin
+	RoleAdmin = "admin"
+	// RoleModerator модерирует игры и ботов
+	RoleModerator = "moderator"
+)
+
+// RoleAccessObject DAO for user_roles
+type RoleAccessObject interface {
+	List(userID int64) ([]string, error)
+	Grant(userID int64, role string) error
+	Revoke(userID int64, role string) error
+
+	// Permissions права, которые дают юзеру все его роли
+	Permissions(userID int64) ([]string, error)
+}
+
+// RoleConn implementation of RoleAccessObject
+type RoleConn struct{}
+
+// Roles interface variable for models methods
+var Roles RoleAccessObject
+
+func init() {
+	Roles = &RoleConn{}
+}
+
+// List роли юзера
+func (rc *RoleConn) List(userID int64) ([]string, error) {
+	rows, err := pqConn.Query(`SELECT r.role FROM user_roles r WHERE user_id = $1 ORDER BY role;`, userID)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "roles get error: %s", err.Error())
+	}
+
+	return scanStrings(rows, "roles")
+}
+
+// Permissions права всех ролей юзера без повторов
+func (rc *RoleConn) Permissions(userID int64) ([]string, error) {
+	rows, err := pqConn.Query(`SELECT DISTINCT p.permission FROM role_permissions p
+		JOIN user_roles r ON r.role = p.role
+		WHERE r.user_id = $1 ORDER BY p.permission;`, userID)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "permissions get error: %s", err.Error())
+	}
+
+	return scanStrings(rows, "permissions")
+}
+
+// Grant выдаёт роль, повторная выдача ничего не меняет
+func (rc *RoleConn) Grant(userID int64, role string) error {
+	_, err := pqConn.Exec(`INSERT INTO user_roles (user_id, role) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING;`, userID, role)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "role grant error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Revoke забирает роль
+func (rc *RoleConn) Revoke(userID int64, role string) error {
+	_, err := pqConn.Exec(`DELETE FROM user_roles WHERE user_id = $1 AND role = $2;`, userID, role)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "role revoke error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// scanStrings вычитывает запрос из одной текстовой колонки и закрывает rows
+func scanStrings(rows *sql.Rows, what string) ([]string, error) {
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "%s scan error: %s", what, err.Error())
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "%s read error: %s", what, err.Error())
+	}
+
+	return values, nil
+}
@@ -15,7 +15,7 @@ ssh-keyscan -H 89.208.198.192 >> ~/.ssh/known_hosts
 ssh -i ./2019_1_HotCode_id_rsa.pem ubuntu@89.208.198.192 docker pull $DOCKER_USER/warscript-users
 for (( c=1; c<=$CONTAINERS_COUNT; c++ ))
 do
-    ssh -i ./2019_1_HotCode_id_rsa.pem ubuntu@89.208.198.192 docker stop warscript-users.$c
+    ssh -i ./2019_1_HotCode_id_rsa.pem ubuntu@89.208.198.192 docker stop -t 15 warscript-users.$c
     if ssh -i ./2019_1_HotCode_id_rsa.pem ubuntu@89.208.198.192 test $? -eq 0
     then
         ssh -i ./2019_1_HotCode_id_rsa.pem ubuntu@89.208.198.192 docker rm warscript-users.$c || true
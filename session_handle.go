@@ -2,6 +2,7 @@ package main
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/HotCodeGroup/warscript-users/jmodels"
@@ -23,6 +24,17 @@ func SessionInfo(r *http.Request) *models.SessionPayload {
 	return nil
 }
 
+// setSessionCookie ставит куку сессии, время жизни берётся из политики сессий
+func setSessionCookie(w http.ResponseWriter, token string, remember bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "JSESSIONID",
+		Path:     "/",
+		Value:    token,
+		Expires:  sessionPolicy.CookieExpires(remember, time.Now()),
+		HttpOnly: true,
+	})
+}
+
 // CreateSession вход + кука
 func CreateSession(w http.ResponseWriter, r *http.Request) {
 	logger := utils.GetLogger(r, logger, "SignInUser")
@@ -35,26 +47,32 @@ func CreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, err := createSessionImpl(form)
+	session, err := createSessionImpl(form, newClientInfo(r))
 	if err != nil {
 		if validErr, ok := err.(*utils.ValidationError); ok {
 			errWriter.WriteValidationError(validErr)
 			return
 		}
 
+		if lockErr, ok := err.(*LoginLockedError); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(lockErr.RetryAfterSeconds(), 10))
+			errWriter.WriteWarn(http.StatusTooManyRequests, lockErr)
+			return
+		}
+
+		// пароль верный, но сессия откроется только после кода
+		if tfErr, ok := err.(*TwoFactorRequiredError); ok {
+			utils.WriteApplicationJSON(w, http.StatusAccepted, &jmodels.TwoFactorChallenge{
+				Challenge: tfErr.Challenge,
+			})
+			return
+		}
+
 		errWriter.WriteError(http.StatusInternalServerError, err)
 		return
 	}
 
-	// ставим куку
-	http.SetCookie(w, &http.Cookie{
-		Name:     "JSESSIONID",
-		Path:     "/",
-		Value:    session.Token,
-		Expires:  time.Now().Add(2628000 * time.Second),
-		HttpOnly: true,
-	})
-
+	setSessionCookie(w, session.Token, form.Remember)
 	w.WriteHeader(http.StatusOK)
 }
 
@@ -68,15 +86,31 @@ func DeleteSession(w http.ResponseWriter, r *http.Request) {
 		errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "get cookie error"))
 		return
 	}
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	if err = checkSessionOwnerImpl(info.ID, cookie.Value); err != nil {
+		if errors.Cause(err) == ErrSessionNotExists {
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
 
 	session := &Session{
-		Token: cookie.Value,
+		Token:  cookie.Value,
+		UserID: info.ID,
 	}
 	err = Sessions.Delete(session)
 	if err != nil {
 		errWriter.WriteWarn(http.StatusInternalServerError, errors.Wrap(err, "session delete error"))
 		return
 	}
+	auditUserImpl(AuditLogout, session.UserID, newClientInfo(r), map[string]string{"session": session.PublicID()})
 
 	cookie.Expires = time.Unix(0, 0)
 	http.SetCookie(w, cookie)
@@ -84,7 +118,7 @@ func DeleteSession(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// GetSession возвращает сессмю
+// GetSession возвращает профиль юзера и информацию о текущей сессии
 func GetSession(w http.ResponseWriter, r *http.Request) {
 	logger := utils.GetLogger(r, logger, "GetSession")
 	errWriter := utils.NewErrorResponseWriter(w, logger)
@@ -94,15 +128,76 @@ func GetSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profileInfoUser, err := getInfoUserByIDImpl(info.ID)
+	cookie, err := r.Cookie("JSESSIONID")
 	if err != nil {
-		if errors.Cause(err) == utils.ErrNotExists {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "get cookie error"))
+		return
+	}
+
+	currentSession, err := getCurrentSessionImpl(info, cookie.Value)
+	if err != nil {
+		switch errors.Cause(err) {
+		case utils.ErrNotExists:
 			errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "user not exists"))
-		} else {
+		case ErrSessionNotExists:
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+		default:
 			errWriter.WriteError(http.StatusInternalServerError, err)
 		}
 		return
 	}
 
-	utils.WriteApplicationJSON(w, http.StatusOK, profileInfoUser)
+	utils.WriteApplicationJSON(w, http.StatusOK, currentSession)
+}
+
+// GetAllSessions возвращает все активные сессии юзера
+func GetAllSessions(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "GetAllSessions")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	cookie, err := r.Cookie("JSESSIONID")
+	if err != nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.Wrap(err, "get cookie error"))
+		return
+	}
+
+	sessions, err := listSessionsImpl(info, cookie.Value)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, sessions)
+}
+
+// DeleteAllSessions выход со всех устройств, включая текущее
+func DeleteAllSessions(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "DeleteAllSessions")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	err := Sessions.DeleteAllForUser(info.ID)
+	if err != nil {
+		errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "sessions delete error"))
+		return
+	}
+	auditUserImpl(AuditLogout, info.ID, newClientInfo(r), map[string]string{"all": "true"})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "JSESSIONID",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+	})
+
+	w.WriteHeader(http.StatusOK)
 }
@@ -2,50 +2,124 @@ package main
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
 	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
-func createSessionImpl(form *jmodels.FormUser) (*Session, error) {
+// lastSeenUpdateInterval как часто обновляем last_seen сессии,
+// чтобы не писать в редис на каждый запрос
+const lastSeenUpdateInterval = time.Minute
+
+func createSessionImpl(form *jmodels.FormUser, client *clientInfo) (*Session, error) {
 	if err := form.Validate(); err != nil {
 		return nil, err
 	}
 
-	user, err := Users.GetUserByUsername(form.Username)
-	if err != nil {
-		return nil, &utils.ValidationError{
+	user, lookupErr := getUserByLoginImpl(form.Username)
+	if lookupErr != nil {
+		user = nil
+	}
+
+	subject := loginSubject(user, form.Username)
+	if err := checkLoginLockImpl(subject, client.IP); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		auditUserImpl(AuditLoginFailed, 0, client, map[string]string{
+			"login":  form.Username,
+			"reason": "username",
+		})
+		return nil, rejectLogin(subject, client, &utils.ValidationError{
 			"username": utils.ErrNotExists.Error(),
-		}
+		})
 	}
 
 	if !Users.CheckPassword(user, form.Password) {
-		return nil, &utils.ValidationError{
+		auditUserImpl(AuditLoginFailed, user.ID, client, map[string]string{"reason": "password"})
+		return nil, rejectLogin(subject, client, &utils.ValidationError{
 			"password": utils.ErrInvalid.Error(),
+		})
+	}
+
+	// пароль верный, так что можно сказать, что аккаунт выключен:
+	// фронтенд предложит включить его обратно
+	if !user.Active {
+		return nil, &utils.ValidationError{
+			"username": "deactivated",
 		}
 	}
 
+	// счётчик неудач сбрасываем только после второго фактора,
+	// иначе пароль позволил бы перебирать коды без блокировки
+	if err := requireSecondFactorImpl(user); err != nil {
+		return nil, err
+	}
+
+	if err := loginSucceededImpl(user.ID); err != nil {
+		logger.Warnf("can not reset login failures: %s", err)
+	}
+
+	return startSessionImpl(user, form.Remember, client)
+}
+
+// startSessionImpl открывает сессию юзеру, который прошёл все проверки входа
+func startSessionImpl(user *UserModel, remember bool, client *clientInfo) (*Session, error) {
+	now := time.Now()
 	data, err := json.Marshal(&jmodels.SessionPayload{
-		ID: user.ID,
+		ID:       user.ID,
+		Remember: remember,
+		SessionMeta: jmodels.SessionMeta{
+			CreatedAt: now,
+			LastSeen:  now,
+			IP:        client.IP,
+			UserAgent: client.UserAgent,
+			Device:    deviceLabel(client.UserAgent),
+		},
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "info marshal error")
 	}
 
 	session := &Session{
+		UserID:       user.ID,
 		Payload:      data,
-		ExpiresAfter: time.Hour * 24 * 30,
+		ExpiresAfter: sessionPolicy.NextTTL(remember, now, now),
 	}
 	err = Sessions.Set(session)
 	if err != nil {
 		return nil, errors.Wrap(err, "set session error")
 	}
+	auditUserImpl(AuditLogin, user.ID, client, map[string]string{"session": session.PublicID()})
 
 	return session, nil
 }
 
+// getUserByLoginImpl ищет юзера по username или по email: в username
+// не бывает @, так что перепутать их нельзя
+func getUserByLoginImpl(login string) (*UserModel, error) {
+	if strings.Contains(login, "@") {
+		return Users.GetUserByEmail(login)
+	}
+
+	return Users.GetUserByUsername(login)
+}
+
+// rejectLogin учитывает неудачный вход и возвращает ошибку для юзера
+func rejectLogin(subject string, client *clientInfo, valErr *utils.ValidationError) error {
+	if err := loginFailedImpl(subject, client.IP); err != nil {
+		return err
+	}
+
+	return valErr
+}
+
 func getSessionImpl(token string) (*jmodels.SessionPayload, error) {
 	session, err := Sessions.GetSession(token)
 	if err != nil {
@@ -57,5 +131,155 @@ func getSessionImpl(token string) (*jmodels.SessionPayload, error) {
 		return nil, errors.Wrap(err, "payload unmarshal error")
 	}
 
+	// сколько бы юзер ни был активен, после AbsoluteTTL надо войти заново
+	if sessionPolicy.Expired(payload.CreatedAt, time.Now()) {
+		if err = Sessions.Delete(&Session{Token: token, UserID: payload.ID}); err != nil {
+			logger.Warnf("can not delete expired session: %s", err)
+		}
+		return nil, ErrSessionNotExists
+	}
+
 	return payload, nil
 }
+
+// touchSessionImpl обновляет last_seen и продлевает сессию по политике
+// не чаще lastSeenUpdateInterval. Ошибки только логируются:
+// из-за них не стоит отказывать в доступе
+func touchSessionImpl(token string, payload *jmodels.SessionPayload) {
+	now := time.Now()
+	if now.Sub(payload.LastSeen) < lastSeenUpdateInterval {
+		return
+	}
+
+	payload.LastSeen = now
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Warnf("can not marshal session payload: %s", err)
+		return
+	}
+
+	if err = Sessions.Update(&Session{Token: token, Payload: data}); err != nil {
+		logger.Warnf("can not update session last seen: %s", err)
+		return
+	}
+
+	err = Sessions.Refresh(&Session{
+		Token:        token,
+		UserID:       payload.ID,
+		ExpiresAfter: sessionPolicy.NextTTL(payload.Remember, payload.CreatedAt, now),
+	})
+	if err != nil {
+		logger.Warnf("can not refresh session: %s", err)
+	}
+}
+
+func getSessionInfoImpl(token string) (*models.SessionPayload, error) {
+	payload, err := getSessionImpl(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// при выключении сессии завершаются, но сессия могла открыться
+	// одновременно с ним, поэтому юзера проверяем на каждом запросе
+	if err = checkSessionUserImpl(token, payload.ID); err != nil {
+		return nil, err
+	}
+	touchSessionImpl(token, payload)
+
+	return &models.SessionPayload{
+		ID: payload.ID,
+	}, nil
+}
+
+// checkSessionUserImpl возвращает ErrSessionNotExists и удаляет сессию,
+// если её юзер выключен или удалён
+func checkSessionUserImpl(token string, userID int64) error {
+	user, err := Users.GetUserByID(userID)
+	if err != nil && errors.Cause(err) != utils.ErrNotExists {
+		return errors.Wrap(err, "get user error")
+	}
+	if err == nil && user.Active {
+		return nil
+	}
+
+	if err = Sessions.Delete(&Session{Token: token, UserID: userID}); err != nil {
+		logger.Warnf("can not delete session of inactive user: %s", err)
+	}
+	return ErrSessionNotExists
+}
+
+// checkSessionOwnerImpl возвращает ErrSessionNotExists, если token не токен
+// сессии юзера userID. Кука приходит от клиента, и без проверки по ней можно
+// прочитать или удалить любой ключ в редисе
+func checkSessionOwnerImpl(userID int64, token string) error {
+	if _, err := uuid.Parse(token); err != nil {
+		return ErrSessionNotExists
+	}
+
+	owned, err := Sessions.BelongsTo(userID, token)
+	if err != nil {
+		return errors.Wrap(err, "session owner error")
+	}
+	if !owned {
+		return ErrSessionNotExists
+	}
+
+	return nil
+}
+
+func listSessionsImpl(info *models.SessionPayload, token string) ([]*jmodels.ActiveSession, error) {
+	sessions, err := Sessions.ListByUser(info.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "list sessions error")
+	}
+
+	active := make([]*jmodels.ActiveSession, 0, len(sessions))
+	for _, s := range sessions {
+		a, err := newActiveSession(s, token)
+		if err != nil {
+			return nil, err
+		}
+		active = append(active, a)
+	}
+
+	return active, nil
+}
+
+func newActiveSession(s *Session, currentToken string) (*jmodels.ActiveSession, error) {
+	payload := &jmodels.SessionPayload{}
+	if err := json.Unmarshal(s.Payload, payload); err != nil {
+		return nil, errors.Wrap(err, "payload unmarshal error")
+	}
+
+	return &jmodels.ActiveSession{
+		SessionMeta: payload.SessionMeta,
+		ID:          s.PublicID(),
+		Current:     s.Token == currentToken,
+	}, nil
+}
+
+func getCurrentSessionImpl(info *models.SessionPayload, token string) (*jmodels.CurrentSession, error) {
+	if err := checkSessionOwnerImpl(info.ID, token); err != nil {
+		return nil, err
+	}
+
+	profile, err := getInfoUserByIDImpl(info.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := Sessions.GetSession(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "get session error")
+	}
+
+	active, err := newActiveSession(session, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jmodels.CurrentSession{
+		ProfileInfoUser: *profile,
+		Session:         *active,
+	}, nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
 	"time"
 
 	"github.com/HotCodeGroup/warscript-utils/utils"
@@ -12,11 +15,42 @@ import (
 
 var rediCli *redis.Client
 
+// ErrSessionNotExists сессии с таким токеном нет или она истекла
+var ErrSessionNotExists = errors.New("session_not_exists")
+
+// updateSessionScript перезаписывает сессию, сохраняя оставшееся время жизни.
+// Если сессия успела истечь, ничего не делает
+var updateSessionScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl <= 0 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+return 1
+`)
+
+// extendTTLScript продлевает ключ, только если он живёт меньше ARGV[1] мс.
+// Так индекс сессий юзера не истечёт раньше самой долгой из них
+var extendTTLScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl ~= -2 and ttl < tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return ttl
+`)
+
 // SessionAccessObject DAO for Session model
 type SessionAccessObject interface {
 	Set(s *Session) error
+	Update(s *Session) error
+	Refresh(s *Session) error
 	Delete(s *Session) error
 	GetSession(token string) (*Session, error)
+	BelongsTo(userID int64, token string) (bool, error)
+
+	ListByUser(userID int64) ([]*Session, error)
+	DeleteAllForUser(userID int64) error
+	DeleteAllExcept(userID int64, token string) error
 }
 
 // SessionConn implementation of SessionAccessObject
@@ -32,15 +66,37 @@ func init() {
 // Session модель для работы с сессиями
 type Session struct {
 	Token        string
+	UserID       int64
 	Payload      []byte
 	ExpiresAfter time.Duration
 }
 
+// PublicID идентификатор сессии, который можно показывать
+// юзеру вместо самого токена
+func (s *Session) PublicID() string {
+	sum := sha256.Sum256([]byte(s.Token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// userSessionsKey ключ множества токенов юзера
+func userSessionsKey(userID int64) string {
+	return "user_sessions:" + strconv.FormatInt(userID, 10)
+}
+
 // Set валидирует и сохраняет сессию в хранилище по сгенерированному токену
 // Токен сохраняется в s.Token
 func (ss *SessionConn) Set(s *Session) error {
 	sessionToken := uuid.New()
-	err := rediCli.Set(sessionToken.String(), s.Payload, s.ExpiresAfter).Err()
+	_, err := rediCli.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(sessionToken.String(), s.Payload, s.ExpiresAfter)
+		if s.UserID != 0 {
+			key := userSessionsKey(s.UserID)
+			pipe.SAdd(key, sessionToken.String())
+			// в пайплайне EVALSHA не сможет откатиться на EVAL, поэтому сразу EVAL
+			extendTTLScript.Eval(pipe, []string{key}, int64(s.ExpiresAfter/time.Millisecond))
+		}
+		return nil
+	})
 	if err != nil {
 		return errors.Wrapf(utils.ErrInternal, "redis save error: %v", err)
 	}
@@ -49,9 +105,50 @@ func (ss *SessionConn) Set(s *Session) error {
 	return nil
 }
 
+// Update перезаписывает payload сессии s.Token, не трогая время её жизни
+func (ss *SessionConn) Update(s *Session) error {
+	updated, err := updateSessionScript.Run(rediCli, []string{s.Token}, s.Payload).Int()
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "redis update error: %v", err)
+	}
+	if updated == 0 {
+		return ErrSessionNotExists
+	}
+
+	return nil
+}
+
+// Refresh продлевает сессию s.Token на s.ExpiresAfter
+func (ss *SessionConn) Refresh(s *Session) error {
+	ok, err := rediCli.Expire(s.Token, s.ExpiresAfter).Result()
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "redis expire error: %v", err)
+	}
+	if !ok {
+		return ErrSessionNotExists
+	}
+
+	if s.UserID != 0 {
+		err = extendTTLScript.Run(rediCli, []string{userSessionsKey(s.UserID)},
+			int64(s.ExpiresAfter/time.Millisecond)).Err()
+		if err != nil {
+			return errors.Wrapf(utils.ErrInternal, "redis index expire error: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // Delete удаляет сессию с токен s.Token из хранилища
+// и из индекса юзера s.UserID, если он задан
 func (ss *SessionConn) Delete(s *Session) error {
-	err := rediCli.Del(s.Token).Err()
+	_, err := rediCli.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(s.Token)
+		if s.UserID != 0 {
+			pipe.SRem(userSessionsKey(s.UserID), s.Token)
+		}
+		return nil
+	})
 	if err != nil {
 		return errors.Wrapf(utils.ErrInternal, "redis delete error: %v", err)
 	}
@@ -59,10 +156,13 @@ func (ss *SessionConn) Delete(s *Session) error {
 	return nil
 }
 
-// GetSession получает сессию из хранилища по токену
+// GetSession получает сессию из хранилища по токену.
+// Если сессии нет или она истекла, возвращает ErrSessionNotExists
 func (ss *SessionConn) GetSession(token string) (*Session, error) {
 	data, err := rediCli.Get(token).Bytes()
-	if err != nil {
+	if err == redis.Nil {
+		return nil, ErrSessionNotExists
+	} else if err != nil {
 		return nil, errors.Wrapf(utils.ErrInternal, "redis get error: %v", err)
 	}
 
@@ -71,3 +171,96 @@ func (ss *SessionConn) GetSession(token string) (*Session, error) {
 		Payload: data,
 	}, nil
 }
+
+// BelongsTo есть ли token в индексе сессий юзера userID
+func (ss *SessionConn) BelongsTo(userID int64, token string) (bool, error) {
+	ok, err := rediCli.SIsMember(userSessionsKey(userID), token).Result()
+	if err != nil {
+		return false, errors.Wrapf(utils.ErrInternal, "redis sismember error: %v", err)
+	}
+
+	return ok, nil
+}
+
+// ListByUser получает все живые сессии юзера.
+// Истёкшие токены заодно вычищаются из индекса
+func (ss *SessionConn) ListByUser(userID int64) ([]*Session, error) {
+	key := userSessionsKey(userID)
+	tokens, err := rediCli.SMembers(key).Result()
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "redis smembers error: %v", err)
+	}
+
+	sessions := make([]*Session, 0, len(tokens))
+	if len(tokens) == 0 {
+		return sessions, nil
+	}
+
+	values, err := rediCli.MGet(tokens...).Result()
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "redis mget error: %v", err)
+	}
+
+	expired := make([]interface{}, 0)
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			expired = append(expired, tokens[i])
+			continue
+		}
+
+		sessions = append(sessions, &Session{
+			Token:   tokens[i],
+			UserID:  userID,
+			Payload: []byte(data),
+		})
+	}
+
+	if len(expired) != 0 {
+		if err = rediCli.SRem(key, expired...).Err(); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "redis srem error: %v", err)
+		}
+	}
+
+	return sessions, nil
+}
+
+// DeleteAllForUser удаляет все сессии юзера
+func (ss *SessionConn) DeleteAllForUser(userID int64) error {
+	return ss.DeleteAllExcept(userID, "")
+}
+
+// DeleteAllExcept удаляет все сессии юзера, кроме сессии с токеном token
+func (ss *SessionConn) DeleteAllExcept(userID int64, token string) error {
+	key := userSessionsKey(userID)
+	tokens, err := rediCli.SMembers(key).Result()
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "redis smembers error: %v", err)
+	}
+
+	revoked := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t != token {
+			revoked = append(revoked, t)
+		}
+	}
+	if len(revoked) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(revoked))
+	for i, t := range revoked {
+		members[i] = t
+	}
+
+	_, err = rediCli.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(revoked...)
+		pipe.SRem(key, members...)
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "redis delete error: %v", err)
+	}
+
+	return nil
+}
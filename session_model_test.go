@@ -98,3 +98,129 @@ func TestGetSessionModelErr(t *testing.T) {
 		t.Errorf(" TestGetSessionModel got unexpected error: %v, expected: %v", err, utils.ErrInternal)
 	}
 }
+
+func TestGetSessionModelNotExists(t *testing.T) {
+	rediCli = newTestRedis()
+	Sessions = &SessionConn{}
+
+	_, err := Sessions.GetSession("kek")
+	if errors.Cause(err) != ErrSessionNotExists {
+		t.Errorf("TestGetSessionModelNotExists got unexpected error: %v, expected: %v", err, ErrSessionNotExists)
+	}
+}
+
+func TestSessionsByUser(t *testing.T) {
+	rediCli = newTestRedis()
+	Sessions = &SessionConn{}
+
+	tokens := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		s := &Session{
+			UserID:       1,
+			Payload:      []byte(`{"id":1}`),
+			ExpiresAfter: time.Minute,
+		}
+		if err := Sessions.Set(s); err != nil {
+			t.Fatalf("TestSessionsByUser got unexpected error: %v", err)
+		}
+		tokens = append(tokens, s.Token)
+	}
+
+	foreign := &Session{UserID: 2, Payload: []byte(`{"id":2}`), ExpiresAfter: time.Minute}
+	if err := Sessions.Set(foreign); err != nil {
+		t.Fatalf("TestSessionsByUser got unexpected error: %v", err)
+	}
+
+	// одна из сессий истекла сама
+	rediCli.Del(tokens[2])
+
+	sessions, err := Sessions.ListByUser(1)
+	if err != nil || len(sessions) != 2 {
+		t.Errorf("TestSessionsByUser ListByUser got: %v, %v, expected 2 sessions", sessions, err)
+	}
+	if n := rediCli.SCard(userSessionsKey(1)).Val(); n != 2 {
+		t.Errorf("TestSessionsByUser expired token was not removed from index: %d", n)
+	}
+
+	if ok, err := Sessions.BelongsTo(1, tokens[0]); err != nil || !ok {
+		t.Errorf("TestSessionsByUser BelongsTo got: %t, %v, expected: true", ok, err)
+	}
+	if ok, err := Sessions.BelongsTo(1, foreign.Token); err != nil || ok {
+		t.Errorf("TestSessionsByUser BelongsTo got: %t, %v, expected: false", ok, err)
+	}
+
+	if err = Sessions.DeleteAllExcept(1, tokens[0]); err != nil {
+		t.Errorf("TestSessionsByUser DeleteAllExcept got unexpected error: %v", err)
+	}
+	if _, err = Sessions.GetSession(tokens[1]); errors.Cause(err) != ErrSessionNotExists {
+		t.Errorf("TestSessionsByUser revoked session still exists: %v", err)
+	}
+	if _, err = Sessions.GetSession(tokens[0]); err != nil {
+		t.Errorf("TestSessionsByUser current session was revoked: %v", err)
+	}
+
+	if err = Sessions.DeleteAllForUser(1); err != nil {
+		t.Errorf("TestSessionsByUser DeleteAllForUser got unexpected error: %v", err)
+	}
+	if _, err = Sessions.GetSession(tokens[0]); errors.Cause(err) != ErrSessionNotExists {
+		t.Errorf("TestSessionsByUser session still exists: %v", err)
+	}
+	if _, err = Sessions.GetSession(foreign.Token); err != nil {
+		t.Errorf("TestSessionsByUser foreign session was revoked: %v", err)
+	}
+}
+
+func TestUpdateSessionModel(t *testing.T) {
+	rediCli = newTestRedis()
+	Sessions = &SessionConn{}
+
+	rediCli.Set("kek", "lol", time.Minute)
+	if err := Sessions.Update(&Session{Token: "kek", Payload: []byte("kek")}); err != nil {
+		t.Errorf("TestUpdateSessionModel got unexpected error: %v", err)
+	}
+	if v := rediCli.Get("kek").Val(); v != "kek" {
+		t.Errorf("TestUpdateSessionModel got payload: %s, expected: %s", v, "kek")
+	}
+	if ttl := rediCli.TTL("kek").Val(); ttl <= 0 {
+		t.Errorf("TestUpdateSessionModel lost session ttl: %v", ttl)
+	}
+
+	err := Sessions.Update(&Session{Token: "lol", Payload: []byte("lol")})
+	if errors.Cause(err) != ErrSessionNotExists {
+		t.Errorf("TestUpdateSessionModel got unexpected error: %v, expected: %v", err, ErrSessionNotExists)
+	}
+}
+
+func TestRefreshSessionModel(t *testing.T) {
+	rediCli = newTestRedis()
+	Sessions = &SessionConn{}
+
+	long := &Session{UserID: 1, Payload: []byte("long"), ExpiresAfter: time.Hour}
+	short := &Session{UserID: 1, Payload: []byte("short"), ExpiresAfter: time.Minute}
+	for _, s := range []*Session{long, short} {
+		if err := Sessions.Set(s); err != nil {
+			t.Fatalf("TestRefreshSessionModel got unexpected error: %v", err)
+		}
+	}
+
+	// короткая сессия не должна укоротить индекс
+	if ttl := rediCli.TTL(userSessionsKey(1)).Val(); ttl != time.Hour {
+		t.Errorf("TestRefreshSessionModel index ttl: %v, expected: %v", ttl, time.Hour)
+	}
+
+	short.ExpiresAfter = 2 * time.Hour
+	if err := Sessions.Refresh(short); err != nil {
+		t.Errorf("TestRefreshSessionModel got unexpected error: %v", err)
+	}
+	if ttl := rediCli.TTL(short.Token).Val(); ttl != 2*time.Hour {
+		t.Errorf("TestRefreshSessionModel session ttl: %v, expected: %v", ttl, 2*time.Hour)
+	}
+	if ttl := rediCli.TTL(userSessionsKey(1)).Val(); ttl != 2*time.Hour {
+		t.Errorf("TestRefreshSessionModel index ttl: %v, expected: %v", ttl, 2*time.Hour)
+	}
+
+	err := Sessions.Refresh(&Session{Token: "kek", ExpiresAfter: time.Hour})
+	if errors.Cause(err) != ErrSessionNotExists {
+		t.Errorf("TestRefreshSessionModel got unexpected error: %v, expected: %v", err, ErrSessionNotExists)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import "time"
+
+// SessionPolicy правила жизни сессий. Сессия продлевается при активности
+// на TTL (или RememberTTL, если юзер попросил его запомнить),
+// но живёт не дольше AbsoluteTTL с момента входа
+type SessionPolicy struct {
+	TTL         time.Duration
+	RememberTTL time.Duration
+	AbsoluteTTL time.Duration
+}
+
+// sessionPolicy политика, с которой работает сервис
+var sessionPolicy = NewSessionPolicy(SessionConfig{})
+
+// NewSessionPolicy создаёт политику из конфига, незаданные значения берутся по умолчанию
+func NewSessionPolicy(c SessionConfig) *SessionPolicy {
+	p := &SessionPolicy{
+		TTL:         24 * time.Hour,
+		RememberTTL: 30 * 24 * time.Hour,
+		AbsoluteTTL: 90 * 24 * time.Hour,
+	}
+
+	if c.TTL > 0 {
+		p.TTL = time.Duration(c.TTL) * time.Second
+	}
+	if c.RememberTTL > 0 {
+		p.RememberTTL = time.Duration(c.RememberTTL) * time.Second
+	}
+	if c.AbsoluteTTL > 0 {
+		p.AbsoluteTTL = time.Duration(c.AbsoluteTTL) * time.Second
+	}
+
+	return p
+}
+
+// IdleTTL сколько сессия живёт без активности
+func (p *SessionPolicy) IdleTTL(remember bool) time.Duration {
+	if remember {
+		return p.RememberTTL
+	}
+
+	return p.TTL
+}
+
+// Expired прошло ли AbsoluteTTL с момента входа.
+// У старых сессий без времени создания ограничения нет
+func (p *SessionPolicy) Expired(createdAt, now time.Time) bool {
+	if createdAt.IsZero() {
+		return false
+	}
+
+	return !now.Before(createdAt.Add(p.AbsoluteTTL))
+}
+
+// NextTTL на сколько продлить сессию при активности в момент now
+func (p *SessionPolicy) NextTTL(remember bool, createdAt, now time.Time) time.Duration {
+	ttl := p.IdleTTL(remember)
+	if createdAt.IsZero() {
+		return ttl
+	}
+
+	if left := createdAt.Add(p.AbsoluteTTL).Sub(now); left < ttl {
+		return left
+	}
+
+	return ttl
+}
+
+// CookieExpires когда истекает кука сессии. Без "запомнить меня"
+// кука живёт до закрытия браузера (нулевое время)
+func (p *SessionPolicy) CookieExpires(remember bool, now time.Time) time.Time {
+	if !remember {
+		return time.Time{}
+	}
+
+	return now.Add(p.AbsoluteTTL)
+}
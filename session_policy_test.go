@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionPolicy(t *testing.T) {
+	p := NewSessionPolicy(SessionConfig{TTL: 60, AbsoluteTTL: 3600})
+	if p.TTL != time.Minute || p.RememberTTL != 30*24*time.Hour || p.AbsoluteTTL != time.Hour {
+		t.Fatalf("TestSessionPolicy got unexpected policy: %+v", p)
+	}
+
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		remember  bool
+		createdAt time.Time
+		expected  time.Duration
+	}{
+		{remember: false, createdAt: now, expected: time.Minute},
+		{remember: true, createdAt: now, expected: time.Hour},
+		{remember: true, createdAt: now.Add(-50 * time.Minute), expected: 10 * time.Minute},
+		{remember: true, createdAt: time.Time{}, expected: 30 * 24 * time.Hour},
+	}
+	for i, c := range cases {
+		if ttl := p.NextTTL(c.remember, c.createdAt, now); ttl != c.expected {
+			t.Errorf("[%d] TestSessionPolicy NextTTL got: %v, expected: %v", i, ttl, c.expected)
+		}
+	}
+
+	if p.Expired(now.Add(-59*time.Minute), now) || !p.Expired(now.Add(-time.Hour), now) || p.Expired(time.Time{}, now) {
+		t.Errorf("TestSessionPolicy Expired works wrong")
+	}
+
+	if !p.CookieExpires(false, now).IsZero() {
+		t.Errorf("TestSessionPolicy cookie without remember must be a session cookie")
+	}
+	if exp := p.CookieExpires(true, now); !exp.Equal(now.Add(time.Hour)) {
+		t.Errorf("TestSessionPolicy CookieExpires got: %v, expected: %v", exp, now.Add(time.Hour))
+	}
+}
+
+func TestGetSessionAbsoluteExpired(t *testing.T) {
+	sessions := &sessionsTest{
+		sessions: map[string][]byte{
+			"old": []byte(`{"id":1,"created_at":"2019-05-01T12:00:00Z"}`),
+		},
+	}
+	Sessions = sessions
+
+	if _, err := getSessionImpl("old"); err != ErrSessionNotExists {
+		t.Errorf("TestGetSessionAbsoluteExpired got unexpected error: %v, expected: %v", err, ErrSessionNotExists)
+	}
+	if _, ok := sessions.sessions["old"]; ok {
+		t.Errorf("TestGetSessionAbsoluteExpired expired session was not deleted")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// shutdownServers перестаёт принимать новые соединения и дожидается
+// завершения текущих запросов http и grpc, но не дольше timeout.
+// После дедлайна оставшиеся grpc вызовы обрываются
+func shutdownServers(timeout time.Duration, httpServer *http.Server, grpcServer *grpc.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			logger.Errorf("http server shutdown error: %s", err)
+			return
+		}
+		logger.Info("http server successfully stopped")
+	}()
+
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			logger.Info("grpc server successfully stopped")
+		case <-ctx.Done():
+			grpcServer.Stop()
+			logger.Errorf("grpc server shutdown error: %s", ctx.Err())
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestShutdownServersDrainsHTTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("TestShutdownServersDrainsHTTP can not listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	serverHTTP := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+	//nolint:errcheck
+	go serverHTTP.Serve(listener)
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("TestShutdownServersDrainsHTTP can not listen: %v", err)
+	}
+	serverGRPC := grpc.NewServer()
+	//nolint:errcheck
+	go serverGRPC.Serve(grpcListener)
+
+	codes := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			codes <- 0
+			return
+		}
+		resp.Body.Close()
+		codes <- resp.StatusCode
+	}()
+
+	<-started
+	shutdownServers(time.Second, serverHTTP, serverGRPC)
+
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("TestShutdownServersDrainsHTTP in-flight request got code: %d, expected: %d", code, http.StatusOK)
+	}
+
+	if _, err := http.Get("http://" + listener.Addr().String()); err == nil {
+		t.Errorf("TestShutdownServersDrainsHTTP server still accepts connections")
+	}
+}
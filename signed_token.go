@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrTokenInvalid токен подделан, испорчен, истёк или выдан для другой цели
+var ErrTokenInvalid = errors.New("token_invalid")
+
+// Цели подписанных токенов: токен одной цели не подходит для другой
+const (
+	TokenPurposeVerifyEmail = "verify_email"
+	TokenPurposeLogin2FA    = "login_2fa"
+)
+
+// TokenClaims содержимое подписанного токена. Binding привязывает токен
+// к состоянию юзера (например, к email): когда оно меняется, токен перестаёт
+// подходить, так что повторно им ничего не сделать
+type TokenClaims struct {
+	Purpose   string `json:"pur"`
+	UserID    int64  `json:"uid"`
+	Binding   string `json:"bnd"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenSigner подписывает токены HMAC-SHA256. Токен имеет вид
+// base64(claims).base64(подпись) и не требует хранилища
+type TokenSigner struct {
+	key []byte
+}
+
+// errNoTokenKey ключ подписи не задан: без него токены не выпускаются и не принимаются
+var errNoTokenKey = errors.New("token secret is not configured")
+
+// tokenSigner подписыватель, с которым работает сервис. Ключ задаётся в main,
+// до этого токены не выпускаются и не принимаются
+var tokenSigner = &TokenSigner{}
+
+// NewTokenSigner создаёт подписыватель с ключом secret. Случайный ключ не подставляется:
+// у каждого контейнера он был бы свой, и ссылки из писем ломались бы после перезапуска
+func NewTokenSigner(secret string) (*TokenSigner, error) {
+	if secret == "" {
+		return nil, errNoTokenKey
+	}
+
+	return &TokenSigner{key: []byte(secret)}, nil
+}
+
+func (s *TokenSigner) mac(payload string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(payload)) //nolint: errcheck
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Sign выпускает токен для purpose, действующий ttl
+func (s *TokenSigner) Sign(purpose string, userID int64, binding string, ttl time.Duration) (string, error) {
+	if len(s.key) == 0 {
+		return "", errNoTokenKey
+	}
+
+	data, err := json.Marshal(&TokenClaims{
+		Purpose:   purpose,
+		UserID:    userID,
+		Binding:   binding,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "claims marshal error")
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + s.mac(payload), nil
+}
+
+// Verify проверяет подпись, цель и срок токена
+func (s *TokenSigner) Verify(purpose, token string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(s.key) == 0 || len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(s.mac(parts[0]))) {
+		return nil, ErrTokenInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	claims := &TokenClaims{}
+	if err = json.Unmarshal(data, claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	if claims.Purpose != purpose || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenInvalid
+	}
+
+	return claims, nil
+}
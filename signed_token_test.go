@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenSigner(t *testing.T) {
+	signer, err := NewTokenSigner("secret")
+	if err != nil {
+		t.Fatalf("TestTokenSigner got unexpected error: %v", err)
+	}
+
+	token, err := signer.Sign(TokenPurposeVerifyEmail, 1, "kek@mail.ru", time.Hour)
+	if err != nil {
+		t.Fatalf("TestTokenSigner got unexpected error: %v", err)
+	}
+
+	claims, err := signer.Verify(TokenPurposeVerifyEmail, token)
+	if err != nil {
+		t.Fatalf("TestTokenSigner got unexpected error: %v", err)
+	}
+	if claims.UserID != 1 || claims.Binding != "kek@mail.ru" {
+		t.Errorf("TestTokenSigner got unexpected claims: %+v", claims)
+	}
+
+	expired, _ := signer.Sign(TokenPurposeVerifyEmail, 1, "kek@mail.ru", -time.Second)
+	other, _ := NewTokenSigner("other secret")
+	cases := []struct {
+		signer  *TokenSigner
+		purpose string
+		token   string
+	}{
+		{signer: signer, purpose: "other", token: token},
+		{signer: other, purpose: TokenPurposeVerifyEmail, token: token},
+		{signer: &TokenSigner{}, purpose: TokenPurposeVerifyEmail, token: token},
+		{signer: signer, purpose: TokenPurposeVerifyEmail, token: token[1:]},
+		{signer: signer, purpose: TokenPurposeVerifyEmail, token: token + "x"},
+		{signer: signer, purpose: TokenPurposeVerifyEmail, token: "kek"},
+		{signer: signer, purpose: TokenPurposeVerifyEmail, token: expired},
+	}
+
+	for i, c := range cases {
+		if _, err := c.signer.Verify(c.purpose, c.token); err != ErrTokenInvalid {
+			t.Errorf("[%d] TestTokenSigner got unexpected error: %v, expected: %v", i, err, ErrTokenInvalid)
+		}
+	}
+}
+
+func TestTokenSignerWithoutSecret(t *testing.T) {
+	if _, err := NewTokenSigner(""); err != errNoTokenKey {
+		t.Errorf("TestTokenSignerWithoutSecret got unexpected error: %v, expected: %v", err, errNoTokenKey)
+	}
+
+	if _, err := (&TokenSigner{}).Sign(TokenPurposeVerifyEmail, 1, "kek@mail.ru", time.Hour); err != errNoTokenKey {
+		t.Errorf("TestTokenSignerWithoutSecret got unexpected error: %v, expected: %v", err, errNoTokenKey)
+	}
+}
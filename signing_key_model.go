@@ -0,0 +1,83 @@
+package main
+
+import (
+	"time"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// SigningKeyAccessObject DAO for signing_keys
+type SigningKeyAccessObject interface {
+	// List все ключи, от новых к старым
+	List() ([]*SigningKeyModel, error)
+	Create(k *SigningKeyModel) error
+	// DeleteBefore удаляет ключи, созданные раньше before
+	DeleteBefore(before time.Time) error
+}
+
+// SigningKeyConn implementation of SigningKeyAccessObject
+type SigningKeyConn struct{}
+
+// SigningKeys interface variable for models methods
+var SigningKeys SigningKeyAccessObject
+
+func init() {
+	SigningKeys = &SigningKeyConn{}
+}
+
+// SigningKeyModel ключ подписи access токенов. Закрытый ключ зашифрован
+// accessKeySecrets, открытый лежит как есть, чтобы JWKS отдавался,
+// даже если закрытый прочитать нельзя
+type SigningKeyModel struct {
+	KID        string
+	PublicKey  []byte // PKIX DER
+	PrivateKey []byte // SEC 1 DER, зашифрован
+	CreatedAt  time.Time
+}
+
+// List все ключи, от новых к старым
+func (sk *SigningKeyConn) List() ([]*SigningKeyModel, error) {
+	rows, err := pqConn.Query(`SELECT k.kid, k.public_key, k.private_key, k.created_at
+		FROM signing_keys k ORDER BY created_at DESC;`)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "signing keys get error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	keys := make([]*SigningKeyModel, 0)
+	for rows.Next() {
+		k := &SigningKeyModel{}
+		if err = rows.Scan(&k.KID, &k.PublicKey, &k.PrivateKey, &k.CreatedAt); err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "signing key scan error: %s", err.Error())
+		}
+		keys = append(keys, k)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "signing keys read error: %s", err.Error())
+	}
+
+	return keys, nil
+}
+
+// Create сохраняет новый ключ, CreatedAt заполняется базой
+func (sk *SigningKeyConn) Create(k *SigningKeyModel) error {
+	err := pqConn.QueryRow(`INSERT INTO signing_keys (kid, public_key, private_key)
+		VALUES ($1, $2, $3) RETURNING created_at;`,
+		k.KID, k.PublicKey, k.PrivateKey).Scan(&k.CreatedAt)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "signing key create error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteBefore удаляет ключи, созданные раньше before
+func (sk *SigningKeyConn) DeleteBefore(before time.Time) error {
+	_, err := pqConn.Exec(`DELETE FROM signing_keys WHERE created_at < $1;`, before)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "signing keys delete error: %s", err.Error())
+	}
+
+	return nil
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/HotCodeGroup/warscript-utils/testutils"
 	"github.com/HotCodeGroup/warscript-utils/utils"
-	"github.com/pkg/errors"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type usersTest struct {
-	ids   int64
-	users map[int64]UserModel
+	ids         int64
+	users       map[int64]UserModel
+	deactivated map[int64]time.Time
 
 	testutils.Failer
 }
@@ -41,18 +49,40 @@ func (u *usersTest) Save(m *UserModel) error {
 	return nil
 }
 
+// SetVkSecret заменяет хеш секрета для вк
+func (u *usersTest) SetVkSecret(id int64, hash []byte) error {
+	if err := u.NextFail(); err != nil {
+		return err
+	}
+
+	m, ok := u.users[id]
+	if !ok {
+		return utils.ErrNotExists
+	}
+	m.VkSecretHash = hash
+	u.users[id] = m
+
+	return nil
+}
+
 // CheckPassword проверяет пароль у юзера и сохранённый в модели
 func (u *usersTest) CheckPassword(m *UserModel, password string) bool {
 	return *m.Password == password
 }
 
-// GetUserByID получает юзера по id
+// GetUserBySecret получает юзера по секрету для вк
 func (u *usersTest) GetUserBySecret(s string) (*UserModel, error) {
 	if err := u.NextFail(); err != nil {
 		return nil, err
 	}
 
-	return nil, nil
+	for _, user := range u.users {
+		if bytes.Equal(user.VkSecretHash, hashVkSecret(s)) {
+			return &user, nil
+		}
+	}
+
+	return nil, utils.ErrNotExists
 }
 
 // GetUserByID получает юзера по id
@@ -69,6 +99,21 @@ func (u *usersTest) GetUserByID(id int64) (*UserModel, error) {
 	return &m, nil
 }
 
+// GetUserByEmail получает юзера по email
+func (u *usersTest) GetUserByEmail(email string) (*UserModel, error) {
+	if err := u.NextFail(); err != nil {
+		return nil, err
+	}
+
+	for _, user := range u.users {
+		if user.Email.Valid && strings.EqualFold(user.Email.String, email) {
+			return &user, nil
+		}
+	}
+
+	return nil, utils.ErrNotExists
+}
+
 // GetUserByUsername получает юзера по имени
 func (u *usersTest) GetUserByUsername(username string) (*UserModel, error) {
 	if err := u.NextFail(); err != nil {
@@ -112,8 +157,264 @@ func (u *usersTest) GetUsersByIDs(ids []int64) ([]*UserModel, error) {
 	return users, nil
 }
 
+// Deactivate выключает аккаунт
+func (u *usersTest) Deactivate(id int64) error {
+	if err := u.NextFail(); err != nil {
+		return err
+	}
+
+	m, ok := u.users[id]
+	if !ok || !m.Active {
+		return nil
+	}
+	m.Active = false
+	u.users[id] = m
+
+	if u.deactivated == nil {
+		u.deactivated = make(map[int64]time.Time)
+	}
+	u.deactivated[id] = time.Now()
+
+	return nil
+}
+
+// Reactivate включает аккаунт, выключенный позже since
+func (u *usersTest) Reactivate(id int64, since time.Time) (bool, error) {
+	if err := u.NextFail(); err != nil {
+		return false, err
+	}
+
+	at, ok := u.deactivated[id]
+	if !ok || !at.After(since) {
+		return false, nil
+	}
+
+	m := u.users[id]
+	m.Active = true
+	u.users[id] = m
+	delete(u.deactivated, id)
+
+	return true, nil
+}
+
+// Anonymize обезличивает аккаунты, выключенные раньше before
+func (u *usersTest) Anonymize(before time.Time) (int64, error) {
+	if err := u.NextFail(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for id, at := range u.deactivated {
+		if at.Before(before) {
+			u.users[id] = UserModel{ID: id, Username: "deleted#" + strconv.FormatInt(id, 10)}
+			delete(u.deactivated, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Search ищет по началу username
+func (u *usersTest) Search(query string, limit, offset int) ([]*UserModel, error) {
+	if err := u.NextFail(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0)
+	for id, user := range u.users {
+		if strconv.FormatInt(id, 10) == query || strings.HasPrefix(strings.ToLower(user.Username), strings.ToLower(query)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	users := make([]*UserModel, 0)
+	for i := offset; i < len(ids) && len(users) < limit; i++ {
+		user := u.users[ids[i]]
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// SetActive включает или выключает аккаунт
+func (u *usersTest) SetActive(id int64, active bool) error {
+	if err := u.NextFail(); err != nil {
+		return err
+	}
+
+	m, ok := u.users[id]
+	if !ok {
+		return utils.ErrNotExists
+	}
+	m.Active = active
+	u.users[id] = m
+	delete(u.deactivated, id)
+
+	return nil
+}
+
+type rolesTest struct {
+	roles  map[int64][]string
+	grants map[string][]string // права ролей, как в role_permissions
+
+	testutils.Failer
+}
+
+// List роли юзера
+func (rt *rolesTest) List(userID int64) ([]string, error) {
+	if err := rt.NextFail(); err != nil {
+		return nil, err
+	}
+
+	return append([]string{}, rt.roles[userID]...), nil
+}
+
+// Grant выдаёт роль
+func (rt *rolesTest) Grant(userID int64, role string) error {
+	if err := rt.NextFail(); err != nil {
+		return err
+	}
+
+	for _, r := range rt.roles[userID] {
+		if r == role {
+			return nil
+		}
+	}
+	rt.roles[userID] = append(rt.roles[userID], role)
+
+	return nil
+}
+
+// Revoke забирает роль
+func (rt *rolesTest) Revoke(userID int64, role string) error {
+	if err := rt.NextFail(); err != nil {
+		return err
+	}
+
+	roles := make([]string, 0)
+	for _, r := range rt.roles[userID] {
+		if r != role {
+			roles = append(roles, r)
+		}
+	}
+	rt.roles[userID] = roles
+
+	return nil
+}
+
+// Permissions права всех ролей юзера без повторов
+func (rt *rolesTest) Permissions(userID int64) ([]string, error) {
+	if err := rt.NextFail(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	perms := make([]string, 0)
+	for _, role := range rt.roles[userID] {
+		for _, p := range rt.grants[role] {
+			if !seen[p] {
+				seen[p] = true
+				perms = append(perms, p)
+			}
+		}
+	}
+	sort.Strings(perms)
+
+	return perms, nil
+}
+
+type auditEventsTest struct {
+	events []*AuditEvent
+
+	testutils.Failer
+}
+
+// Write запоминает событие
+func (at *auditEventsTest) Write(e *AuditEvent) error {
+	if err := at.NextFail(); err != nil {
+		return err
+	}
+
+	e.ID = int64(len(at.events)) + 1
+	e.CreatedAt = time.Now()
+	at.events = append(at.events, e)
+
+	return nil
+}
+
+// List события по фильтру от новых к старым
+func (at *auditEventsTest) List(f *AuditFilter) ([]*AuditEvent, error) {
+	if err := at.NextFail(); err != nil {
+		return nil, err
+	}
+
+	events := make([]*AuditEvent, 0)
+	for i := len(at.events) - 1; i >= 0 && len(events) < f.Limit; i-- {
+		e := at.events[i]
+		if (f.UserID != 0 && e.UserID.Int64 != f.UserID) ||
+			(f.ActorID != 0 && e.ActorID.Int64 != f.ActorID) ||
+			(f.Event != "" && e.Event != f.Event) ||
+			(f.IP != "" && e.IP != f.IP) ||
+			(!f.Since.IsZero() && e.CreatedAt.Before(f.Since)) ||
+			(!f.Until.IsZero() && !e.CreatedAt.Before(f.Until)) ||
+			(f.BeforeID != 0 && e.ID >= f.BeforeID) {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+type signingKeysTest struct {
+	keys []*SigningKeyModel // от новых к старым
+
+	testutils.Failer
+}
+
+// List все ключи, от новых к старым
+func (st *signingKeysTest) List() ([]*SigningKeyModel, error) {
+	if err := st.NextFail(); err != nil {
+		return nil, err
+	}
+
+	return append([]*SigningKeyModel{}, st.keys...), nil
+}
+
+// Create сохраняет новый ключ
+func (st *signingKeysTest) Create(k *SigningKeyModel) error {
+	if err := st.NextFail(); err != nil {
+		return err
+	}
+
+	k.CreatedAt = time.Now()
+	st.keys = append([]*SigningKeyModel{k}, st.keys...)
+
+	return nil
+}
+
+// DeleteBefore удаляет ключи, созданные раньше before
+func (st *signingKeysTest) DeleteBefore(before time.Time) error {
+	if err := st.NextFail(); err != nil {
+		return err
+	}
+
+	keys := make([]*SigningKeyModel, 0)
+	for _, k := range st.keys {
+		if !k.CreatedAt.Before(before) {
+			keys = append(keys, k)
+		}
+	}
+	st.keys = keys
+
+	return nil
+}
+
 type sessionsTest struct {
 	sessions map[string][]byte
+	owners   map[string]int64
 
 	testutils.Failer
 }
@@ -126,6 +427,39 @@ func (ss *sessionsTest) Set(s *Session) error {
 	}
 
 	ss.sessions[s.Token] = s.Payload
+	if s.UserID != 0 {
+		if ss.owners == nil {
+			ss.owners = make(map[string]int64)
+		}
+		ss.owners[s.Token] = s.UserID
+	}
+	return nil
+}
+
+// Update перезаписывает payload сессии
+func (ss *sessionsTest) Update(s *Session) error {
+	if err := ss.NextFail(); err != nil {
+		return err
+	}
+
+	if _, ok := ss.sessions[s.Token]; !ok {
+		return ErrSessionNotExists
+	}
+	ss.sessions[s.Token] = s.Payload
+
+	return nil
+}
+
+// Refresh продлевает сессию
+func (ss *sessionsTest) Refresh(s *Session) error {
+	if err := ss.NextFail(); err != nil {
+		return err
+	}
+
+	if _, ok := ss.sessions[s.Token]; !ok {
+		return ErrSessionNotExists
+	}
+
 	return nil
 }
 
@@ -135,6 +469,7 @@ func (ss *sessionsTest) Delete(s *Session) error {
 		return err
 	}
 	delete(ss.sessions, s.Token)
+	delete(ss.owners, s.Token)
 
 	return nil
 }
@@ -146,7 +481,7 @@ func (ss *sessionsTest) GetSession(token string) (*Session, error) {
 	}
 	data, ok := ss.sessions[token]
 	if !ok {
-		return nil, errors.Wrap(utils.ErrNotExists, "redis get error")
+		return nil, ErrSessionNotExists
 	}
 
 	return &Session{
@@ -154,3 +489,543 @@ func (ss *sessionsTest) GetSession(token string) (*Session, error) {
 		Payload: data,
 	}, nil
 }
+
+// BelongsTo принадлежит ли сессия юзеру
+func (ss *sessionsTest) BelongsTo(userID int64, token string) (bool, error) {
+	if err := ss.NextFail(); err != nil {
+		return false, err
+	}
+
+	owner, ok := ss.owners[token]
+	return ok && owner == userID, nil
+}
+
+// ListByUser получает все сессии юзера
+func (ss *sessionsTest) ListByUser(userID int64) ([]*Session, error) {
+	if err := ss.NextFail(); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0)
+	for token, owner := range ss.owners {
+		if owner == userID {
+			sessions = append(sessions, &Session{
+				Token:   token,
+				UserID:  owner,
+				Payload: ss.sessions[token],
+			})
+		}
+	}
+
+	return sessions, nil
+}
+
+// DeleteAllForUser удаляет все сессии юзера
+func (ss *sessionsTest) DeleteAllForUser(userID int64) error {
+	return ss.DeleteAllExcept(userID, "")
+}
+
+// DeleteAllExcept удаляет все сессии юзера, кроме token
+func (ss *sessionsTest) DeleteAllExcept(userID int64, token string) error {
+	if err := ss.NextFail(); err != nil {
+		return err
+	}
+
+	for t, owner := range ss.owners {
+		if owner == userID && t != token {
+			delete(ss.sessions, t)
+			delete(ss.owners, t)
+		}
+	}
+
+	return nil
+}
+
+type loginAttemptsTest struct {
+	failures map[string]int64
+	locks    map[string]time.Duration
+
+	testutils.Failer
+}
+
+// LockedFor самая долгая блокировка из subjects
+func (la *loginAttemptsTest) LockedFor(subjects ...string) (time.Duration, error) {
+	if err := la.NextFail(); err != nil {
+		return 0, err
+	}
+
+	var longest time.Duration
+	for _, s := range subjects {
+		if la.locks[s] > longest {
+			longest = la.locks[s]
+		}
+	}
+
+	return longest, nil
+}
+
+// Fail увеличивает счётчик неудач
+func (la *loginAttemptsTest) Fail(subject string, window time.Duration) (int64, error) {
+	if err := la.NextFail(); err != nil {
+		return 0, err
+	}
+
+	la.failures[subject]++
+	return la.failures[subject], nil
+}
+
+// Lock блокирует вход для subject
+func (la *loginAttemptsTest) Lock(subject string, d time.Duration) error {
+	if err := la.NextFail(); err != nil {
+		return err
+	}
+
+	la.locks[subject] = d
+	return nil
+}
+
+// Reset сбрасывает счётчик и блокировку
+func (la *loginAttemptsTest) Reset(subject string) error {
+	if err := la.NextFail(); err != nil {
+		return err
+	}
+
+	delete(la.failures, subject)
+	delete(la.locks, subject)
+	return nil
+}
+
+type rateLimitsTest struct {
+	hits map[string]int64
+
+	testutils.Failer
+}
+
+// Hit считает запросы, окно никогда не заканчивается
+func (rl *rateLimitsTest) Hit(key string, window time.Duration) (int64, time.Duration, error) {
+	if err := rl.NextFail(); err != nil {
+		return 0, 0, err
+	}
+
+	rl.hits[key]++
+	return rl.hits[key], window, nil
+}
+
+type passwordResetsTest struct {
+	tokens map[string]int64
+	latest map[int64]string
+
+	testutils.Failer
+}
+
+// Create выдаёт токен, предыдущий токен юзера удаляется
+func (pr *passwordResetsTest) Create(userID int64, ttl time.Duration) (string, error) {
+	if err := pr.NextFail(); err != nil {
+		return "", err
+	}
+
+	delete(pr.tokens, pr.latest[userID])
+	token := uuid.New().String()
+	pr.tokens[token] = userID
+	pr.latest[userID] = token
+
+	return token, nil
+}
+
+// Get юзер токена
+func (pr *passwordResetsTest) Get(token string) (int64, error) {
+	if err := pr.NextFail(); err != nil {
+		return 0, err
+	}
+
+	id, ok := pr.tokens[token]
+	if !ok {
+		return 0, ErrTokenInvalid
+	}
+
+	return id, nil
+}
+
+// Consume тратит токен
+func (pr *passwordResetsTest) Consume(token string) (int64, error) {
+	if err := pr.NextFail(); err != nil {
+		return 0, err
+	}
+
+	id, ok := pr.tokens[token]
+	if !ok {
+		return 0, ErrTokenInvalid
+	}
+	delete(pr.tokens, token)
+
+	return id, nil
+}
+
+type twoFactorsTest struct {
+	settings map[int64]TwoFactorModel
+	steps    map[int64]int64
+
+	testutils.Failer
+}
+
+// Get настройки 2FA юзера
+func (tf *twoFactorsTest) Get(userID int64) (*TwoFactorModel, error) {
+	if err := tf.NextFail(); err != nil {
+		return nil, err
+	}
+
+	m, ok := tf.settings[userID]
+	if !ok {
+		return nil, utils.ErrNotExists
+	}
+
+	return &m, nil
+}
+
+// Save перезаписывает настройки 2FA
+func (tf *twoFactorsTest) Save(m *TwoFactorModel) error {
+	if err := tf.NextFail(); err != nil {
+		return err
+	}
+
+	tf.settings[m.UserID] = *m
+	return nil
+}
+
+// Delete отключает 2FA
+func (tf *twoFactorsTest) Delete(userID int64) error {
+	if err := tf.NextFail(); err != nil {
+		return err
+	}
+
+	delete(tf.settings, userID)
+	return nil
+}
+
+// UseStep шаги только растут
+func (tf *twoFactorsTest) UseStep(userID, step int64) (bool, error) {
+	if err := tf.NextFail(); err != nil {
+		return false, err
+	}
+
+	if tf.steps[userID] >= step {
+		return false, nil
+	}
+	tf.steps[userID] = step
+
+	return true, nil
+}
+
+// UseRecoveryCode удаляет код
+func (tf *twoFactorsTest) UseRecoveryCode(userID int64, hash string) (bool, error) {
+	if err := tf.NextFail(); err != nil {
+		return false, err
+	}
+
+	m, ok := tf.settings[userID]
+	if !ok || !m.Enabled {
+		return false, nil
+	}
+
+	for i, h := range m.RecoveryCodes {
+		if h == hash {
+			m.RecoveryCodes = append(m.RecoveryCodes[:i:i], m.RecoveryCodes[i+1:]...)
+			tf.settings[userID] = m
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type mailerTest struct {
+	sent []*Mail
+
+	testutils.Failer
+}
+
+// Send запоминает письмо
+func (m *mailerTest) Send(mail *Mail) error {
+	if err := m.NextFail(); err != nil {
+		return err
+	}
+
+	m.sent = append(m.sent, mail)
+	return nil
+}
+
+type identitiesTest struct {
+	identities []*IdentityModel
+
+	testutils.Failer
+}
+
+// Get юзер, к которому привязан аккаунт провайдера
+func (it *identitiesTest) Get(provider, subject string) (*IdentityModel, error) {
+	if err := it.NextFail(); err != nil {
+		return nil, err
+	}
+
+	for _, i := range it.identities {
+		if i.Provider == provider && i.Subject == subject {
+			c := *i
+			return &c, nil
+		}
+	}
+
+	return nil, utils.ErrNotExists
+}
+
+// ListForUser привязанные аккаунты юзера, от старых к новым
+func (it *identitiesTest) ListForUser(userID int64) ([]*IdentityModel, error) {
+	if err := it.NextFail(); err != nil {
+		return nil, err
+	}
+
+	identities := make([]*IdentityModel, 0)
+	for _, i := range it.identities {
+		if i.UserID == userID {
+			c := *i
+			identities = append(identities, &c)
+		}
+	}
+
+	return identities, nil
+}
+
+// Create привязывает аккаунт
+func (it *identitiesTest) Create(i *IdentityModel) error {
+	if err := it.NextFail(); err != nil {
+		return err
+	}
+
+	for _, e := range it.identities {
+		if e.Provider == i.Provider && (e.Subject == i.Subject || e.UserID == i.UserID) {
+			return utils.ErrTaken
+		}
+	}
+
+	i.CreatedAt = time.Now()
+	c := *i
+	it.identities = append(it.identities, &c)
+
+	return nil
+}
+
+// Delete отвязывает аккаунт провайдера
+func (it *identitiesTest) Delete(userID int64, provider string) error {
+	if err := it.NextFail(); err != nil {
+		return err
+	}
+
+	for n, i := range it.identities {
+		if i.UserID == userID && i.Provider == provider {
+			it.identities = append(it.identities[:n], it.identities[n+1:]...)
+			return nil
+		}
+	}
+
+	return utils.ErrNotExists
+}
+
+type oauthStatesTest struct {
+	states map[string]*OAuthState
+
+	testutils.Failer
+}
+
+// Create сохраняет данные входа
+func (st *oauthStatesTest) Create(s *OAuthState, ttl time.Duration) (string, error) {
+	if err := st.NextFail(); err != nil {
+		return "", err
+	}
+
+	state := uuid.New().String()
+	c := *s
+	st.states[state] = &c
+
+	return state, nil
+}
+
+// Consume тратит state
+func (st *oauthStatesTest) Consume(state string) (*OAuthState, error) {
+	if err := st.NextFail(); err != nil {
+		return nil, err
+	}
+
+	s, ok := st.states[state]
+	if !ok {
+		return nil, ErrTokenInvalid
+	}
+	delete(st.states, state)
+
+	return s, nil
+}
+
+// FakeOAuthProvider провайдер без сети: юзер "входит" через Authorize,
+// а Exchange проверяет PKCE так же, как настоящий провайдер
+type FakeOAuthProvider struct {
+	codes map[string]fakeOAuthGrant
+
+	testutils.Failer
+}
+
+type fakeOAuthGrant struct {
+	challenge string
+	identity  ExternalIdentity
+}
+
+// NewFakeOAuthProvider создаёт пустой провайдер
+func NewFakeOAuthProvider() *FakeOAuthProvider {
+	return &FakeOAuthProvider{codes: make(map[string]fakeOAuthGrant)}
+}
+
+// AuthURL адрес с state и challenge, их достаёт Authorize
+func (p *FakeOAuthProvider) AuthURL(state, challenge string) string {
+	return "https://oauth.test/authorize?state=" + state + "&code_challenge=" + challenge
+}
+
+// Authorize выдаёт код за юзера identity, как будто тот вошёл у провайдера
+// по адресу authURL. Возвращает код и state, с которыми юзер вернётся
+func (p *FakeOAuthProvider) Authorize(authURL string, identity ExternalIdentity) (string, string) {
+	var state, challenge string
+	for _, param := range strings.Split(authURL[strings.Index(authURL, "?")+1:], "&") {
+		kv := strings.SplitN(param, "=", 2)
+		switch kv[0] {
+		case "state":
+			state = kv[1]
+		case "code_challenge":
+			challenge = kv[1]
+		}
+	}
+
+	code := uuid.New().String()
+	p.codes[code] = fakeOAuthGrant{challenge: challenge, identity: identity}
+
+	return code, state
+}
+
+// Exchange код одноразовый и подходит только к verifier, из которого сделан challenge
+func (p *FakeOAuthProvider) Exchange(code, verifier string) (*ExternalIdentity, error) {
+	if err := p.NextFail(); err != nil {
+		return nil, err
+	}
+
+	grant, ok := p.codes[code]
+	if !ok || pkceChallenge(verifier) != grant.challenge {
+		return nil, ErrOAuthRejected
+	}
+	delete(p.codes, code)
+
+	identity := grant.identity
+	return &identity, nil
+}
+
+type personalTokensTest struct {
+	tokens []*PersonalTokenModel
+	nextID int64
+
+	testutils.Failer
+}
+
+// Create сохраняет токен
+func (pt *personalTokensTest) Create(t *PersonalTokenModel) error {
+	if err := pt.NextFail(); err != nil {
+		return err
+	}
+
+	for _, e := range pt.tokens {
+		if bytes.Equal(e.Hash, t.Hash) {
+			return utils.ErrTaken
+		}
+	}
+
+	pt.nextID++
+	t.ID = pt.nextID
+	t.CreatedAt = time.Now()
+	c := *t
+	pt.tokens = append(pt.tokens, &c)
+
+	return nil
+}
+
+// GetByHash токен по хешу секрета
+func (pt *personalTokensTest) GetByHash(hash []byte) (*PersonalTokenModel, error) {
+	if err := pt.NextFail(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range pt.tokens {
+		if bytes.Equal(t.Hash, hash) {
+			c := *t
+			return &c, nil
+		}
+	}
+
+	return nil, utils.ErrNotExists
+}
+
+// ListForUser токены юзера, от новых к старым
+func (pt *personalTokensTest) ListForUser(userID int64) ([]*PersonalTokenModel, error) {
+	if err := pt.NextFail(); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*PersonalTokenModel, 0)
+	for i := len(pt.tokens) - 1; i >= 0; i-- {
+		if pt.tokens[i].UserID == userID {
+			c := *pt.tokens[i]
+			tokens = append(tokens, &c)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Delete отзывает токен юзера
+func (pt *personalTokensTest) Delete(userID, id int64) error {
+	if err := pt.NextFail(); err != nil {
+		return err
+	}
+
+	for n, t := range pt.tokens {
+		if t.UserID == userID && t.ID == id {
+			pt.tokens = append(pt.tokens[:n], pt.tokens[n+1:]...)
+			return nil
+		}
+	}
+
+	return utils.ErrNotExists
+}
+
+// DeleteAllForUser отзывает все токены юзера
+func (pt *personalTokensTest) DeleteAllForUser(userID int64) error {
+	if err := pt.NextFail(); err != nil {
+		return err
+	}
+
+	kept := pt.tokens[:0]
+	for _, t := range pt.tokens {
+		if t.UserID != userID {
+			kept = append(kept, t)
+		}
+	}
+	pt.tokens = kept
+
+	return nil
+}
+
+// Touch запоминает время использования
+func (pt *personalTokensTest) Touch(id int64, at time.Time) error {
+	if err := pt.NextFail(); err != nil {
+		return err
+	}
+
+	for _, t := range pt.tokens {
+		if t.ID == id {
+			t.LastUsedAt = pq.NullTime{Time: at, Valid: true}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint: gosec RFC 6238 и все приложения-аутентификаторы используют SHA1
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Параметры TOTP (RFC 6238). Их понимают все приложения-аутентификаторы,
+// так что настраивать их незачем
+const (
+	totpDigits     = 6
+	totpPeriod     = 30 * time.Second
+	totpSkew       = 1 // сколько соседних шагов принимаем из-за расхождения часов
+	totpSecretSize = 20
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newTOTPSecret генерирует случайный секрет
+func newTOTPSecret() ([]byte, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "can not generate totp secret")
+	}
+
+	return secret, nil
+}
+
+// totpStep номер 30-секундного шага для момента t
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// hotp код для шага step (RFC 4226)
+func hotp(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	h := hmac.New(sha1.New, secret)
+	h.Write(msg[:]) //nolint: errcheck
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, code%mod)
+}
+
+// matchTOTP ищет шаг, которому соответствует code, в окне ±totpSkew от now.
+// Возвращает шаг, чтобы один и тот же код нельзя было использовать дважды
+func matchTOTP(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpURI ссылка otpauth://, которую приложение-аутентификатор читает из QR-кода
+func totpURI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", totpEncoding.EncodeToString(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(int(totpPeriod/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+// SecretBox шифрует секреты перед записью в базу AES-256-GCM,
+// чтобы дамп базы не раздавал вторые факторы
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// errNoSecretKey ключ шифрования не задан: без него секреты не шифруются и не расшифровываются
+var errNoSecretKey = errors.New("secret key is not configured")
+
+// totpSecrets шифровальщик секретов TOTP. Ключ задаётся в main,
+// до этого включить 2FA или войти с ней нельзя
+var totpSecrets = &SecretBox{}
+
+// NewSecretBox создаёт шифровальщик с ключом из key. Случайный ключ не подставляется:
+// после перезапуска им нельзя было бы расшифровать то, что уже лежит в базе
+func NewSecretBox(key string) (*SecretBox, error) {
+	if key == "" {
+		return nil, errNoSecretKey
+	}
+
+	k := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(k[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not create gcm")
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal шифрует plain, nonce кладётся в начало результата
+func (b *SecretBox) Seal(plain []byte) ([]byte, error) {
+	if b.aead == nil {
+		return nil, errNoSecretKey
+	}
+
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "can not generate nonce")
+	}
+
+	return b.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+// Open расшифровывает то, что зашифровал Seal с тем же ключом
+func (b *SecretBox) Open(sealed []byte) ([]byte, error) {
+	if b.aead == nil {
+		return nil, errNoSecretKey
+	}
+
+	size := b.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, errors.New("sealed data is too short")
+	}
+
+	plain, err := b.aead.Open(nil, sealed[:size], sealed[size:], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not open sealed data")
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 6238, приложение B, последние 6 цифр
+	secret := []byte("12345678901234567890")
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, c := range cases {
+		if code := hotp(secret, totpStep(time.Unix(c.unix, 0))); code != c.code {
+			t.Errorf("TestHOTPVectors got unexpected code at %d: %s, expected: %s", c.unix, code, c.code)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := totpStep(now)
+
+	cases := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{"current", hotp(secret, current), current, true},
+		{"previous", hotp(secret, current-1), current - 1, true},
+		{"next", hotp(secret, current+1), current + 1, true},
+		{"too old", hotp(secret, current-2), 0, false},
+		{"short", "12345", 0, false},
+	}
+
+	for _, c := range cases {
+		step, ok := matchTOTP(secret, c.code, now)
+		if ok != c.ok || step != c.step {
+			t.Errorf("TestMatchTOTP %s got unexpected result: %d, %t, expected: %d, %t",
+				c.name, step, ok, c.step, c.ok)
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(totpURI("Warscript", "golang", []byte("12345678901234567890")))
+	if err != nil {
+		t.Fatalf("TestTOTPURI got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Warscript:golang" {
+		t.Errorf("TestTOTPURI got unexpected uri: %s", u)
+	}
+	if s := u.Query().Get("secret"); s != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("TestTOTPURI got unexpected secret: %s", s)
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	box := newTestSecretBox("kek")
+	sealed, err := box.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatalf("TestSecretBox got unexpected error: %v, expected: %v", err, nil)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Errorf("TestSecretBox plain text leaked: %v", sealed)
+	}
+
+	plain, err := newTestSecretBox("kek").Open(sealed)
+	if err != nil || string(plain) != "secret" {
+		t.Errorf("TestSecretBox got unexpected result: %s, %v, expected: %s", plain, err, "secret")
+	}
+
+	if _, err = newTestSecretBox("lol").Open(sealed); err == nil {
+		t.Errorf("TestSecretBox opened with another key")
+	}
+	if _, err = box.Open([]byte{1, 2}); err == nil {
+		t.Errorf("TestSecretBox opened short data")
+	}
+
+	if _, err = NewSecretBox(""); err != errNoSecretKey {
+		t.Errorf("TestSecretBox got unexpected error: %v, expected: %v", err, errNoSecretKey)
+	}
+	if _, err = (&SecretBox{}).Seal([]byte("secret")); err != errNoSecretKey {
+		t.Errorf("TestSecretBox got unexpected error: %v, expected: %v", err, errNoSecretKey)
+	}
+}
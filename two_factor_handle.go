@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+// EnrollTwoFactor начинает подключение 2FA, отдаёт секрет и otpauth URI
+func EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "EnrollTwoFactor")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	enrollment, err := enrollTwoFactorImpl(info)
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor включает 2FA по первому коду из приложения, отдаёт коды восстановления
+func ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "ConfirmTwoFactor")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	form := &jmodels.FormTwoFactorCode{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	codes, err := confirmTwoFactorImpl(info, form, newClientInfo(r))
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, codes)
+}
+
+// DisableTwoFactor отключает 2FA, нужен пароль
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "DisableTwoFactor")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	form := &jmodels.FormTwoFactorDisable{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = disableTwoFactorImpl(info, form, newClientInfo(r)); err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// CreateSessionTwoFactor второй шаг входа: challenge из CreateSession и код
+func CreateSessionTwoFactor(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "CreateSessionTwoFactor")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormTwoFactorLogin{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	session, err := completeTwoFactorLoginImpl(form, newClientInfo(r))
+	if err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+			return
+		}
+
+		if errors.Cause(err) == ErrTokenInvalid {
+			errWriter.WriteValidationError(&utils.ValidationError{
+				"challenge": utils.ErrInvalid.Error(),
+			})
+			return
+		}
+
+		if lockErr, ok := err.(*LoginLockedError); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(lockErr.RetryAfterSeconds(), 10))
+			errWriter.WriteWarn(http.StatusTooManyRequests, lockErr)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	setSessionCookie(w, session.Token, form.Remember)
+	w.WriteHeader(http.StatusOK)
+}
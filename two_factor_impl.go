@@ -0,0 +1,296 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// twoFactorChallengeTTL сколько есть времени, чтобы ввести код после пароля
+	twoFactorChallengeTTL = 5 * time.Minute
+	// recoveryCodesCount сколько кодов восстановления выдаём при включении 2FA
+	recoveryCodesCount = 10
+)
+
+// totpIssuer название сервиса в приложении-аутентификаторе
+var totpIssuer = "Warscript"
+
+// TwoFactorRequiredError пароль верный, но для входа нужен второй фактор.
+// Challenge подтверждает первый шаг и передаётся вместе с кодом
+type TwoFactorRequiredError struct {
+	Challenge string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "second factor is required"
+}
+
+// passwordFingerprint привязывает challenge к паролю: после смены
+// пароля challenge, полученный со старым, не подойдёт
+func passwordFingerprint(user *UserModel) string {
+	sum := sha256.Sum256(user.PasswordCrypt)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// normalizeRecoveryCode коды можно вводить в любом регистре, с дефисом и без
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRecoveryCodes генерирует коды вида xxxxx-xxxxx и их хеши для базы.
+// В кодах 50 случайных бит, так что быстрого хеша достаточно
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, errors.Wrap(err, "can not generate recovery code")
+		}
+
+		code := strings.ToLower(totpEncoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// twoFactorEnabledImpl включена ли у юзера 2FA
+func twoFactorEnabledImpl(userID int64) (bool, error) {
+	tf, err := TwoFactors.Get(userID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "get totp error")
+	}
+
+	return tf.Enabled, nil
+}
+
+// enrollTwoFactorImpl начинает подключение 2FA: выдаёт новый секрет.
+// Пока он не подтверждён кодом, вход работает по-старому
+func enrollTwoFactorImpl(info *models.SessionPayload) (*jmodels.TwoFactorEnrollment, error) {
+	user, err := Users.GetUserByID(info.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get user error")
+	}
+
+	enabled, err := twoFactorEnabledImpl(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, &utils.ValidationError{
+			"2fa": "already_enabled",
+		}
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := totpSecrets.Seal(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "totp secret seal error")
+	}
+
+	if err = TwoFactors.Save(&TwoFactorModel{UserID: user.ID, Secret: sealed}); err != nil {
+		return nil, errors.Wrap(err, "totp save error")
+	}
+
+	return &jmodels.TwoFactorEnrollment{
+		Secret: totpEncoding.EncodeToString(secret),
+		URI:    totpURI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// confirmTwoFactorImpl включает 2FA, если юзер ввёл верный код из приложения,
+// и выдаёт коды восстановления
+func confirmTwoFactorImpl(info *models.SessionPayload, form *jmodels.FormTwoFactorCode,
+	client *clientInfo) (*jmodels.RecoveryCodes, error) {
+	tf, err := TwoFactors.Get(info.ID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return nil, &utils.ValidationError{
+				"2fa": "not_enrolled",
+			}
+		}
+		return nil, errors.Wrap(err, "get totp error")
+	}
+	if tf.Enabled {
+		return nil, &utils.ValidationError{
+			"2fa": "already_enabled",
+		}
+	}
+
+	secret, err := totpSecrets.Open(tf.Secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "totp secret open error")
+	}
+
+	step, ok := matchTOTP(secret, strings.TrimSpace(form.Code), time.Now())
+	if !ok {
+		return nil, &utils.ValidationError{
+			"code": utils.ErrInvalid.Error(),
+		}
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tf.Enabled = true
+	tf.RecoveryCodes = hashes
+	if err = TwoFactors.Save(tf); err != nil {
+		return nil, errors.Wrap(err, "totp save error")
+	}
+	auditUserImpl(AuditTwoFactorOn, tf.UserID, client, nil)
+
+	// этим кодом уже воспользовались, войти с ним нельзя
+	if _, err = TwoFactors.UseStep(tf.UserID, step); err != nil {
+		logger.Warnf("can not mark totp step used: %s", err)
+	}
+
+	return &jmodels.RecoveryCodes{Codes: codes}, nil
+}
+
+// disableTwoFactorImpl отключает 2FA, если юзер подтвердил пароль
+func disableTwoFactorImpl(info *models.SessionPayload, form *jmodels.FormTwoFactorDisable, client *clientInfo) error {
+	user, err := Users.GetUserByID(info.ID)
+	if err != nil {
+		return errors.Wrap(err, "get user error")
+	}
+
+	if form.Password == "" {
+		return &utils.ValidationError{
+			"password": utils.ErrRequired.Error(),
+		}
+	}
+	if !Users.CheckPassword(user, form.Password) {
+		return &utils.ValidationError{
+			"password": utils.ErrInvalid.Error(),
+		}
+	}
+
+	if err = TwoFactors.Delete(user.ID); err != nil {
+		return errors.Wrap(err, "totp delete error")
+	}
+	auditUserImpl(AuditTwoFactorOff, user.ID, client, nil)
+
+	return nil
+}
+
+// requireSecondFactorImpl выдаёт challenge, если у юзера включена 2FA
+func requireSecondFactorImpl(user *UserModel) error {
+	enabled, err := twoFactorEnabledImpl(user.ID)
+	if err != nil || !enabled {
+		return err
+	}
+
+	challenge, err := tokenSigner.Sign(TokenPurposeLogin2FA, user.ID, passwordFingerprint(user), twoFactorChallengeTTL)
+	if err != nil {
+		return errors.Wrap(err, "challenge sign error")
+	}
+
+	return &TwoFactorRequiredError{Challenge: challenge}
+}
+
+// checkSecondFactorImpl проверяет код из приложения или код восстановления.
+// Оба одноразовые
+func checkSecondFactorImpl(userID int64, form *jmodels.FormTwoFactorLogin) (bool, error) {
+	if form.RecoveryCode != "" {
+		return TwoFactors.UseRecoveryCode(userID, hashRecoveryCode(form.RecoveryCode))
+	}
+
+	tf, err := TwoFactors.Get(userID)
+	if err != nil {
+		return false, errors.Wrap(err, "get totp error")
+	}
+	if !tf.Enabled {
+		return false, nil
+	}
+
+	secret, err := totpSecrets.Open(tf.Secret)
+	if err != nil {
+		return false, errors.Wrap(err, "totp secret open error")
+	}
+
+	step, ok := matchTOTP(secret, strings.TrimSpace(form.Code), time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return TwoFactors.UseStep(userID, step)
+}
+
+// completeTwoFactorLoginImpl второй шаг входа. Неверные коды считаются
+// неудачными входами, так что перебор упирается в ту же блокировку, что и пароль
+func completeTwoFactorLoginImpl(form *jmodels.FormTwoFactorLogin, client *clientInfo) (*Session, error) {
+	if form.Code == "" && form.RecoveryCode == "" {
+		return nil, &utils.ValidationError{
+			"code": utils.ErrRequired.Error(),
+		}
+	}
+
+	claims, err := tokenSigner.Verify(TokenPurposeLogin2FA, form.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := Users.GetUserByID(claims.UserID)
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			return nil, ErrTokenInvalid
+		}
+		return nil, errors.Wrap(err, "get user error")
+	}
+	if passwordFingerprint(user) != claims.Binding {
+		return nil, ErrTokenInvalid
+	}
+
+	if err = checkLoginLockImpl(loginUserSubject(user.ID), client.IP); err != nil {
+		return nil, err
+	}
+
+	ok, err := checkSecondFactorImpl(user.ID, form)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err = loginFailedImpl(loginUserSubject(user.ID), client.IP); err != nil {
+			return nil, err
+		}
+
+		field := "code"
+		if form.RecoveryCode != "" {
+			field = "recovery_code"
+		}
+		auditUserImpl(AuditLoginFailed, user.ID, client, map[string]string{"reason": field})
+		return nil, &utils.ValidationError{
+			field: utils.ErrInvalid.Error(),
+		}
+	}
+
+	if err = loginSucceededImpl(user.ID); err != nil {
+		logger.Warnf("can not reset login failures: %s", err)
+	}
+
+	return startSessionImpl(user, form.Remember, client)
+}
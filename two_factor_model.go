@@ -0,0 +1,113 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/HotCodeGroup/warscript-utils/utils"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// TwoFactorAccessObject DAO for TwoFactorModel
+type TwoFactorAccessObject interface {
+	Get(userID int64) (*TwoFactorModel, error)
+	Save(m *TwoFactorModel) error
+	Delete(userID int64) error
+
+	// UseStep отмечает шаг TOTP использованным. false, если этот
+	// или более поздний шаг уже использовали
+	UseStep(userID, step int64) (bool, error)
+	// UseRecoveryCode удаляет код восстановления по хешу. false, если такого кода нет
+	UseRecoveryCode(userID int64, hash string) (bool, error)
+}
+
+// TwoFactorConn implementation of TwoFactorAccessObject
+type TwoFactorConn struct{}
+
+// TwoFactors interface variable for models methods
+var TwoFactors TwoFactorAccessObject
+
+func init() {
+	TwoFactors = &TwoFactorConn{}
+}
+
+// TwoFactorModel model for user_totp table. Пока Enabled false,
+// юзер только начал подключение и на вход это не влияет
+type TwoFactorModel struct {
+	UserID        int64
+	Secret        []byte // зашифрован totpSecrets
+	Enabled       bool
+	RecoveryCodes []string // sha256 кодов восстановления
+}
+
+// Get получает настройки 2FA юзера
+func (tf *TwoFactorConn) Get(userID int64) (*TwoFactorModel, error) {
+	m := &TwoFactorModel{}
+	row := pqConn.QueryRow(`SELECT t.user_id, t.secret, t.enabled, t.recovery_codes
+						FROM user_totp t WHERE user_id = $1;`, userID)
+	err := row.Scan(&m.UserID, &m.Secret, &m.Enabled, pq.Array(&m.RecoveryCodes))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrNotExists
+		}
+
+		return nil, errors.Wrapf(utils.ErrInternal, "totp get error: %s", err.Error())
+	}
+
+	return m, nil
+}
+
+// Save создаёт или перезаписывает настройки 2FA юзера
+func (tf *TwoFactorConn) Save(m *TwoFactorModel) error {
+	_, err := pqConn.Exec(`INSERT INTO user_totp (user_id, secret, enabled, recovery_codes)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET (secret, enabled, recovery_codes) =
+		(EXCLUDED.secret, EXCLUDED.enabled, EXCLUDED.recovery_codes);`,
+		m.UserID, m.Secret, m.Enabled, pq.Array(m.RecoveryCodes))
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "totp save error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Delete отключает 2FA юзера
+func (tf *TwoFactorConn) Delete(userID int64) error {
+	_, err := pqConn.Exec(`DELETE FROM user_totp WHERE user_id = $1;`, userID)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "totp delete error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// UseStep шаги только растут, поэтому перехваченный код не сработает второй раз
+func (tf *TwoFactorConn) UseStep(userID, step int64) (bool, error) {
+	res, err := pqConn.Exec(`UPDATE user_totp SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2;`, userID, step)
+	if err != nil {
+		return false, errors.Wrapf(utils.ErrInternal, "totp use step error: %s", err.Error())
+	}
+
+	return affectedOne(res)
+}
+
+// UseRecoveryCode удаляет код одним запросом, так что два входа с одним кодом не пройдут
+func (tf *TwoFactorConn) UseRecoveryCode(userID int64, hash string) (bool, error) {
+	res, err := pqConn.Exec(`UPDATE user_totp SET recovery_codes = array_remove(recovery_codes, $2)
+		WHERE user_id = $1 AND enabled AND $2 = ANY(recovery_codes);`, userID, hash)
+	if err != nil {
+		return false, errors.Wrapf(utils.ErrInternal, "totp use recovery code error: %s", err.Error())
+	}
+
+	return affectedOne(res)
+}
+
+func affectedOne(res sql.Result) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.Wrapf(utils.ErrInternal, "rows affected error: %s", err.Error())
+	}
+
+	return n == 1, nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/testutils"
+)
+
+// twoFactorRequest вызывает обработчик с телом payload от имени юзера 1
+func twoFactorRequest(handler http.HandlerFunc, payload string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(payload)))
+	r = r.WithContext(context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+//nolint: gocyclo
+func TestTwoFactorFlow(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true,
+		PasswordCrypt: []byte("hash")}
+
+	// подключение
+	w := twoFactorRequest(EnrollTwoFactor, ``)
+	if w.Code != http.StatusOK {
+		t.Fatalf("TestTwoFactorFlow got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	enrollment := &jmodels.TwoFactorEnrollment{}
+	if err := json.Unmarshal(w.Body.Bytes(), enrollment); err != nil {
+		t.Fatalf("TestTwoFactorFlow got unexpected error: %v, expected: %v", err, nil)
+	}
+	secret, err := totpEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatalf("TestTwoFactorFlow got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	// пока 2FA не подтверждена, вход по паролю
+	form := &jmodels.FormUser{BasicUser: jmodels.BasicUser{Username: "golang"}, Password: pass}
+	if _, err = createSessionImpl(form, &clientInfo{}); err != nil {
+		t.Errorf("TestTwoFactorFlow got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	if w = twoFactorRequest(ConfirmTwoFactor, `{"code":"000000"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("TestTwoFactorFlow got unexpected code: %d, expected: %d", w.Code, http.StatusBadRequest)
+	}
+
+	now := time.Now()
+	w = twoFactorRequest(ConfirmTwoFactor, `{"code":"`+hotp(secret, totpStep(now))+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("TestTwoFactorFlow got unexpected code: %d, expected: %d", w.Code, http.StatusOK)
+	}
+	codes := &jmodels.RecoveryCodes{}
+	if err = json.Unmarshal(w.Body.Bytes(), codes); err != nil || len(codes.Codes) != recoveryCodesCount {
+		t.Fatalf("TestTwoFactorFlow got unexpected recovery codes: %v, %v", codes, err)
+	}
+
+	if w = twoFactorRequest(EnrollTwoFactor, ``); w.Code != http.StatusBadRequest {
+		t.Errorf("TestTwoFactorFlow got unexpected code: %d, expected: %d", w.Code, http.StatusBadRequest)
+	}
+
+	// теперь пароль даёт только challenge
+	login := func() string {
+		_, err := createSessionImpl(form, &clientInfo{})
+		tfErr, ok := err.(*TwoFactorRequiredError)
+		if !ok {
+			t.Fatalf("TestTwoFactorFlow got unexpected error: %v, expected: %v", err, &TwoFactorRequiredError{})
+		}
+		return tfErr.Challenge
+	}
+	challenge := login()
+
+	second := func(payload string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/sessions/2fa", bytes.NewReader([]byte(payload)))
+		w := httptest.NewRecorder()
+		CreateSessionTwoFactor(w, r)
+		return w
+	}
+
+	cases := []struct {
+		name    string
+		payload string
+		code    int
+		cookie  bool
+	}{
+		{"no code", `{"challenge":"` + challenge + `"}`, http.StatusBadRequest, false},
+		{"bad challenge", `{"challenge":"kek.lol","code":"123456"}`, http.StatusBadRequest, false},
+		{"bad code", `{"challenge":"` + challenge + `","code":"000000"}`, http.StatusBadRequest, false},
+		// этим кодом подтверждали подключение
+		{"used code", `{"challenge":"` + challenge + `","code":"` + hotp(secret, totpStep(now)) + `"}`,
+			http.StatusBadRequest, false},
+		{"next code", `{"challenge":"` + challenge + `","code":"` + hotp(secret, totpStep(now)+1) + `"}`,
+			http.StatusOK, true},
+		{"recovery code", `{"challenge":"` + challenge + `","recovery_code":"` + codes.Codes[0] + `"}`,
+			http.StatusOK, true},
+		{"used recovery code", `{"challenge":"` + challenge + `","recovery_code":"` + codes.Codes[0] + `"}`,
+			http.StatusBadRequest, false},
+	}
+	for _, c := range cases {
+		w = second(c.payload)
+		if w.Code != c.code || (len(w.Result().Cookies()) != 0) != c.cookie {
+			t.Errorf("TestTwoFactorFlow %s got unexpected response: %d %s, expected: %d",
+				c.name, w.Code, w.Body.String(), c.code)
+		}
+	}
+
+	// после смены пароля старый challenge не подходит
+	user := Users.(*usersTest).users[1]
+	user.PasswordCrypt = []byte("new hash")
+	Users.(*usersTest).users[1] = user
+	if w = second(`{"challenge":"` + challenge + `","recovery_code":"` + codes.Codes[1] + `"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("TestTwoFactorFlow got unexpected code: %d, expected: %d", w.Code, http.StatusBadRequest)
+	}
+
+	// отключение только с паролем
+	cases2 := []*UserTestCase{
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"password":"kek"}`),
+				ExpectedCode: 400,
+				ExpectedBody: `{"password":"invalid"}`,
+				Method:       "DELETE",
+				Pattern:      "/users/2fa",
+				Function:     DisableTwoFactor,
+				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+			},
+		},
+		{
+			Case: testutils.Case{
+				Payload:      []byte(`{"password":"golang4ever"}`),
+				ExpectedCode: 200,
+				ExpectedBody: ``,
+				Method:       "DELETE",
+				Pattern:      "/users/2fa",
+				Function:     DisableTwoFactor,
+				Context:      context.WithValue(context.Background(), middlewares.SessionInfoKey, &models.SessionPayload{ID: 1}),
+			},
+		},
+	}
+	runTableAPITests(t, cases2)
+
+	if _, err = createSessionImpl(form, &clientInfo{}); err != nil {
+		t.Errorf("TestTwoFactorFlow got unexpected error: %v, expected: %v", err, nil)
+	}
+	if !hasAuditEvents(1, AuditTwoFactorOn, AuditTwoFactorOff) {
+		t.Errorf("TestTwoFactorFlow got unexpected audit: %v", auditEventsOf(1))
+	}
+}
+
+func TestEnrollTwoFactorWithoutKey(t *testing.T) {
+	initTests()
+	totpSecrets = &SecretBox{}
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+
+	if w := twoFactorRequest(EnrollTwoFactor, ``); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestEnrollTwoFactorWithoutKey got unexpected code: %d, expected: %d",
+			w.Code, http.StatusInternalServerError)
+	}
+	if _, ok := TwoFactors.(*twoFactorsTest).settings[1]; ok {
+		t.Errorf("TestEnrollTwoFactorWithoutKey secret was saved without key")
+	}
+}
+
+func TestTwoFactorCodeLockout(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	Users.(*usersTest).users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true}
+	TwoFactors.(*twoFactorsTest).settings[1] = TwoFactorModel{UserID: 1, Enabled: true}
+
+	challenge, err := tokenSigner.Sign(TokenPurposeLogin2FA, 1, passwordFingerprint(&UserModel{}), time.Minute)
+	if err != nil {
+		t.Fatalf("TestTwoFactorCodeLockout got unexpected error: %v, expected: %v", err, nil)
+	}
+
+	form := &jmodels.FormTwoFactorLogin{Challenge: challenge, RecoveryCode: "kek"}
+	for i := int64(0); i < loginPolicy.MaxFailures; i++ {
+		if _, err = completeTwoFactorLoginImpl(form, &clientInfo{}); err == nil {
+			t.Fatalf("TestTwoFactorCodeLockout got unexpected error: %v", err)
+		}
+	}
+
+	if _, err = completeTwoFactorLoginImpl(form, &clientInfo{}); err == nil {
+		t.Fatalf("TestTwoFactorCodeLockout got unexpected error: %v", err)
+	} else if _, ok := err.(*LoginLockedError); !ok {
+		t.Errorf("TestTwoFactorCodeLockout got unexpected error: %v, expected: %v", err, &LoginLockedError{})
+	}
+}
+
+func TestUseRecoveryCodeModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE user_totp SET recovery_codes").
+		WithArgs(1, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE user_totp SET recovery_codes").
+		WithArgs(1, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	pqConn = db
+	tf := &TwoFactorConn{}
+
+	if ok, err := tf.UseRecoveryCode(1, "hash"); !ok || err != nil {
+		t.Errorf("TestUseRecoveryCodeModel got unexpected result: %t, %v, expected: %t", ok, err, true)
+	}
+	if ok, err := tf.UseRecoveryCode(1, "hash"); ok || err != nil {
+		t.Errorf("TestUseRecoveryCodeModel got unexpected result: %t, %v, expected: %t", ok, err, false)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestUseRecoveryCodeModel there were unfulfilled expectations: %s", err)
+	}
+}
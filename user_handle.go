@@ -1,6 +1,7 @@
 package main
 
 import (
+	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,7 +13,7 @@ import (
 	"github.com/pkg/errors"
 )
 
-// CheckUsername checks if username already used
+// CheckUsername checks if username already used or can not be taken at all
 func CheckUsername(w http.ResponseWriter, r *http.Request) {
 	logger := utils.GetLogger(r, logger, "CheckUsername")
 	errWriter := utils.NewErrorResponseWriter(w, logger)
@@ -24,7 +25,16 @@ func CheckUsername(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = Users.GetUserByUsername(bUser.Username) // если база лежит
+	// такое имя не пройдёт регистрацию, в базу можно не ходить
+	username := jmodels.NormalizeUsername(bUser.Username)
+	if policyErr := jmodels.UsernameRules.Check(username); policyErr != nil {
+		utils.WriteApplicationJSON(w, http.StatusOK, &jmodels.UsernameAvailability{
+			Reason: policyErr.Error(),
+		})
+		return
+	}
+
+	_, err = Users.GetUserByUsername(username) // если база лежит
 	if err != nil && errors.Cause(err) != utils.ErrNotExists {
 		errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "get user method error"))
 		return
@@ -33,12 +43,15 @@ func CheckUsername(w http.ResponseWriter, r *http.Request) {
 	// Если нет ошибки, то такой юзер точно есть, а если ошибка есть,
 	// то это точно models.ErrNotExists,
 	// так как остальные вышли бы раньше
-	used := (err == nil)
-	utils.WriteApplicationJSON(w, http.StatusOK, &struct {
-		Used bool `json:"used"`
-	}{
-		Used: used,
-	})
+	availability := &jmodels.UsernameAvailability{
+		Used:      err == nil,
+		Available: err != nil,
+	}
+	if availability.Used {
+		availability.Reason = utils.ErrTaken.Error()
+	}
+
+	utils.WriteApplicationJSON(w, http.StatusOK, availability)
 }
 
 // GetUser get user info by ID
@@ -84,7 +97,13 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = updateUserImpl(info, updateForm)
+	// текущую сессию при смене пароля не трогаем
+	token := ""
+	if cookie, cookieErr := r.Cookie("JSESSIONID"); cookieErr == nil {
+		token = cookie.Value
+	}
+
+	err = updateUserImpl(info, token, newClientInfo(r), updateForm)
 	if err != nil {
 		if validErr, ok := err.(*utils.ValidationError); ok {
 			errWriter.WriteValidationError(validErr)
@@ -115,7 +134,11 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if valError := form.Validate(); valError != nil {
+	valError := form.ValidateRegistration()
+	if valError == nil {
+		valError = passwordPolicy.Validate("password", form.Username, form.Password)
+	}
+	if valError != nil {
 		errWriter.WriteValidationError(valError)
 		return
 	}
@@ -123,21 +146,35 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	user := &UserModel{
 		Username: form.Username,
 		Password: &form.Password,
+		Email:    sql.NullString{String: form.Email, Valid: form.Email != ""},
 	}
 
 	if err = Users.Create(user); err != nil {
-		if errors.Cause(err) == utils.ErrTaken {
+		switch errors.Cause(err) {
+		case utils.ErrTaken:
 			errWriter.WriteValidationError(&utils.ValidationError{
 				"username": utils.ErrTaken.Error(),
 			})
-		} else {
+		case ErrEmailTaken:
+			errWriter.WriteValidationError(&utils.ValidationError{
+				"email": utils.ErrTaken.Error(),
+			})
+		default:
 			errWriter.WriteError(http.StatusInternalServerError, errors.Wrap(err, "user create error"))
 		}
 		return
 	}
 
+	auditUserImpl(AuditRegister, user.ID, newClientInfo(r), nil)
+
+	if user.Email.Valid {
+		if err = sendVerificationEmailImpl(user); err != nil {
+			logger.Warnf("can not send verification email: %s", err)
+		}
+	}
+
 	// сразу же логиним юзера
-	session, err := createSessionImpl(form)
+	session, err := createSessionImpl(form, newClientInfo(r))
 	if err != nil {
 		if validErr, ok := err.(*utils.ValidationError); ok {
 			errWriter.WriteValidationError(validErr)
@@ -148,14 +185,200 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ставим куку
+	setSessionCookie(w, session.Token, form.Remember)
+	w.WriteHeader(http.StatusOK)
+}
+
+// VerifyEmail подтверждает email по токену из письма
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "VerifyEmail")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormVerifyEmail{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = verifyEmailImpl(form.Token); err != nil {
+		if errors.Cause(err) == ErrTokenInvalid {
+			errWriter.WriteValidationError(&utils.ValidationError{
+				"token": utils.ErrInvalid.Error(),
+			})
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RequestPasswordReset отправляет ссылку для сброса пароля.
+// Отвечает 200, даже если такого юзера нет
+func RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "RequestPasswordReset")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormPasswordReset{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = requestPasswordResetImpl(form); err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ConfirmPasswordReset ставит новый пароль по токену из письма
+func ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "ConfirmPasswordReset")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormPasswordResetConfirm{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = confirmPasswordResetImpl(form, newClientInfo(r)); err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else if errors.Cause(err) == ErrTokenInvalid {
+			errWriter.WriteValidationError(&utils.ValidationError{
+				"token": utils.ErrInvalid.Error(),
+			})
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DeactivateUser выключает аккаунт текущего юзера, нужен пароль
+func DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "DeactivateUser")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	form := &jmodels.FormPasswordConfirm{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = deactivateUserImpl(info, form, newClientInfo(r)); err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+		} else {
+			errWriter.WriteError(http.StatusInternalServerError, err)
+		}
+		return
+	}
+
 	http.SetCookie(w, &http.Cookie{
 		Name:     "JSESSIONID",
 		Path:     "/",
-		Value:    session.Token,
-		Expires:  time.Now().Add(2628000 * time.Second),
+		Expires:  time.Unix(0, 0),
 		HttpOnly: true,
 	})
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// ReactivateUser включает выключенный аккаунт по username и паролю
+func ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "ReactivateUser")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+
+	form := &jmodels.FormUser{}
+	err := utils.DecodeBodyJSON(r.Body, form)
+	if err != nil {
+		errWriter.WriteWarn(http.StatusBadRequest, errors.Wrap(err, "decode body error"))
+		return
+	}
+
+	if err = reactivateUserImpl(form, newClientInfo(r)); err != nil {
+		if validErr, ok := err.(*utils.ValidationError); ok {
+			errWriter.WriteValidationError(validErr)
+			return
+		}
+
+		if lockErr, ok := err.(*LoginLockedError); ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(lockErr.RetryAfterSeconds(), 10))
+			errWriter.WriteWarn(http.StatusTooManyRequests, lockErr)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RotateVkSecret выпускает новый секрет для вк. Секрет отдаётся только в этом ответе
+func RotateVkSecret(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "RotateVkSecret")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	secret, err := rotateVkSecretImpl(info, newClientInfo(r))
+	if err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteApplicationJSON(w, http.StatusOK, secret)
+}
+
+// RevokeVkSecret отзывает секрет для вк
+func RevokeVkSecret(w http.ResponseWriter, r *http.Request) {
+	logger := utils.GetLogger(r, logger, "RevokeVkSecret")
+	errWriter := utils.NewErrorResponseWriter(w, logger)
+	info := SessionInfo(r)
+	if info == nil {
+		errWriter.WriteWarn(http.StatusUnauthorized, errors.New("session info is not presented"))
+		return
+	}
+
+	if err := revokeVkSecretImpl(info, newClientInfo(r)); err != nil {
+		if errors.Cause(err) == utils.ErrNotExists {
+			errWriter.WriteWarn(http.StatusUnauthorized, err)
+			return
+		}
+
+		errWriter.WriteError(http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/HotCodeGroup/warscript-users/jmodels"
 	"github.com/HotCodeGroup/warscript-utils/models"
@@ -9,6 +10,64 @@ import (
 	"github.com/pkg/errors"
 )
 
+// newInfoUserModel конвертирует юзера из базы в grpc модель
+func newInfoUserModel(u *UserModel) *models.InfoUser {
+	return &models.InfoUser{
+		ID:        u.ID,
+		Username:  u.Username,
+		PhotoUUID: u.GetPhotoUUID(),
+		Active:    u.Active,
+	}
+}
+
+func getUserByIDImpl(id int64) (*models.InfoUser, error) {
+	user, err := Users.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInfoUserModel(user), nil
+}
+
+func getUserByUsernameImpl(username string) (*models.InfoUser, error) {
+	user, err := Users.GetUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInfoUserModel(user), nil
+}
+
+func getUserBySecretImpl(secret string) (*models.InfoUser, error) {
+	user, err := Users.GetUserBySecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInfoUserModel(user), nil
+}
+
+func getUsersByIDsImpl(idsM *models.UserIDs) (*models.InfoUsers, error) {
+	ids := make([]int64, len(idsM.IDs))
+	for i, id := range idsM.IDs {
+		ids[i] = id.ID
+	}
+
+	users, err := Users.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	usersM := &models.InfoUsers{
+		Users: make([]*models.InfoUser, 0, len(users)),
+	}
+	for _, u := range users {
+		usersM.Users = append(usersM.Users, newInfoUserModel(u))
+	}
+
+	return usersM, nil
+}
+
 func getInfoUserByIDImpl(id int64) (*jmodels.ProfileInfoUser, error) {
 	user, err := Users.GetUserByID(id)
 	if err != nil {
@@ -24,12 +83,14 @@ func getInfoUserByIDImpl(id int64) (*jmodels.ProfileInfoUser, error) {
 				PhotoUUID: user.GetPhotoUUID(), // точно знаем, что там 16 байт
 			},
 		},
-		VkSecret: user.VkSecret,
+		Email:         user.GetEmail(),
+		EmailVerified: user.EmailVerified,
 	}, nil
 }
 
 //nolint: gocyclo
-func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpdate) error {
+func updateUserImpl(info *models.SessionPayload, token string, client *clientInfo,
+	updateForm *jmodels.FormUserUpdate) error {
 	if err := updateForm.Validate(); err != nil {
 		return err
 	}
@@ -37,7 +98,8 @@ func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpd
 	// нечего обновлять
 	if !updateForm.Username.IsDefined() &&
 		!updateForm.NewPassword.IsDefined() &&
-		!updateForm.PhotoUUID.IsDefined() {
+		!updateForm.PhotoUUID.IsDefined() &&
+		!updateForm.Email.IsDefined() {
 		return nil
 	}
 
@@ -47,8 +109,12 @@ func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpd
 		return errors.Wrap(err, "get user error")
 	}
 
-	// хотим обновить username
-	if updateForm.Username.IsDefined() {
+	// хотим обновить username. Политика только для нового имени
+	oldUsername := user.Username
+	if updateForm.Username.IsDefined() && updateForm.Username.V != user.Username {
+		if valErr := jmodels.UsernameRules.Validate("username", updateForm.Username.V); valErr != nil {
+			return valErr
+		}
 		user.Username = updateForm.Username.V
 	}
 
@@ -56,9 +122,17 @@ func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpd
 		user.PhotoUUID = sql.NullString{String: updateForm.PhotoUUID.V, Valid: true}
 	}
 
-	// Если обновляется пароль, нужно проверить,
-	// что пользователь знает старый
-	if updateForm.NewPassword.IsDefined() {
+	// через email восстанавливается пароль, так что менять его
+	// можно только зная пароль, как и сам пароль
+	emailChanged := updateForm.Email.IsDefined() && !strings.EqualFold(updateForm.Email.V, user.GetEmail())
+	if emailChanged {
+		user.Email = sql.NullString{String: updateForm.Email.V, Valid: updateForm.Email.V != ""}
+		user.EmailVerified = false
+	}
+
+	// Если обновляется пароль или email, нужно проверить,
+	// что пользователь знает старый пароль
+	if updateForm.NewPassword.IsDefined() || emailChanged {
 		if !updateForm.OldPassword.IsDefined() {
 			return &utils.ValidationError{
 				"oldPassword": utils.ErrRequired.Error(),
@@ -70,6 +144,13 @@ func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpd
 				"oldPassword": utils.ErrInvalid.Error(),
 			}
 		}
+	}
+
+	if updateForm.NewPassword.IsDefined() {
+		// username к этому моменту уже новый, если его тоже меняют
+		if valErr := passwordPolicy.Validate("newPassword", user.Username, updateForm.NewPassword.V); valErr != nil {
+			return valErr
+		}
 
 		user.Password = &updateForm.NewPassword.V
 	}
@@ -81,9 +162,43 @@ func updateUserImpl(info *models.SessionPayload, updateForm *jmodels.FormUserUpd
 				"username": utils.ErrTaken.Error(),
 			}
 		}
+		if errors.Cause(err) == ErrEmailTaken {
+			return &utils.ValidationError{
+				"email": utils.ErrTaken.Error(),
+			}
+		}
 
 		return errors.Wrap(err, "user save error")
 	}
 
+	if user.Username != oldUsername {
+		auditUserImpl(AuditUsernameChange, user.ID, client, map[string]string{
+			"old": oldUsername,
+			"new": user.Username,
+		})
+	}
+	if updateForm.NewPassword.IsDefined() {
+		auditUserImpl(AuditPasswordChange, user.ID, client, nil)
+	}
+	if emailChanged {
+		auditUserImpl(AuditEmailChange, user.ID, client, nil)
+	}
+
+	if emailChanged && user.Email.Valid {
+		if err := sendVerificationEmailImpl(user); err != nil {
+			logger.Warnf("can not send verification email: %s", err)
+		}
+	}
+
+	// после смены пароля разлогиниваем все остальные устройства и отзываем личные токены
+	if updateForm.NewPassword.IsDefined() {
+		if err := Sessions.DeleteAllExcept(user.ID, token); err != nil {
+			return errors.Wrap(err, "sessions revoke error")
+		}
+		if err := PersonalTokens.DeleteAllForUser(user.ID); err != nil {
+			return errors.Wrap(err, "personal tokens revoke error")
+		}
+	}
+
 	return nil
 }
@@ -4,31 +4,43 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/HotCodeGroup/warscript-utils/postgresql"
 	"github.com/HotCodeGroup/warscript-utils/utils"
-	"github.com/google/uuid"
 
 	"github.com/pkg/errors"
-	"golang.org/x/crypto/bcrypt"
 
 	"database/sql"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 var pqConn *sql.DB
 
+// ErrEmailTaken email уже привязан к другому юзеру
+var ErrEmailTaken = errors.New("email_taken")
+
 // UserAccessObject DAO for User model
 type UserAccessObject interface {
 	GetUserByID(id int64) (*UserModel, error)
 	GetUserByUsername(username string) (*UserModel, error)
 	GetUsersByIDs(ids []int64) ([]*UserModel, error)
 	GetUserBySecret(secret string) (*UserModel, error)
+	GetUserByEmail(email string) (*UserModel, error)
 
 	Create(u *UserModel) error
 	Save(u *UserModel) error
 	CheckPassword(u *UserModel, password string) bool
+
+	Deactivate(id int64) error
+	Reactivate(id int64, since time.Time) (bool, error)
+	Anonymize(before time.Time) (int64, error)
+
+	Search(query string, limit, offset int) ([]*UserModel, error)
+	SetActive(id int64, active bool) error
+	// SetVkSecret заменяет хеш секрета для вк, старый секрет перестаёт работать
+	SetVkSecret(id int64, hash []byte) error
 }
 
 // AccessObject implementation of UserAccessObject
@@ -49,7 +61,9 @@ type UserModel struct {
 	Password      *string // строка для сохранения
 	Active        bool
 	PasswordCrypt []byte // внутренний хеш для проверки
-	VkSecret      string
+	VkSecretHash  []byte // sha256 секрета для вк, сам секрет не хранится
+	Email         sql.NullString
+	EmailVerified bool
 }
 
 // GetPhotoUUID возвращает photoUUID или пустую строку, если его нет в базе
@@ -61,10 +75,19 @@ func (u *UserModel) GetPhotoUUID() string {
 	return ""
 }
 
+// GetEmail возвращает email или пустую строку, если его нет в базе
+func (u *UserModel) GetEmail() string {
+	if u.Email.Valid {
+		return u.Email.String
+	}
+
+	return ""
+}
+
 // Create создаёт запись в базе с новыми полями
 func (us *AccessObject) Create(u *UserModel) error {
 	var err error
-	u.PasswordCrypt, err = bcrypt.GenerateFromPassword([]byte(*u.Password), bcrypt.MinCost)
+	u.PasswordCrypt, err = passwordHashers.Hash(*u.Password)
 	if err != nil {
 		return errors.Wrapf(utils.ErrInternal, "password generate error: %s", err.Error())
 	}
@@ -85,11 +108,23 @@ func (us *AccessObject) Create(u *UserModel) error {
 		return errors.Wrapf(utils.ErrInternal, "check duplicate error: %s", err.Error())
 	}
 
-	vkSecret := uuid.New().String()[:8] // создаём секретный ключ для вк
-	_, err = tx.Exec(`INSERT INTO users (username, password, vk_secret) VALUES($1, $2, $3);`, &u.Username, &u.PasswordCrypt, vkSecret)
+	if err = us.checkEmailFree(tx, u); err != nil {
+		return err
+	}
+
+	// секрет для вк юзер получит, когда выпустит новый: этот никто не видит
+	_, vkSecretHash, err := newVkSecret()
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "vk secret generate error: %s", err.Error())
+	}
+	err = tx.QueryRow(`INSERT INTO users (username, password, vk_secret_hash, email, email_verified)
+		VALUES($1, $2, $3, $4, $5) RETURNING id;`,
+		&u.Username, &u.PasswordCrypt, vkSecretHash, &u.Email, &u.EmailVerified).Scan(&u.ID)
 	if err != nil {
 		return errors.Wrapf(utils.ErrInternal, "user create error: %s", err.Error())
 	}
+	u.Active = true
+	u.VkSecretHash = vkSecretHash
 
 	err = tx.Commit()
 	if err != nil {
@@ -103,7 +138,7 @@ func (us *AccessObject) Create(u *UserModel) error {
 func (us *AccessObject) Save(u *UserModel) error {
 	var err error
 	if u.Password != nil {
-		u.PasswordCrypt, err = bcrypt.GenerateFromPassword([]byte(*u.Password), bcrypt.MinCost)
+		u.PasswordCrypt, err = passwordHashers.Hash(*u.Password)
 		if err != nil {
 			return errors.Wrapf(utils.ErrInternal, "password generate error: %s", err.Error())
 		}
@@ -123,14 +158,20 @@ func (us *AccessObject) Save(u *UserModel) error {
 		return errors.Wrapf(utils.ErrInternal, "check duplicate error: %s", err.Error())
 	}
 
-	_, err = tx.Exec(`UPDATE users SET (username, password, photo_uuid, active) = (
+	if err = us.checkEmailFree(tx, u); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET (username, password, photo_uuid, active, email, email_verified) = (
 		COALESCE($1, username),
 		COALESCE($2, password),
 		$3,
-		COALESCE($4, active)
+		COALESCE($4, active),
+		$5,
+		$6
 		)
-		WHERE id = $5;`,
-		&u.Username, &u.PasswordCrypt, &u.PhotoUUID, &u.Active, &u.ID)
+		WHERE id = $7;`,
+		&u.Username, &u.PasswordCrypt, &u.PhotoUUID, &u.Active, &u.Email, &u.EmailVerified, &u.ID)
 	if err != nil {
 		return errors.Wrapf(utils.ErrInternal, "user save error: %s", err.Error())
 	}
@@ -143,15 +184,59 @@ func (us *AccessObject) Save(u *UserModel) error {
 	return nil
 }
 
-// CheckPassword проверяет пароль у юзера и сохранённый в модели
+// checkEmailFree проверяет, что email юзера не занят кем-то другим
+func (us *AccessObject) checkEmailFree(q postgresql.Queryer, u *UserModel) error {
+	if !u.Email.Valid {
+		return nil
+	}
+
+	du, err := us.getUserImpl(q, "email", u.Email.String)
+	if err == nil && u.ID != du.ID {
+		return ErrEmailTaken
+	} else if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(utils.ErrInternal, "check duplicate email error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// CheckPassword проверяет пароль у юзера и сохранённый в модели.
+// Если хеш сделан устаревшим алгоритмом или с меньшей стоимостью,
+// он сразу пересчитывается: открытый пароль есть только в этот момент
 func (us *AccessObject) CheckPassword(u *UserModel, password string) bool {
-	err := bcrypt.CompareHashAndPassword(u.PasswordCrypt, []byte(password))
-	return err == nil
+	if !passwordHashers.Verify(u.PasswordCrypt, password) {
+		return false
+	}
+
+	if passwordHashers.NeedsRehash(u.PasswordCrypt) {
+		if err := us.rehashPassword(u, password); err != nil {
+			logger.Warnf("can not rehash password of user %d: %s", u.ID, err)
+		}
+	}
+
+	return true
 }
 
-// GetUserBySecret получает юзера по id
+// rehashPassword обновляет хеш, только если его никто не поменял с момента чтения
+func (us *AccessObject) rehashPassword(u *UserModel, password string) error {
+	hash, err := passwordHashers.Hash(password)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "password generate error: %s", err.Error())
+	}
+
+	_, err = pqConn.Exec(`UPDATE users SET password = $1 WHERE id = $2 AND password = $3;`,
+		hash, u.ID, u.PasswordCrypt)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "password rehash error: %s", err.Error())
+	}
+
+	u.PasswordCrypt = hash
+	return nil
+}
+
+// GetUserBySecret получает юзера по секрету для вк, в базе ищется его хеш
 func (us *AccessObject) GetUserBySecret(secret string) (*UserModel, error) {
-	u, err := us.getUserImpl(pqConn, "vk_secret", secret)
+	u, err := us.getUserImpl(pqConn, "vk_secret_hash", hashVkSecret(secret))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, utils.ErrNotExists
@@ -163,6 +248,20 @@ func (us *AccessObject) GetUserBySecret(secret string) (*UserModel, error) {
 	return u, nil
 }
 
+// GetUserByEmail получает юзера по email
+func (us *AccessObject) GetUserByEmail(email string) (*UserModel, error) {
+	u, err := us.getUserImpl(pqConn, "email", email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, utils.ErrNotExists
+		}
+
+		return nil, errors.Wrapf(utils.ErrInternal, "user get by email error: %s", err.Error())
+	}
+
+	return u, nil
+}
+
 // GetUserByID получает юзера по id
 func (us *AccessObject) GetUserByID(id int64) (*UserModel, error) {
 	u, err := us.getUserImpl(pqConn, "id", strconv.FormatInt(id, 10))
@@ -193,12 +292,13 @@ func (us *AccessObject) GetUserByUsername(username string) (*UserModel, error) {
 }
 
 //nolint: gosec
-func (us *AccessObject) getUserImpl(q postgresql.Queryer, field, value string) (*UserModel, error) {
+func (us *AccessObject) getUserImpl(q postgresql.Queryer, field string, value interface{}) (*UserModel, error) {
 	u := &UserModel{}
 
 	row := q.QueryRow(`SELECT u.id, u.username, u.password,
-	 					u.active, u.photo_uuid, u.vk_secret FROM users u WHERE `+field+` = $1;`, value)
-	if err := row.Scan(&u.ID, &u.Username, &u.PasswordCrypt, &u.Active, &u.PhotoUUID, &u.VkSecret); err != nil {
+	 					u.active, u.photo_uuid, u.vk_secret_hash, u.email, u.email_verified FROM users u WHERE `+field+` = $1;`, value)
+	if err := row.Scan(&u.ID, &u.Username, &u.PasswordCrypt, &u.Active, &u.PhotoUUID, &u.VkSecretHash,
+		&u.Email, &u.EmailVerified); err != nil {
 		return nil, err
 	}
 
@@ -214,7 +314,8 @@ func (us *AccessObject) GetUsersByIDs(ids []int64) ([]*UserModel, error) {
 
 	//nolint: gosec тут точно инты и никакие хакеры ничего не сломают
 	rows, err := pqConn.Query(fmt.Sprintf(`SELECT u.id, u.username, u.password,
-	 					u.active, u.photo_uuid, u.vk_secret FROM users u WHERE id IN (%s);`, strings.Join(placeholders, ",")))
+	 					u.active, u.photo_uuid, u.vk_secret_hash, u.email, u.email_verified
+	 					FROM users u WHERE id IN (%s);`, strings.Join(placeholders, ",")))
 	if err != nil {
 		return nil, errors.Wrapf(utils.ErrInternal, "users get by ids error: %s", err.Error())
 	}
@@ -225,7 +326,8 @@ func (us *AccessObject) GetUsersByIDs(ids []int64) ([]*UserModel, error) {
 		u := &UserModel{}
 		err = rows.Scan(&u.ID, &u.Username,
 			&u.PasswordCrypt, &u.Active,
-			&u.PhotoUUID, &u.VkSecret)
+			&u.PhotoUUID, &u.VkSecretHash,
+			&u.Email, &u.EmailVerified)
 		if err != nil {
 			return nil, errors.Wrapf(utils.ErrInternal, "users get by ids user scan error: %s", err.Error())
 		}
@@ -235,3 +337,171 @@ func (us *AccessObject) GetUsersByIDs(ids []int64) ([]*UserModel, error) {
 
 	return users, nil
 }
+
+// Deactivate выключает аккаунт и запоминает когда, чтобы удалить его по прошествии срока
+func (us *AccessObject) Deactivate(id int64) error {
+	_, err := pqConn.Exec(`UPDATE users SET (active, deactivated_at) = (false, now())
+		WHERE id = $1 AND active;`, id)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "user deactivate error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Reactivate включает аккаунт обратно, если он выключен позже since и ещё не удалён
+func (us *AccessObject) Reactivate(id int64, since time.Time) (bool, error) {
+	res, err := pqConn.Exec(`UPDATE users SET (active, deactivated_at) = (true, NULL)
+		WHERE id = $1 AND NOT active AND deactivated_at > $2;`, id, since)
+	if err != nil {
+		return false, errors.Wrapf(utils.ErrInternal, "user reactivate error: %s", err.Error())
+	}
+
+	return affectedOne(res)
+}
+
+// Anonymize обезличивает аккаунты, выключенные раньше before. Строки не удаляются:
+// на id юзеров ссылаются другие сервисы. Username получает недопустимый
+// для регистрации символ, так что ни с кем не совпадёт
+func (us *AccessObject) Anonymize(before time.Time) (int64, error) {
+	tx, err := pqConn.Begin()
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "can not open anonymize transaction: %s", err.Error())
+	}
+	//nolint:errcheck
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`UPDATE users SET (username, password, photo_uuid, email, email_verified,
+		vk_secret_hash, deactivated_at) = ('deleted#' || id, '', NULL, NULL, false,
+		sha256(convert_to(random()::text || id::text, 'UTF8')), NULL)
+		WHERE NOT active AND deactivated_at < $1 RETURNING id;`, before)
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "users anonymize error: %s", err.Error())
+	}
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, errors.Wrapf(utils.ErrInternal, "anonymized id scan error: %s", err.Error())
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized ids read error: %s", err.Error())
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_totp WHERE user_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized totp delete error: %s", err.Error())
+	}
+
+	// через старый аккаунт провайдера должно быть можно зарегистрироваться заново
+	_, err = tx.Exec(`DELETE FROM user_identities WHERE user_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized identities delete error: %s", err.Error())
+	}
+
+	_, err = tx.Exec(`DELETE FROM personal_tokens WHERE user_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized personal tokens delete error: %s", err.Error())
+	}
+
+	_, err = tx.Exec(`DELETE FROM user_roles WHERE user_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized roles delete error: %s", err.Error())
+	}
+
+	// события остаются, но без адресов и подробностей вроде старых username.
+	// Подробности действий самого юзера над другими принадлежат им и не стираются
+	_, err = tx.Exec(`UPDATE audit_events SET ip = '',
+		details = CASE WHEN user_id = ANY($1) THEN '{}'::jsonb ELSE details END
+		WHERE user_id = ANY($1) OR actor_id = ANY($1);`, pq.Array(ids))
+	if err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymized audit scrub error: %s", err.Error())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, errors.Wrapf(utils.ErrInternal, "anonymize transaction commit error: %s", err.Error())
+	}
+
+	return int64(len(ids)), nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE в поисковом запросе
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search ищет юзеров по id или началу username или email
+func (us *AccessObject) Search(query string, limit, offset int) ([]*UserModel, error) {
+	rows, err := pqConn.Query(`SELECT u.id, u.username, u.password,
+	 					u.active, u.photo_uuid, u.vk_secret_hash, u.email, u.email_verified
+	 					FROM users u WHERE u.id::text = $1 OR u.username LIKE $2 OR u.email LIKE $2
+	 					ORDER BY u.id LIMIT $3 OFFSET $4;`,
+		query, likeEscaper.Replace(query)+"%", limit, offset)
+	if err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "users search error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	users := make([]*UserModel, 0)
+	for rows.Next() {
+		u := &UserModel{}
+		err = rows.Scan(&u.ID, &u.Username,
+			&u.PasswordCrypt, &u.Active,
+			&u.PhotoUUID, &u.VkSecretHash,
+			&u.Email, &u.EmailVerified)
+		if err != nil {
+			return nil, errors.Wrapf(utils.ErrInternal, "users search user scan error: %s", err.Error())
+		}
+
+		users = append(users, u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrapf(utils.ErrInternal, "users search read error: %s", err.Error())
+	}
+
+	return users, nil
+}
+
+// SetActive включает или выключает аккаунт вручную. Отсчёт до удаления
+// при этом сбрасывается: заблокированный юзер не включит аккаунт сам
+// и не будет обезличен
+func (us *AccessObject) SetActive(id int64, active bool) error {
+	res, err := pqConn.Exec(`UPDATE users SET (active, deactivated_at) = ($2, NULL) WHERE id = $1;`, id, active)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "user set active error: %s", err.Error())
+	}
+
+	ok, err := affectedOne(res)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrNotExists
+	}
+
+	return nil
+}
+
+// SetVkSecret заменяет хеш секрета для вк
+func (us *AccessObject) SetVkSecret(id int64, hash []byte) error {
+	res, err := pqConn.Exec(`UPDATE users SET vk_secret_hash = $2 WHERE id = $1;`, id, hash)
+	if err != nil {
+		return errors.Wrapf(utils.ErrInternal, "user set vk secret error: %s", err.Error())
+	}
+
+	ok, err := affectedOne(res)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return utils.ErrNotExists
+	}
+
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/HotCodeGroup/warscript-utils/utils"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCreateOK(t *testing.T) {
@@ -18,7 +19,7 @@ func TestCreateOK(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT").WillReturnError(sql.ErrNoRows)
-	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
 	pqConn = db
@@ -30,8 +31,8 @@ func TestCreateOK(t *testing.T) {
 		Password: &pass,
 	}
 
-	if err = Users.Create(u); err != nil {
-		t.Errorf("TestCreate got unexpected error: %v", err)
+	if err = Users.Create(u); err != nil || u.ID != 1 {
+		t.Errorf("TestCreate got unexpected result: %d, %v", u.ID, err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -48,8 +49,8 @@ func TestCreateTaken(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret"}).
-			AddRow(1, "kek", []byte{1, 2, 3}, true, "kek", "lol"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "kek", []byte{1, 2, 3}, true, "kek", "lol", nil, false))
 	mock.ExpectRollback()
 
 	pqConn = db
@@ -142,8 +143,8 @@ func TestSaveTaken(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectQuery("SELECT").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret"}).
-			AddRow(2, "kek", []byte{1, 2, 3}, true, "kek", "lol"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(2, "kek", []byte{1, 2, 3}, true, "kek", "lol", nil, false))
 	mock.ExpectRollback()
 
 	pqConn = db
@@ -205,10 +206,10 @@ func TestGetUsersByIDsModelOK(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery("SELECT").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret"}).
-			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol").
-			AddRow(2, "kek2", []byte{1, 2, 3}, true, "kek", "lol").
-			AddRow(3, "kek3", []byte{1, 2, 3}, true, "kek", "lol"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol", nil, false).
+			AddRow(2, "kek2", []byte{1, 2, 3}, true, "kek", "lol", nil, false).
+			AddRow(3, "kek3", []byte{1, 2, 3}, true, "kek", "lol", nil, false))
 
 	pqConn = db
 	Users = &AccessObject{}
@@ -230,8 +231,8 @@ func TestGetUserByIDModelOK(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery("SELECT").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret"}).
-			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol", nil, false))
 
 	pqConn = db
 	Users = &AccessObject{}
@@ -276,8 +277,8 @@ func TestGetUserByUsernameModelOK(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery("SELECT").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret"}).
-			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "kek1", []byte{1, 2, 3}, true, "kek", "lol", nil, false))
 
 	pqConn = db
 	Users = &AccessObject{}
@@ -313,3 +314,107 @@ func TestGetUserByUsernameModelNoRows(t *testing.T) {
 		t.Errorf("TestCreate there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestCheckPasswordRehash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	defaultHashers := passwordHashers
+	defer func() { passwordHashers = defaultHashers }()
+
+	passwordHashers, _ = NewPasswordHashers(HashingConfig{BcryptCost: bcrypt.MinCost})
+	oldHash, _ := passwordHashers.Hash("lolkek123")
+	u := &UserModel{ID: 1, Username: "kek", PasswordCrypt: oldHash}
+
+	// актуальный хеш не трогаем
+	if !Users.CheckPassword(u, "lolkek123") {
+		t.Errorf("TestCheckPasswordRehash can not check correct password")
+	}
+
+	passwordHashers, _ = NewPasswordHashers(HashingConfig{BcryptCost: bcrypt.MinCost + 1})
+	if Users.CheckPassword(u, "lolkek1234") {
+		t.Errorf("TestCheckPasswordRehash checked wrong password")
+	}
+
+	mock.ExpectExec("UPDATE users SET password").
+		WithArgs(sqlmock.AnyArg(), 1, oldHash).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if !Users.CheckPassword(u, "lolkek123") {
+		t.Errorf("TestCheckPasswordRehash can not check correct password")
+	}
+	if passwordHashers.NeedsRehash(u.PasswordCrypt) {
+		t.Errorf("TestCheckPasswordRehash hash was not upgraded")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestCheckPasswordRehash there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCreateEmailTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WithArgs("kek").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("SELECT").WithArgs("kek@mail.ru").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(2, "lol", []byte{1, 2, 3}, true, "kek", "lol", "kek@mail.ru", true))
+	mock.ExpectRollback()
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	pass := "lol"
+	u := &UserModel{
+		Username: "kek",
+		Password: &pass,
+		Email:    sql.NullString{String: "kek@mail.ru", Valid: true},
+	}
+
+	if err = Users.Create(u); err != ErrEmailTaken {
+		t.Errorf("TestCreateEmailTaken got unexpected error: %v, expected: %v", err, ErrEmailTaken)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestCreateEmailTaken there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetUserByEmailModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WithArgs("kek@mail.ru").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "kek", []byte{1, 2, 3}, true, "kek", "lol", "kek@mail.ru", true))
+	mock.ExpectQuery("SELECT").WithArgs("lol@mail.ru").WillReturnError(sql.ErrNoRows)
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	u, err := Users.GetUserByEmail("kek@mail.ru")
+	if err != nil || u.GetEmail() != "kek@mail.ru" || !u.EmailVerified {
+		t.Errorf("TestGetUserByEmailModel got unexpected result: %+v, %v", u, err)
+	}
+
+	if _, err = Users.GetUserByEmail("lol@mail.ru"); err != utils.ErrNotExists {
+		t.Errorf("TestGetUserByEmailModel got unexpected error: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestGetUserByEmailModel there were unfulfilled expectations: %s", err)
+	}
+}
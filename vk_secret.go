@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/pkg/errors"
+)
+
+// vkSecretBytes сколько случайных байт в секрете для вк
+const vkSecretBytes = 24
+
+// newVkSecret выпускает секрет для вк и его хеш для базы
+func newVkSecret() (string, []byte, error) {
+	secret, err := randomToken(vkSecretBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return secret, hashVkSecret(secret), nil
+}
+
+// hashVkSecret секрет случайный и длинный, так что хватает sha256 без соли,
+// а по хешу можно искать юзера
+func hashVkSecret(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// rotateVkSecretImpl выпускает юзеру новый секрет для вк. Старый перестаёт
+// работать, а новый показывается только сейчас
+func rotateVkSecretImpl(info *models.SessionPayload, client *clientInfo) (*jmodels.VkSecret, error) {
+	secret, hash, err := newVkSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "vk secret generate error")
+	}
+
+	if err = Users.SetVkSecret(info.ID, hash); err != nil {
+		return nil, errors.Wrap(err, "vk secret save error")
+	}
+	auditUserImpl(AuditVkSecretRotate, info.ID, client, nil)
+
+	return &jmodels.VkSecret{VkSecret: secret}, nil
+}
+
+// revokeVkSecretImpl отзывает секрет для вк, не выпуская новый
+func revokeVkSecretImpl(info *models.SessionPayload, client *clientInfo) error {
+	_, hash, err := newVkSecret()
+	if err != nil {
+		return errors.Wrap(err, "vk secret generate error")
+	}
+
+	if err = Users.SetVkSecret(info.ID, hash); err != nil {
+		return errors.Wrap(err, "vk secret save error")
+	}
+	auditUserImpl(AuditVkSecretRevoke, info.ID, client, nil)
+
+	return nil
+}
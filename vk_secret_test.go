@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/HotCodeGroup/warscript-users/jmodels"
+	"github.com/HotCodeGroup/warscript-utils/middlewares"
+	"github.com/HotCodeGroup/warscript-utils/models"
+	"github.com/HotCodeGroup/warscript-utils/utils"
+)
+
+func vkSecretRequest(userID int64, method string) *httptest.ResponseRecorder {
+	h := RotateVkSecret
+	if method == "DELETE" {
+		h = RevokeVkSecret
+	}
+
+	r := httptest.NewRequest(method, "/users/me/vk-secret", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middlewares.SessionInfoKey, &models.SessionPayload{ID: userID}))
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestNewVkSecret(t *testing.T) {
+	first, hash, err := newVkSecret()
+	if err != nil {
+		t.Fatalf("TestNewVkSecret got unexpected error: %v", err)
+	}
+	if len(first) != 32 || !bytes.Equal(hash, hashVkSecret(first)) {
+		t.Errorf("TestNewVkSecret got unexpected secret: %s, %x", first, hash)
+	}
+
+	second, _, err := newVkSecret()
+	if err != nil || second == first {
+		t.Errorf("TestNewVkSecret got repeated secret: %s, %v", second, err)
+	}
+}
+
+func TestRotateVkSecret(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	users := Users.(*usersTest).users
+	users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true, VkSecretHash: hashVkSecret("old")}
+
+	w := vkSecretRequest(1, "POST")
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("TestRotateVkSecret got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	secret := &jmodels.VkSecret{}
+	if err := json.Unmarshal(w.Body.Bytes(), secret); err != nil {
+		t.Fatalf("TestRotateVkSecret got bad body: %v", err)
+	}
+
+	if user, err := Users.GetUserBySecret(secret.VkSecret); err != nil || user.ID != 1 {
+		t.Errorf("TestRotateVkSecret new secret does not work: %v, %v", user, err)
+	}
+	if _, err := Users.GetUserBySecret("old"); err != utils.ErrNotExists {
+		t.Errorf("TestRotateVkSecret old secret still works: %v", err)
+	}
+	if events := auditEventsOf(1); len(events) != 1 || events[0] != AuditVkSecretRotate {
+		t.Errorf("TestRotateVkSecret got unexpected events: %v", events)
+	}
+
+	if w = vkSecretRequest(2, "POST"); w.Code != http.StatusUnauthorized {
+		t.Errorf("TestRotateVkSecret got unexpected code: %d, expected: %d", w.Code, http.StatusUnauthorized)
+	}
+
+	Users.(*usersTest).SetNextFail(utils.ErrInternal)
+	if w = vkSecretRequest(1, "POST"); w.Code != http.StatusInternalServerError {
+		t.Errorf("TestRotateVkSecret got unexpected code: %d, expected: %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestRevokeVkSecret(t *testing.T) {
+	initTests()
+	pass := "golang4ever"
+	users := Users.(*usersTest).users
+	users[1] = UserModel{ID: 1, Username: "golang", Password: &pass, Active: true, VkSecretHash: hashVkSecret("old")}
+
+	if w := vkSecretRequest(1, "DELETE"); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("TestRevokeVkSecret got unexpected result: %d, %s", w.Code, w.Body.String())
+	}
+	if _, err := Users.GetUserBySecret("old"); err != utils.ErrNotExists {
+		t.Errorf("TestRevokeVkSecret old secret still works: %v", err)
+	}
+	if events := auditEventsOf(1); len(events) != 1 || events[0] != AuditVkSecretRevoke {
+		t.Errorf("TestRevokeVkSecret got unexpected events: %v", events)
+	}
+
+	if w := vkSecretRequest(2, "DELETE"); w.Code != http.StatusUnauthorized {
+		t.Errorf("TestRevokeVkSecret got unexpected code: %d, expected: %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestVkSecretModel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	pqConn = db
+	Users = &AccessObject{}
+
+	mock.ExpectQuery("SELECT").WithArgs(hashVkSecret("secret")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "active", "photo_uuid", "vk_secret_hash", "email", "email_verified"}).
+			AddRow(1, "golang", []byte("hash"), true, nil, hashVkSecret("secret"), nil, false))
+	if user, err := Users.GetUserBySecret("secret"); err != nil || user.ID != 1 {
+		t.Errorf("TestVkSecretModel GetUserBySecret got unexpected result: %v, %v", user, err)
+	}
+
+	mock.ExpectExec("UPDATE users SET vk_secret_hash").WithArgs(1, hashVkSecret("new")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err = Users.SetVkSecret(1, hashVkSecret("new")); err != nil {
+		t.Errorf("TestVkSecretModel SetVkSecret got unexpected error: %v", err)
+	}
+
+	mock.ExpectExec("UPDATE users SET vk_secret_hash").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err = Users.SetVkSecret(2, hashVkSecret("new")); err != utils.ErrNotExists {
+		t.Errorf("TestVkSecretModel SetVkSecret got: %v, expected: %v", err, utils.ErrNotExists)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("TestVkSecretModel there were unfulfilled expectations: %s", err)
+	}
+}